@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
@@ -20,6 +21,15 @@ type AuthorizeRequest struct {
 	State               string
 	CodeChallenge       string `json:"code_challenge"`
 	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce"`
+	// RequestUri is set when the parameters were pushed beforehand and referenced by request_uri (RFC 9126).
+	RequestUri string `json:"request_uri"`
+	// Request is the verified request object the parameters were taken from, if any (RFC 9101).
+	Request string `json:"request"`
+	// AuthorizationDetails is the JSON array of fine-grained permissions the client asks for (RFC 9396).
+	AuthorizationDetails string `json:"authorization_details"`
+	// Resources are the resource indicators of the resources the client wants access to (RFC 8707).
+	Resources []string `json:"resource"`
 }
 
 // DecodeAuthorizeRequest function to handle URL encoded data
@@ -29,18 +39,29 @@ func DecodeAuthorizeRequest(r *http.Request) (*AuthorizeRequest, error) {
 		return nil, fmt.Errorf("failed to parse form data: %w", err)
 	}
 
+	return DecodeAuthorizeRequestFromValues(r.Form)
+}
+
+// DecodeAuthorizeRequestFromValues builds an AuthorizeRequest from already parsed parameters, such as the ones
+// stored by a pushed authorization request.
+func DecodeAuthorizeRequestFromValues(values url.Values) (*AuthorizeRequest, error) {
 	// Convert response_type from string to responsetype.ResponseType
-	responseType := responsetype.ResponseType(r.FormValue("response_type"))
+	responseType := responsetype.ResponseType(values.Get("response_type"))
 
 	// Extract form data into AuthorizeRequest struct
 	request := &AuthorizeRequest{
-		ResponseType:        responseType,
-		ClientId:            r.FormValue("client_id"),
-		RedirectUri:         r.FormValue("redirect_uri"),
-		Scope:               r.FormValue("scope"),
-		State:               r.FormValue("state"),
-		CodeChallenge:       r.FormValue("code_challenge"),
-		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+		ResponseType:         responseType,
+		ClientId:             values.Get("client_id"),
+		RedirectUri:          values.Get("redirect_uri"),
+		Scope:                values.Get("scope"),
+		State:                values.Get("state"),
+		CodeChallenge:        values.Get("code_challenge"),
+		CodeChallengeMethod:  values.Get("code_challenge_method"),
+		Nonce:                values.Get("nonce"),
+		RequestUri:           values.Get("request_uri"),
+		Request:              values.Get("request"),
+		AuthorizationDetails: values.Get("authorization_details"),
+		Resources:            values["resource"],
 	}
 
 	err := sanitizeAuthorizeRequest(request)
@@ -61,6 +82,9 @@ func sanitizeAuthorizeRequest(request *AuthorizeRequest) error {
 	request.State = strings.TrimSpace(request.State)
 	request.CodeChallenge = strings.TrimSpace(request.CodeChallenge)
 	request.CodeChallengeMethod = strings.TrimSpace(request.CodeChallengeMethod)
+	request.Nonce = strings.TrimSpace(request.Nonce)
+	request.AuthorizationDetails = strings.TrimSpace(request.AuthorizationDetails)
+	request.Resources = trimResources(request.Resources)
 
 	// Validate ClientId length
 	if len(request.ClientId) < 1 || len(request.ClientId) > 256 {
@@ -72,6 +96,11 @@ func sanitizeAuthorizeRequest(request *AuthorizeRequest) error {
 		return errors.New("state length is invalid")
 	}
 
+	// Validate Nonce length
+	if len(request.Nonce) > 256 {
+		return errors.New("nonce length is invalid")
+	}
+
 	// Validate RedirectUri
 	if _, err := url.ParseRequestURI(request.RedirectUri); err != nil {
 		return errors.New("redirect_uri is invalid")
@@ -88,8 +117,8 @@ func sanitizeAuthorizeRequest(request *AuthorizeRequest) error {
 		return errors.New("unsupported code_challenge_method: only S256 is supported")
 	}
 	// Additional checks for potential injection attacks
-	if containsInjectionPatterns(request.ClientId) || containsInjectionPatterns(request.State) || containsInjectionPatterns(request.CodeChallenge) || containsInjectionPatterns(request.CodeChallengeMethod) {
-		return errors.New("client_id, state, code_challenge or code_challenge_method contains invalid characters")
+	if containsInjectionPatterns(request.ClientId) || containsInjectionPatterns(request.State) || containsInjectionPatterns(request.CodeChallenge) || containsInjectionPatterns(request.CodeChallengeMethod) || containsInjectionPatterns(request.Nonce) {
+		return errors.New("client_id, state, code_challenge, code_challenge_method or nonce contains invalid characters")
 	}
 
 	return nil
@@ -157,5 +186,26 @@ func (r *AuthorizeRequest) Validate() error {
 		return fmt.Errorf("unsupported code_challenge_method: %s", r.CodeChallengeMethod)
 	}
 
+	// ID tokens are only issued to OpenID Connect requests, and front-channel ID tokens must be bound to a nonce
+	if responsetype.Includes(r.ResponseType, responsetype.IDToken) {
+		if !slices.Contains(strings.Fields(r.Scope), "openid") {
+			return errors.New("the openid scope is required when requesting an id_token")
+		}
+		if r.Nonce == "" {
+			return errors.New("nonce is required when requesting an id_token")
+		}
+	}
+
 	return nil
 }
+
+// trimResources trims the resource parameters of a request, dropping the empty ones.
+func trimResources(resources []string) []string {
+	var trimmed []string
+	for _, resource := range resources {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			trimmed = append(trimmed, resource)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+)
+
+// ClientCredentialParams lists the form parameters that carry client credentials. They are never stored along with
+// the rest of a request.
+var ClientCredentialParams = []string{"client_secret", "client_assertion", "client_assertion_type"}
+
+// ClientCredentials holds what a client sent to identify and authenticate itself: a secret in the Authorization
+// header or in the body (RFC 6749, section 2.3.1), a signed assertion (RFC 7523, section 2.2) or the certificate of
+// the TLS connection (RFC 8705, section 2).
+type ClientCredentials struct {
+	ClientId            string
+	ClientSecret        string
+	ClientAssertion     string
+	ClientAssertionType string
+	// ClientCertificates is the certificate chain the client presented during the TLS handshake, leaf first
+	ClientCertificates []*x509.Certificate
+}
+
+// DecodeClientCredentials reads the client credentials of a form-encoded request. A client may use only one way of
+// sending a secret or an assertion per request.
+func DecodeClientCredentials(r *http.Request) (ClientCredentials, error) {
+	var credentials ClientCredentials
+	if err := r.ParseForm(); err != nil {
+		return credentials, fmt.Errorf("failed to parse form data: %w", err)
+	}
+
+	credentials.ClientId = strings.TrimSpace(r.PostFormValue("client_id"))
+	credentials.ClientSecret = r.PostFormValue("client_secret")
+	credentials.ClientAssertion = r.PostFormValue("client_assertion")
+	credentials.ClientAssertionType = r.PostFormValue("client_assertion_type")
+	if r.TLS != nil {
+		credentials.ClientCertificates = r.TLS.PeerCertificates
+	}
+
+	methods := 0
+	if credentials.ClientSecret != "" {
+		methods++
+	}
+	if credentials.ClientAssertion != "" || credentials.ClientAssertionType != "" {
+		methods++
+	}
+
+	// Client id and secret in the Authorization header are form-encoded before being joined (RFC 6749, section 2.3.1)
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Basic ") {
+		methods++
+		clientId, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			return credentials, errors.New("invalid Authorization header format")
+		}
+		clientId, idErr := url.QueryUnescape(clientId)
+		clientSecret, secretErr := url.QueryUnescape(clientSecret)
+		if idErr != nil || secretErr != nil {
+			return credentials, errors.New("malformed client credentials in Authorization header")
+		}
+		if credentials.ClientId != "" && credentials.ClientId != clientId {
+			return credentials, errors.New("client_id does not match the Authorization header")
+		}
+		credentials.ClientId = clientId
+		credentials.ClientSecret = clientSecret
+	}
+
+	if methods > 1 {
+		return credentials, errors.New("more than one client authentication method was used")
+	}
+
+	// A client assertion must come with the JWT bearer assertion type, and names the client in its subject
+	if credentials.ClientAssertion != "" || credentials.ClientAssertionType != "" {
+		if credentials.ClientAssertionType != authmethodtype.ClientAssertionType {
+			return credentials, fmt.Errorf("unsupported client_assertion_type: %s", credentials.ClientAssertionType)
+		}
+		if credentials.ClientAssertion == "" {
+			return credentials, errors.New("client_assertion is required with client_assertion_type")
+		}
+		assertion, err := jwt.ParseString(credentials.ClientAssertion)
+		if err != nil {
+			return credentials, fmt.Errorf("malformed client_assertion: %w", err)
+		}
+		if credentials.ClientId == "" {
+			credentials.ClientId = assertion.Subject()
+		}
+	}
+
+	return credentials, nil
+}
+
+// HasClientCredentials reports whether the client sent a secret, an assertion or a certificate to authenticate with.
+func (c *ClientCredentials) HasClientCredentials() bool {
+	return c.ClientSecret != "" || c.ClientAssertion != "" || len(c.ClientCertificates) > 0
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DeviceAuthorizationRequest represents the request a device sends to start the device authorization grant.
+type DeviceAuthorizationRequest struct {
+	ClientCredentials
+	Scope string
+}
+
+// DecodeDeviceAuthorizationRequest function to handle URL encoded data and Authorization header.
+func DecodeDeviceAuthorizationRequest(r *http.Request) (*DeviceAuthorizationRequest, error) {
+	credentials, err := DecodeClientCredentials(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceAuthorizationRequest{
+		ClientCredentials: credentials,
+		Scope:             strings.TrimSpace(r.FormValue("scope")),
+	}, nil
+}
+
+// Validate checks the required fields for the DeviceAuthorizationRequest.
+func (r *DeviceAuthorizationRequest) Validate() error {
+	if r.ClientId == "" {
+		return errors.New("client_id is required")
+	}
+	if r.Scope != "" && !IsValidScope(r.Scope) {
+		return errors.New("the requested scope is invalid, unknown, or malformed")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// PushedAuthorizationRequest represents an authorization request pushed to the PAR endpoint (RFC 9126).
+type PushedAuthorizationRequest struct {
+	ClientCredentials
+	AuthorizeRequest *AuthorizeRequest
+	// Params holds the authorization parameters to store, without the client's credentials.
+	Params url.Values
+}
+
+// DecodePushedAuthorizationRequest function to handle URL encoded data and Authorization header.
+func DecodePushedAuthorizationRequest(r *http.Request) (*PushedAuthorizationRequest, error) {
+	credentials, err := DecodeClientCredentials(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.PostForm.Has("request_uri") {
+		return nil, errors.New("request_uri must not be used in a pushed authorization request")
+	}
+
+	request := &PushedAuthorizationRequest{
+		ClientCredentials: credentials,
+	}
+
+	request.Params = url.Values{}
+	for key, values := range r.PostForm {
+		if !slices.Contains(ClientCredentialParams, key) {
+			request.Params[key] = values
+		}
+	}
+	request.Params.Set("client_id", request.ClientId)
+
+	return request, nil
+}
+
+// DecodeAuthorizeRequest reads the authorization parameters out of Params. It is kept apart from decoding so the
+// parameters of a signed request object can be merged into Params first.
+func (r *PushedAuthorizationRequest) DecodeAuthorizeRequest() error {
+	authorizeRequest, err := DecodeAuthorizeRequestFromValues(r.Params)
+	if err != nil {
+		return err
+	}
+	r.AuthorizeRequest = authorizeRequest
+	return nil
+}
+
+// Validate applies the authorization endpoint's rules to the pushed parameters.
+func (r *PushedAuthorizationRequest) Validate() error {
+	if r.ClientId == "" {
+		return errors.New("client_id is required")
+	}
+	return r.AuthorizeRequest.Validate()
+}
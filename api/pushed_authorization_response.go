@@ -0,0 +1,14 @@
+package api
+
+// PushedAuthorizationResponse is returned by the PAR endpoint (RFC 9126, section 2.2).
+type PushedAuthorizationResponse struct {
+	RequestUri string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+func NewPushedAuthorizationResponse(requestUri string, expiresIn int) *PushedAuthorizationResponse {
+	return &PushedAuthorizationResponse{
+		RequestUri: requestUri,
+		ExpiresIn:  expiresIn,
+	}
+}
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 )
 
 type RegisterClientRequest struct {
@@ -17,14 +21,58 @@ type RegisterClientRequest struct {
 	TokenEndpointAuthMethod authmethodtype.TokenEndpointAuthMethod `json:"token_endpoint_auth_method"`
 	RedirectUris            []string                               `json:"redirect_uris"`
 	Scopes                  string                                 `json:"scope"`
+	// Jwks and JwksUri register the client's public keys, e.g. for JWT bearer assertions (RFC 7523)
+	Jwks    json.RawMessage `json:"jwks,omitempty"`
+	JwksUri string          `json:"jwks_uri,omitempty"`
+	// TokenExchangeSubjectClients lists the client ids whose tokens this client may exchange (RFC 8693).
+	TokenExchangeSubjectClients []string `json:"token_exchange_subject_clients,omitempty"`
+	// TokenExchangeAudiences lists the audiences this client may request exchanged tokens for.
+	TokenExchangeAudiences []string `json:"token_exchange_audiences,omitempty"`
+	// RequirePushedAuthorizationRequests makes the client start every authorization through /oauth/par (RFC 9126).
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty"`
+	// RequireSignedRequestObject makes the client send its authorization parameters as a signed JWT (RFC 9101).
+	RequireSignedRequestObject bool `json:"require_signed_request_object,omitempty"`
+	// RequestUris lists the https URLs the client may pass request objects by reference from (RFC 9101, section 5.2).
+	RequestUris []string `json:"request_uris,omitempty"`
+	// CertificateSubject holds the tls_client_auth_* value identifying the certificate of a tls_client_auth client (RFC 8705).
+	authmethodtype.CertificateSubject
+	// TokenEncryption holds the *_encrypted_response_* algorithms the client's tokens are encrypted to its keys with.
+	encryptiontype.TokenEncryption
+	// TokenSigning holds the *_signed_response_alg algorithms the client's tokens are signed with.
+	signingtype.TokenSigning
 }
 
 func (r *RegisterClientRequest) Sanitize() {
 	r.ClientName = strings.TrimSpace(r.ClientName)
 	r.Scopes = strings.TrimSpace(r.Scopes)
+	r.JwksUri = strings.TrimSpace(r.JwksUri)
+	r.SubjectDN = strings.TrimSpace(r.SubjectDN)
+	r.SanDNS = strings.TrimSpace(r.SanDNS)
+	r.SanURI = strings.TrimSpace(r.SanURI)
+	r.SanIP = strings.TrimSpace(r.SanIP)
+	r.SanEmail = strings.TrimSpace(r.SanEmail)
+	r.IdTokenEncryptedResponseAlg = strings.TrimSpace(r.IdTokenEncryptedResponseAlg)
+	r.IdTokenEncryptedResponseEnc = strings.TrimSpace(r.IdTokenEncryptedResponseEnc)
+	r.AccessTokenEncryptedResponseAlg = strings.TrimSpace(r.AccessTokenEncryptedResponseAlg)
+	r.AccessTokenEncryptedResponseEnc = strings.TrimSpace(r.AccessTokenEncryptedResponseEnc)
+	r.IntrospectionEncryptedResponseAlg = strings.TrimSpace(r.IntrospectionEncryptedResponseAlg)
+	r.IntrospectionEncryptedResponseEnc = strings.TrimSpace(r.IntrospectionEncryptedResponseEnc)
+	r.TokenEncryption.Normalize()
+	r.IdTokenSignedResponseAlg = strings.TrimSpace(r.IdTokenSignedResponseAlg)
+	r.AccessTokenSignedResponseAlg = strings.TrimSpace(r.AccessTokenSignedResponseAlg)
+	r.IntrospectionSignedResponseAlg = strings.TrimSpace(r.IntrospectionSignedResponseAlg)
 	for i, uri := range r.RedirectUris {
 		r.RedirectUris[i] = strings.TrimSpace(uri)
 	}
+	for i, clientId := range r.TokenExchangeSubjectClients {
+		r.TokenExchangeSubjectClients[i] = strings.TrimSpace(clientId)
+	}
+	for i, audience := range r.TokenExchangeAudiences {
+		r.TokenExchangeAudiences[i] = strings.TrimSpace(audience)
+	}
+	for i, uri := range r.RequestUris {
+		r.RequestUris[i] = strings.TrimSpace(uri)
+	}
 }
 
 // Validate checks if the RegisterClientRequest is valid.
@@ -74,5 +122,76 @@ func (r *RegisterClientRequest) Validate() error {
 		}
 	}
 
+	// Validate client keys (if specified)
+	if len(r.Jwks) > 0 && r.JwksUri != "" {
+		return errors.New("jwks and jwks_uri must not both be present")
+	}
+	if len(r.Jwks) > 0 {
+		var jwks struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if err := json.Unmarshal(r.Jwks, &jwks); err != nil || len(jwks.Keys) == 0 {
+			return errors.New("jwks must be a JWK Set with at least one key")
+		}
+	}
+	if r.JwksUri != "" {
+		if u, err := url.Parse(r.JwksUri); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("malformed jwks_uri: %s", r.JwksUri)
+		}
+	}
+
+	// Validate certificate and assertion based authentication (if specified)
+	switch r.TokenEndpointAuthMethod {
+	case authmethodtype.PrivateKeyJWT:
+		if len(r.Jwks) == 0 && r.JwksUri == "" {
+			return errors.New("private_key_jwt requires jwks or jwks_uri to verify client assertions")
+		}
+	case authmethodtype.TLSClientAuth:
+		if r.CertificateSubject.Count() != 1 {
+			return errors.New("tls_client_auth requires exactly one tls_client_auth_* certificate subject value")
+		}
+	case authmethodtype.SelfSignedTLSClientAuth:
+		if len(r.Jwks) == 0 && r.JwksUri == "" {
+			return errors.New("self_signed_tls_client_auth requires jwks or jwks_uri with the client certificate")
+		}
+	}
+	if r.CertificateSubject.Count() > 0 && r.TokenEndpointAuthMethod != authmethodtype.TLSClientAuth {
+		return errors.New("tls_client_auth_* values are only allowed with tls_client_auth")
+	}
+
+	if r.RequireSignedRequestObject && len(r.Jwks) == 0 && r.JwksUri == "" {
+		return errors.New("require_signed_request_object needs jwks or jwks_uri to verify request objects")
+	}
+	for _, uri := range r.RequestUris {
+		if u, err := url.Parse(uri); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("malformed request_uri: %s", uri)
+		}
+	}
+
+	// Validate token encryption (if specified)
+	if err := r.TokenEncryption.Validate(); err != nil {
+		return err
+	}
+	if r.TokenEncryption.Requested() && len(r.Jwks) == 0 && r.JwksUri == "" {
+		return errors.New("encrypted tokens need jwks or jwks_uri with an encryption key")
+	}
+
+	// Validate token signing algorithms (if specified)
+	if err := r.TokenSigning.Validate(); err != nil {
+		return err
+	}
+
+	// Validate token exchange policy (if specified)
+	for _, clientId := range r.TokenExchangeSubjectClients {
+		if clientId == "" {
+			return errors.New("token_exchange_subject_clients cannot contain empty values")
+		}
+	}
+	for _, audience := range r.TokenExchangeAudiences {
+		if audience == "" {
+			return errors.New("token_exchange_audiences cannot contain empty values")
+		}
+	}
+
 	return nil
 }
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+
 	"github.com/manuelrojas19/go-oauth2-server/oauth"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 )
 
 type RegisterClientResponse struct {
@@ -18,4 +22,17 @@ type RegisterClientResponse struct {
 	TokenEndpointAuthMethod authmethodtype.TokenEndpointAuthMethod `json:"token_endpoint_auth_method"`
 	RedirectUris            []string                               `json:"redirect_uris"`
 	Scopes                  []oauth.Scope                          `json:"scopes"`
+
+	Jwks                        json.RawMessage `json:"jwks,omitempty"`
+	JwksUri                     string          `json:"jwks_uri,omitempty"`
+	TokenExchangeSubjectClients []string        `json:"token_exchange_subject_clients,omitempty"`
+	TokenExchangeAudiences      []string        `json:"token_exchange_audiences,omitempty"`
+	RequestUris                 []string        `json:"request_uris,omitempty"`
+
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests"`
+	RequireSignedRequestObject         bool `json:"require_signed_request_object"`
+
+	authmethodtype.CertificateSubject
+	encryptiontype.TokenEncryption
+	signingtype.TokenSigning
 }
@@ -1,87 +1,99 @@
 package api
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/tokentype"
 )
 
 // TokenRequest represents the request to obtain an access token.
 type TokenRequest struct {
-	ClientId     string
-	ClientSecret string
+	// ClientCredentials identifies and authenticates the client, whatever the grant type
+	ClientCredentials
+
 	RefreshToken string
 	GrantType    granttype.GrantType
 	AuthCode     string
 	RedirectUri  string
 	CodeVerifier string
+	Username     string
+	Password     string
+	Scope        string
+	DeviceCode   string
+
+	// Token exchange parameters (RFC 8693, section 2.1)
+	SubjectToken       string
+	SubjectTokenType   tokentype.TokenType
+	ActorToken         string
+	ActorTokenType     tokentype.TokenType
+	RequestedTokenType tokentype.TokenType
+	Audience           []string
+
+	// Assertion is the signed JWT presented with the JWT bearer grant (RFC 7523, section 2.1)
+	Assertion string
+
+	// AuthorizationDetails requests fine-grained permissions, or narrows the ones of the grant (RFC 9396, section 6)
+	AuthorizationDetails string
+	// Resources names the resources the access token is requested for, or narrows the ones of the grant (RFC 8707)
+	Resources []string
 }
 
 // DecodeTokenRequest function to handle URL encoded data and Authorization header.
 func DecodeTokenRequest(r *http.Request, request *TokenRequest) error {
-	// Parse URL encoded form data
-	if err := r.ParseForm(); err != nil {
-		return fmt.Errorf("failed to parse form data: %w", err)
+	// Parse URL encoded form data and the client credentials, sent the same way for every grant type
+	credentials, err := DecodeClientCredentials(r)
+	if err != nil {
+		return err
 	}
+	request.ClientCredentials = credentials
 
 	// Extract grant_type from form data
 	grantTypeStr := r.FormValue("grant_type")
 	request.GrantType = granttype.GrantType(grantTypeStr)
+	request.AuthorizationDetails = strings.TrimSpace(r.FormValue("authorization_details"))
+	request.Resources = trimResources(r.Form["resource"])
 
 	// Handle different grant types
 	switch request.GrantType {
 	case granttype.AuthorizationCode:
-		// For Authorization Code grant type, client credentials, code, and redirect URI are required
-		request.ClientId = r.FormValue("client_id")
-		request.ClientSecret = r.FormValue("client_secret")
+		// For Authorization Code grant type, code and redirect URI are required
 		request.AuthCode = r.FormValue("code")
 		request.RedirectUri = r.FormValue("redirect_uri")
 		request.CodeVerifier = r.FormValue("code_verifier")
-	case granttype.Implicit, granttype.Password, granttype.ClientCredentials:
-		// For these grant types, client credentials are required
-		request.ClientId = r.FormValue("client_id")
-		request.ClientSecret = r.FormValue("client_secret")
+	case granttype.Implicit, granttype.ClientCredentials:
+		// For these grant types, the client credentials are all there is
+	case granttype.Password:
+		// For Resource Owner Password Credentials, the resource owner credentials travel with the client's
+		request.Username = r.FormValue("username")
+		request.Password = r.FormValue("password")
+		request.Scope = r.FormValue("scope")
+	case granttype.DeviceCode:
+		// For Device Code grant type, the device polls with its device_code; public clients send no secret
+		request.DeviceCode = r.FormValue("device_code")
+	case granttype.TokenExchange:
+		// For Token Exchange, the client swaps a subject (and optionally actor) token for a new token
+		request.SubjectToken = r.FormValue("subject_token")
+		request.SubjectTokenType = tokentype.TokenType(r.FormValue("subject_token_type"))
+		request.ActorToken = r.FormValue("actor_token")
+		request.ActorTokenType = tokentype.TokenType(r.FormValue("actor_token_type"))
+		request.RequestedTokenType = tokentype.TokenType(r.FormValue("requested_token_type"))
+		request.Audience = r.Form["audience"]
+		request.Scope = r.FormValue("scope")
+	case granttype.JWTBearer:
+		// For JWT Bearer grant type, the signed assertion authorizes the request; client_id is optional
+		request.Assertion = r.FormValue("assertion")
+		request.Scope = r.FormValue("scope")
 	case granttype.RefreshToken:
-		// For Refresh Token grant type, client credentials are optional
+		// For Refresh Token grant type, public clients may leave the client to the refresh token
 		request.RefreshToken = r.FormValue("refresh_token")
-		request.ClientId = r.FormValue("client_id")
-		request.ClientSecret = r.FormValue("client_secret")
-		// Check Authorization header if client credentials are not provided on form value
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			if strings.HasPrefix(authHeader, "Basic ") {
-				if err := parseBasicAuth(authHeader, request); err != nil {
-					return err
-				}
-			} else {
-				return errors.New("unsupported Authorization header format")
-			}
-		}
 	default:
 		return fmt.Errorf("unsupported grant_type: %s", request.GrantType)
 	}
-	return nil
-}
 
-// parseBasicAuth extracts client credentials from the Basic Authentication header.
-func parseBasicAuth(authHeader string, request *TokenRequest) error {
-	// Extract the Base64 encoded credentials
-	encodedCredentials := strings.TrimPrefix(authHeader, "Basic ")
-	decodedCredentials, err := base64.StdEncoding.DecodeString(encodedCredentials)
-	if err != nil {
-		return fmt.Errorf("failed to decode Basic Authentication header: %w", err)
-	}
-	// Split credentials into client Id and client secret
-	credentials := strings.SplitN(string(decodedCredentials), ":", 2)
-	if len(credentials) != 2 {
-		return errors.New("invalid Authorization header format")
-	}
-	request.ClientId = credentials[0]
-	request.ClientSecret = credentials[1]
 	return nil
 }
 
@@ -95,7 +107,7 @@ func (r *TokenRequest) Validate() error {
 	// Validate required fields based on GrantType
 	switch r.GrantType {
 	case granttype.AuthorizationCode:
-		// Ensure ClientId, ClientSecret, AuthCode, and RedirectUri are not empty
+		// Ensure ClientId, AuthCode, and RedirectUri are not empty
 		if strings.TrimSpace(r.ClientId) == "" {
 			return errors.New("client_id is required for authorization_code grant type")
 		}
@@ -110,13 +122,68 @@ func (r *TokenRequest) Validate() error {
 		if strings.TrimSpace(r.CodeVerifier) == "" {
 			return errors.New("code_verifier is required for authorization_code grant type when PKCE is used")
 		}
-	case granttype.Implicit, granttype.Password, granttype.ClientCredentials:
-		// Ensure ClientId and ClientSecret are not empty
+	case granttype.Password:
+		// Ensure client credentials and resource owner credentials are present
 		if strings.TrimSpace(r.ClientId) == "" {
-			return errors.New("client_id is required for the grant_type: " + string(r.GrantType))
+			return errors.New("client_id is required for password grant type")
 		}
-		if strings.TrimSpace(r.ClientSecret) == "" {
-			return errors.New("client_secret is required for the grant_type: " + string(r.GrantType))
+		if strings.TrimSpace(r.Username) == "" {
+			return errors.New("username is required for password grant type")
+		}
+		if r.Password == "" {
+			return errors.New("password is required for password grant type")
+		}
+		if strings.TrimSpace(r.Scope) != "" && !IsValidScope(r.Scope) {
+			return errors.New("the requested scope is invalid, unknown, or malformed")
+		}
+	case granttype.DeviceCode:
+		// Ensure the polling client identifies itself and the device authorization
+		if strings.TrimSpace(r.ClientId) == "" {
+			return errors.New("client_id is required for device_code grant type")
+		}
+		if strings.TrimSpace(r.DeviceCode) == "" {
+			return errors.New("device_code is required for device_code grant type")
+		}
+	case granttype.TokenExchange:
+		// Ensure the client authenticates and presents a supported subject token
+		if strings.TrimSpace(r.ClientId) == "" {
+			return errors.New("client_id is required for token-exchange grant type")
+		}
+		if strings.TrimSpace(r.SubjectToken) == "" {
+			return errors.New("subject_token is required for token-exchange grant type")
+		}
+		if !tokentype.IsExchangeable(r.SubjectTokenType) {
+			return fmt.Errorf("unsupported subject_token_type: %s", r.SubjectTokenType)
+		}
+		if r.ActorToken != "" && !tokentype.IsExchangeable(r.ActorTokenType) {
+			return fmt.Errorf("unsupported actor_token_type: %s", r.ActorTokenType)
+		}
+		if r.ActorToken == "" && r.ActorTokenType != "" {
+			return errors.New("actor_token_type must not be sent without actor_token")
+		}
+		if r.RequestedTokenType != "" && !tokentype.IsIssuable(r.RequestedTokenType) {
+			return fmt.Errorf("unsupported requested_token_type: %s", r.RequestedTokenType)
+		}
+		for _, audience := range r.Audience {
+			if strings.TrimSpace(audience) == "" {
+				return errors.New("audience cannot be empty")
+			}
+		}
+		if strings.TrimSpace(r.Scope) != "" && !IsValidScope(r.Scope) {
+			return errors.New("the requested scope is invalid, unknown, or malformed")
+		}
+	case granttype.JWTBearer:
+		// Ensure a single assertion is present
+		if strings.TrimSpace(r.Assertion) == "" {
+			return errors.New("assertion is required for jwt-bearer grant type")
+		}
+		if strings.TrimSpace(r.Scope) != "" && !IsValidScope(r.Scope) {
+			return errors.New("the requested scope is invalid, unknown, or malformed")
+		}
+	case granttype.Implicit, granttype.ClientCredentials:
+		// Ensure ClientId is not empty
+		if strings.TrimSpace(r.ClientId) == "" {
+			return errors.New("client_id is required for the grant_type: " + string(r.GrantType))
 		}
 	case granttype.RefreshToken:
 		// Ensure RefreshToken is not empty
@@ -1,11 +1,19 @@
 package api
 
+import "github.com/manuelrojas19/go-oauth2-server/oauth"
+
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
+	// IssuedTokenType is only present in token exchange responses (RFC 8693, section 2.2.1).
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	// IDToken is returned when the authorization request included the openid scope.
+	IDToken string `json:"id_token,omitempty"`
+	// AuthorizationDetails lists the fine-grained permissions the access token was issued for (RFC 9396, section 7).
+	AuthorizationDetails oauth.AuthorizationDetails `json:"authorization_details,omitempty"`
 }
 
 func NewTokenResponse(accessToken string, tokenType string, accessTokenExpiresIn int, refreshToken string, scope string) *TokenResponse {
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/url"
 	"regexp"
+	"slices"
 
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
@@ -12,12 +13,7 @@ import (
 
 // IsValidGrantType checks if the GrantType is valid
 func IsValidGrantType(gt granttype.GrantType) bool {
-	switch gt {
-	case granttype.AuthorizationCode,
-		granttype.Implicit,
-		granttype.ClientCredentials,
-		granttype.RefreshToken,
-		granttype.Password:
+	if slices.Contains(granttype.Supported, gt) {
 		log.Printf("Valid grant type: %s", gt)
 		return true
 	}
@@ -25,26 +21,15 @@ func IsValidGrantType(gt granttype.GrantType) bool {
 	return false
 }
 
-// IsValidResponseType checks if the ResponseType is valid
+// IsValidResponseType checks if the ResponseType is valid, including the combined
+// response types such as "code id_token"
 func IsValidResponseType(rt responsetype.ResponseType) bool {
-	switch rt {
-	case responsetype.Code,
-		responsetype.Token,
-		responsetype.IDToken:
-		return true
-	}
-	return false
+	return responsetype.IsSupported(rt)
 }
 
 // IsValidAuthMethod checks if the TokenEndpointAuthMethod is valid
 func IsValidAuthMethod(authMethod authmethodtype.TokenEndpointAuthMethod) bool {
-	switch authMethod {
-	case authmethodtype.ClientSecretBasic,
-		authmethodtype.ClientSecretPost,
-		authmethodtype.None:
-		return true
-	}
-	return false
+	return slices.Contains(authmethodtype.Supported, authMethod)
 }
 
 // IsValidRedirectUri checks if the redirect_uri is a valid URL
@@ -0,0 +1,14 @@
+package cache
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewReplayCache,
+		NewPushedAuthorizationStore,
+		NewRevocationList,
+		NewSecurityEventPublisher,
+	),
+)
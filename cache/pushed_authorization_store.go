@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const pushedAuthorizationKeyPrefix = "par"
+
+type pushedAuthorizationStore struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+// NewPushedAuthorizationStore creates a Redis backed store for pushed authorization requests (RFC 9126).
+func NewPushedAuthorizationStore(redisClient *redis.Client, logger *zap.Logger) services.PushedAuthorizationStore {
+	return &pushedAuthorizationStore{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Save keeps the authorization request parameters under requestUri until expiresAt.
+func (s *pushedAuthorizationStore) Save(requestUri string, params url.Values, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.redisClient.Set(ctx, s.key(requestUri), params.Encode(), time.Until(expiresAt)).Err(); err != nil {
+		s.logger.Error("Error saving pushed authorization request", zap.Error(err))
+		return fmt.Errorf("failed to save pushed authorization request: %w", err)
+	}
+	return nil
+}
+
+// Find returns the parameters pushed under requestUri, or nil when it is unknown or has expired.
+func (s *pushedAuthorizationStore) Find(requestUri string) (url.Values, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	encoded, err := s.redisClient.Get(ctx, s.key(requestUri)).Result()
+	if errors.Is(err, redis.Nil) {
+		s.logger.Debug("Pushed authorization request not found", zap.String("requestUri", requestUri))
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error("Error retrieving pushed authorization request", zap.Error(err))
+		return nil, fmt.Errorf("failed to retrieve pushed authorization request: %w", err)
+	}
+
+	params, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pushed authorization request: %w", err)
+	}
+	return params, nil
+}
+
+// Delete removes requestUri once the authorization request it refers to has been completed.
+func (s *pushedAuthorizationStore) Delete(requestUri string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.redisClient.Del(ctx, s.key(requestUri)).Err(); err != nil {
+		s.logger.Error("Error deleting pushed authorization request", zap.Error(err))
+		return fmt.Errorf("failed to delete pushed authorization request: %w", err)
+	}
+	return nil
+}
+
+func (s *pushedAuthorizationStore) key(requestUri string) string {
+	return fmt.Sprintf("%s:%s", pushedAuthorizationKeyPrefix, requestUri)
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const replayKeyPrefix = "replay"
+
+type replayCache struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+// NewReplayCache creates a Redis backed cache of one-time identifiers, such as JWT jti values.
+func NewReplayCache(redisClient *redis.Client, logger *zap.Logger) services.ReplayCache {
+	return &replayCache{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Remember records id within namespace until expiresAt. It returns false when the id had already been recorded,
+// which means the token carrying it is being replayed.
+func (c *replayCache) Remember(namespace, id string, expiresAt time.Time) (bool, error) {
+	key := fmt.Sprintf("%s:%s:%s", replayKeyPrefix, namespace, id)
+	ttl := time.Until(expiresAt)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fresh, err := c.redisClient.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		c.logger.Error("Error recording identifier in replay cache",
+			zap.String("namespace", namespace),
+			zap.Error(err),
+		)
+		return false, fmt.Errorf("failed to record identifier: %w", err)
+	}
+
+	if !fresh {
+		c.logger.Warn("Replayed identifier detected", zap.String("namespace", namespace), zap.String("id", id))
+	}
+	return fresh, nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	revokedKeyPrefix = "revoked"
+	// revokedIndexKey is a sorted set of the revoked jti values scored by their expiry, from which the list is served
+	revokedIndexKey = "revocation_list"
+	// RevocationChannel is the pub/sub channel every revoked jti is announced on, as a JSON services.RevokedToken
+	RevocationChannel = "oauth:revocations"
+)
+
+type revocationList struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+// NewRevocationList creates a Redis backed deny list of the jti values of revoked access tokens, which resource
+// servers validating tokens locally fetch or subscribe to.
+func NewRevocationList(redisClient *redis.Client, logger *zap.Logger) services.RevocationList {
+	return &revocationList{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Add denies jti until expiresAt, when the token carrying it expires anyway, and announces it on RevocationChannel.
+func (l *revocationList) Add(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		l.logger.Debug("Revoked token already expired, not denying it", zap.String("jti", jti))
+		return nil
+	}
+
+	announcement, err := json.Marshal(services.RevokedToken{Jti: jti, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to encode revoked token: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = l.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, l.key(jti), expiresAt.Unix(), ttl)
+		pipe.ZAdd(ctx, revokedIndexKey, redis.Z{Score: float64(expiresAt.Unix()), Member: jti})
+		pipe.ZRemRangeByScore(ctx, revokedIndexKey, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+		pipe.Publish(ctx, RevocationChannel, announcement)
+		return nil
+	})
+	if err != nil {
+		l.logger.Error("Error adding jti to revocation list", zap.String("jti", jti), zap.Error(err))
+		return fmt.Errorf("failed to add jti to revocation list: %w", err)
+	}
+
+	l.logger.Info("jti added to revocation list", zap.String("jti", jti), zap.Time("expiresAt", expiresAt))
+	return nil
+}
+
+// Contains reports whether jti belongs to a revoked token that has not expired yet.
+func (l *revocationList) Contains(jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := l.redisClient.Get(ctx, l.key(jti)).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		l.logger.Error("Error checking revocation list", zap.String("jti", jti), zap.Error(err))
+		return false, fmt.Errorf("failed to check revocation list: %w", err)
+	}
+	return true, nil
+}
+
+// List returns the revoked tokens that have not expired yet, soonest to expire first.
+func (l *revocationList) List() ([]services.RevokedToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	entries, err := l.redisClient.ZRangeByScoreWithScores(ctx, revokedIndexKey, &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil {
+		l.logger.Error("Error reading revocation list", zap.Error(err))
+		return nil, fmt.Errorf("failed to read revocation list: %w", err)
+	}
+
+	revoked := make([]services.RevokedToken, 0, len(entries))
+	for _, entry := range entries {
+		jti, _ := entry.Member.(string)
+		revoked = append(revoked, services.RevokedToken{Jti: jti, ExpiresAt: int64(entry.Score)})
+	}
+	return revoked, nil
+}
+
+func (l *revocationList) key(jti string) string {
+	return fmt.Sprintf("%s:%s", revokedKeyPrefix, jti)
+}
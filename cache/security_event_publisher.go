@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// SecurityEventChannel is the pub/sub channel security events are published on, as JSON services.SecurityEvent
+const SecurityEventChannel = "oauth:security-events"
+
+type securityEventPublisher struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+// NewSecurityEventPublisher creates a publisher of security events on a Redis pub/sub channel, which monitoring
+// systems subscribe to.
+func NewSecurityEventPublisher(redisClient *redis.Client, logger *zap.Logger) services.SecurityEventPublisher {
+	return &securityEventPublisher{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Publish logs the event and announces it on SecurityEventChannel.
+func (p *securityEventPublisher) Publish(event services.SecurityEvent) error {
+	p.logger.Warn("Security event",
+		zap.String("type", event.Type),
+		zap.String("clientId", event.ClientId),
+		zap.String("userId", event.UserId),
+		zap.String("familyId", event.FamilyId),
+		zap.String("refreshTokenId", event.RefreshTokenId),
+		zap.Time("occurredAt", event.OccurredAt),
+	)
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode security event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.redisClient.Publish(ctx, SecurityEventChannel, message).Err(); err != nil {
+		p.logger.Error("Error publishing security event", zap.String("type", event.Type), zap.Error(err))
+		return fmt.Errorf("failed to publish security event: %w", err)
+	}
+	return nil
+}
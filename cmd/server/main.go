@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/manuelrojas19/go-oauth2-server/cache"
 	"github.com/manuelrojas19/go-oauth2-server/configuration"
 	"github.com/manuelrojas19/go-oauth2-server/handlers"
 	"github.com/manuelrojas19/go-oauth2-server/internal"
@@ -22,6 +23,9 @@ func main() {
 		// Include the session module which handles session management
 		session.Module,
 
+		// Include the cache module which provides Redis backed caches such as the replay cache
+		cache.Module,
+
 		// Include the repositories module which provides data access layer (DAL) functionality
 		repositories.Module,
 
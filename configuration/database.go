@@ -23,6 +23,9 @@ func NewDatabaseConnection() (*gorm.DB, error) {
 		&store.User{},
 		&store.AuthCode{},
 		&store.AccessConsent{},
+		&store.DeviceAuthorization{},
+		&store.TrustedIssuer{},
+		&store.AuthorizationDetailType{},
 	)
 
 	if err != nil {
@@ -30,5 +33,12 @@ func NewDatabaseConnection() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Refresh tokens are stored as their SHA-256; hash the ones earlier versions stored in plain
+	err = datasource.Exec(`UPDATE refresh_tokens SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token !~ '^[0-9a-f]{64}$'`).Error
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	return datasource, nil
 }
@@ -3,7 +3,9 @@ package configuration
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"log"
@@ -12,7 +14,6 @@ import (
 )
 
 var (
-	JWTGenerationKeys KeyPair
 	JWEGenerationKeys KeyPair
 	once              sync.Once
 )
@@ -23,26 +24,37 @@ type KeyPair struct {
 	PublicKey  *rsa.PublicKey
 }
 
-// InitializeKeys initializes the JWT and JWE keys. It is thread-safe and will only run once.
+// InitializeKeys initializes the JWE keys and the key ring JWTs are signed with. It is thread-safe and will only
+// run once.
 func InitializeKeys() error {
 	var err error
 	once.Do(func() {
 		if err = loadKeys(); err != nil {
 			log.Printf("Failed to load keys: %v", err)
-			err = generateAndSaveKeys()
+			if err = generateAndSaveKeys(); err != nil {
+				return
+			}
 		}
+		err = SigningKeys.load()
 	})
 	return err
 }
 
-// GetJWTPublicKey returns the public key for JWT.
-func GetJWTPublicKey() (*rsa.PublicKey, error) {
-	return getPublicKey(JWTGenerationKeys.PublicKey, "JWT")
+// GetJWTSigningKey returns the active key of the key ring new JWTs signed with alg are signed with.
+func GetJWTSigningKey(alg string) (*SigningKey, error) {
+	return SigningKeys.Active(alg)
 }
 
-// GetJWTPrivateKey returns the private key for JWT.
-func GetJWTPrivateKey() (*rsa.PrivateKey, error) {
-	return getPrivateKey(JWTGenerationKeys.PrivateKey, "JWT")
+// GetJWTVerificationKey returns the key that verifies JWTs carrying the given kid header.
+func GetJWTVerificationKey(kid string) (*SigningKey, error) {
+	return SigningKeys.VerificationKey(kid)
+}
+
+// KeyID derives a key ID from the modulus of an RSA public key.
+func KeyID(publicKey *rsa.PublicKey) string {
+	hasher := sha1.New()
+	hasher.Write(publicKey.N.Bytes())
+	return hex.EncodeToString(hasher.Sum(nil))
 }
 
 // GetJWEPublicKey returns the public key for JWE.
@@ -72,13 +84,6 @@ func getPrivateKey(key *rsa.PrivateKey, keyType string) (*rsa.PrivateKey, error)
 func generateAndSaveKeys() error {
 	var err error
 
-	// Generate JWT keys
-	JWTGenerationKeys.PrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return fmt.Errorf("failed to generate JWT private key: %w", err)
-	}
-	JWTGenerationKeys.PublicKey = &JWTGenerationKeys.PrivateKey.PublicKey
-
 	// Generate JWE keys
 	JWEGenerationKeys.PrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -94,13 +99,6 @@ func generateAndSaveKeys() error {
 }
 
 func saveKeys() error {
-	if err := saveKeyToFile("jwt_private_key.pem", JWTGenerationKeys.PrivateKey); err != nil {
-		return err
-	}
-	if err := savePublicKeyToFile("jwt_public_key.pem", JWTGenerationKeys.PublicKey); err != nil {
-		return err
-	}
-
 	if err := saveKeyToFile("jwe_private_key.pem", JWEGenerationKeys.PrivateKey); err != nil {
 		return err
 	}
@@ -137,13 +135,6 @@ func saveToFile(filename, blockType string, bytes []byte) error {
 }
 
 func loadKeys() error {
-	if err := loadPrivateKeyFromFile("jwt_private_key.pem", &JWTGenerationKeys.PrivateKey); err != nil {
-		return err
-	}
-	if err := loadPublicKeyFromFile("jwt_public_key.pem", &JWTGenerationKeys.PublicKey); err != nil {
-		return err
-	}
-
 	if err := loadPrivateKeyFromFile("jwe_private_key.pem", &JWEGenerationKeys.PrivateKey); err != nil {
 		return err
 	}
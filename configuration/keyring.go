@@ -0,0 +1,523 @@
+package configuration
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// KeyState is the stage of a signing key in its rotation lifecycle.
+type KeyState string
+
+const (
+	// KeyStateNext keys are published ahead of use, so verifiers have them cached by the time they start signing
+	KeyStateNext KeyState = "next"
+	// KeyStateActive is the single key of its algorithm new tokens are signed with
+	KeyStateActive KeyState = "active"
+	// KeyStateRetiring keys no longer sign, but stay published until the tokens they signed have expired
+	KeyStateRetiring KeyState = "retiring"
+	// KeyStateRevoked keys were withdrawn after a compromise; tokens they signed are no longer accepted
+	KeyStateRevoked KeyState = "revoked"
+)
+
+// rsaSigningKeySize is the modulus size of generated RSA signing keys.
+const rsaSigningKeySize = 2048
+
+// SigningKey is a key of the key ring, identified by the kid header of the tokens it signs. Its private half is an
+// *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey depending on Algorithm.
+type SigningKey struct {
+	Id          string
+	Algorithm   string
+	State       KeyState
+	PrivateKey  crypto.Signer
+	CreatedAt   time.Time
+	ActivatedAt time.Time // when the key started signing, zero while it is next
+	RetiredAt   time.Time // when the key stopped signing, zero until it is retiring or revoked
+}
+
+// PublicKey returns the public half of the key, which verifiers find in the JWK Set.
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// KeyRing holds the keys tokens are signed and verified with. Every algorithm of SigningAlgorithms has its own
+// rotation: exactly one key per algorithm is active at a time, the next key is published before it takes over, and
+// retired keys remain valid for verification until the tokens they signed have expired. The ring is persisted to
+// SigningKeysFile on every change.
+type KeyRing struct {
+	mu      sync.RWMutex
+	keys    []*SigningKey
+	version int
+}
+
+// SigningKeys is the key ring of the server, loaded by InitializeKeys.
+var SigningKeys = &KeyRing{}
+
+// Active returns a copy of the key new tokens signed with alg are signed with.
+func (r *KeyRing) Active(alg string) (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if key := r.find(alg, KeyStateActive); key != nil {
+		active := *key
+		return &active, nil
+	}
+	return nil, fmt.Errorf("no active %s signing key", alg)
+}
+
+// VerificationKey returns a copy of the key a token with the given kid header is verified with. Revoked and unknown
+// keys are refused, so tokens they signed fail verification.
+func (r *KeyRing) VerificationKey(kid string) (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Id != kid {
+			continue
+		}
+		if key.State == KeyStateRevoked {
+			return nil, fmt.Errorf("signing key %s has been revoked", kid)
+		}
+		verification := *key
+		return &verification, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+// Published returns copies of the keys the JWK Set must carry: the next, active and retiring ones.
+func (r *KeyRing) Published() []SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var published []SigningKey
+	for _, key := range r.keys {
+		if key.State != KeyStateRevoked {
+			published = append(published, *key)
+		}
+	}
+	return published
+}
+
+// Keys returns copies of every key of the ring, revoked ones included.
+func (r *KeyRing) Keys() []SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]SigningKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	return keys
+}
+
+// Version changes whenever the keys of the ring change, so caches of the JWK Set know when to refresh.
+func (r *KeyRing) Version() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// RotateIfDue performs the scheduled rotation of every algorithm whose active key has signed for
+// SigningKeyRotationInterval, and drops retired keys once SigningKeyRetirementPeriod has passed. It reports whether
+// the ring changed.
+func (r *KeyRing) RotateIfDue(now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, err := r.update(now)
+	if err != nil || !changed {
+		return false, err
+	}
+	r.version++
+	return true, r.save()
+}
+
+// Rotate performs a scheduled rotation of every algorithm right away: the next keys become active, the active keys
+// start retiring and new next keys are generated. Tokens signed with the retiring keys remain valid.
+func (r *KeyRing) Rotate(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, alg := range SigningAlgorithms {
+		if err := r.promote(now, alg, KeyStateRetiring); err != nil {
+			return err
+		}
+	}
+	r.version++
+	return r.save()
+}
+
+// EmergencyRotate replaces compromised keys: the active and next keys of every algorithm are revoked, since they
+// were all stored alongside each other, and fresh active and next keys are generated. Tokens signed with the revoked
+// keys stop verifying at once, while tokens signed with keys that were already retiring are unaffected.
+func (r *KeyRing) EmergencyRotate(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.State == KeyStateActive || key.State == KeyStateNext {
+			key.State = KeyStateRevoked
+			key.RetiredAt = now
+		}
+	}
+	for _, alg := range SigningAlgorithms {
+		if err := r.promote(now, alg, KeyStateRevoked); err != nil {
+			return err
+		}
+	}
+	r.version++
+	return r.save()
+}
+
+// update brings the ring in line with the configuration at now: expired keys are dropped, algorithms no longer in
+// SigningAlgorithms stop signing, every configured algorithm whose active key is missing or due is rotated, and
+// every other one gets a next key if it lacks one. It reports whether the ring changed. The caller holds the write
+// lock.
+func (r *KeyRing) update(now time.Time) (bool, error) {
+	changed := r.purge(now)
+	if r.withdraw(now) {
+		changed = true
+	}
+	for _, alg := range SigningAlgorithms {
+		active := r.find(alg, KeyStateActive)
+		if active != nil && now.Before(active.ActivatedAt.Add(SigningKeyRotationInterval)) {
+			if r.find(alg, KeyStateNext) == nil {
+				next, err := generateSigningKey(now, alg)
+				if err != nil {
+					return false, err
+				}
+				r.keys = append(r.keys, next)
+				changed = true
+			}
+			continue
+		}
+		if err := r.promote(now, alg, KeyStateRetiring); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// promote makes the next key of alg active, generating one when there is none, moves the previously active key to
+// retiredState and generates a new next key. The caller holds the write lock.
+func (r *KeyRing) promote(now time.Time, alg string, retiredState KeyState) error {
+	next := r.find(alg, KeyStateNext)
+	if next == nil {
+		generated, err := generateSigningKey(now, alg)
+		if err != nil {
+			return err
+		}
+		r.keys = append(r.keys, generated)
+		next = generated
+	}
+
+	upcoming, err := generateSigningKey(now, alg)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range r.keys {
+		if key.Algorithm == alg && key.State == KeyStateActive {
+			key.State = retiredState
+			key.RetiredAt = now
+		}
+	}
+	next.State = KeyStateActive
+	next.ActivatedAt = now
+	r.keys = append(r.keys, upcoming)
+	return nil
+}
+
+// withdraw retires the active keys of algorithms removed from SigningAlgorithms and drops their next keys, which
+// never signed anything. It reports whether any key changed. The caller holds the write lock.
+func (r *KeyRing) withdraw(now time.Time) bool {
+	changed := false
+	kept := r.keys[:0]
+	for _, key := range r.keys {
+		if slices.Contains(SigningAlgorithms, key.Algorithm) {
+			kept = append(kept, key)
+			continue
+		}
+		switch key.State {
+		case KeyStateNext:
+			changed = true
+			continue
+		case KeyStateActive:
+			key.State = KeyStateRetiring
+			key.RetiredAt = now
+			changed = true
+		}
+		kept = append(kept, key)
+	}
+	r.keys = kept
+	return changed
+}
+
+// purge drops the retiring and revoked keys retired for longer than SigningKeyRetirementPeriod, by which time every
+// token they signed has expired. It reports whether any key was dropped. The caller holds the write lock.
+func (r *KeyRing) purge(now time.Time) bool {
+	kept := r.keys[:0]
+	for _, key := range r.keys {
+		retired := key.State == KeyStateRetiring || key.State == KeyStateRevoked
+		if retired && now.After(key.RetiredAt.Add(SigningKeyRetirementPeriod)) {
+			continue
+		}
+		kept = append(kept, key)
+	}
+	purged := len(kept) != len(r.keys)
+	r.keys = kept
+	return purged
+}
+
+// find returns the first key of alg in state. The caller holds the lock.
+func (r *KeyRing) find(alg string, state KeyState) *SigningKey {
+	for _, key := range r.keys {
+		if key.Algorithm == alg && key.State == state {
+			return key
+		}
+	}
+	return nil
+}
+
+// generateSigningKey creates a key of the type alg signs with: RSA for RS256 and PS256, ECDSA on P-256 or P-384 for
+// ES256 and ES384, and Ed25519 for EdDSA.
+func generateSigningKey(now time.Time, alg string) (*SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch alg {
+	case signingtype.RS256, signingtype.PS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaSigningKeySize)
+	case signingtype.ES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case signingtype.ES384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case signingtype.EdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s signing key: %w", alg, err)
+	}
+
+	id, err := SigningKeyID(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		Id:         id,
+		Algorithm:  alg,
+		State:      KeyStateNext,
+		PrivateKey: privateKey,
+		CreatedAt:  now,
+	}, nil
+}
+
+// SigningKeyID derives a key ID from a public key: from the modulus of RSA keys, as KeyID does, and from the DER
+// encoding of any other key.
+func SigningKeyID(publicKey crypto.PublicKey) (string, error) {
+	if rsaKey, ok := publicKey.(*rsa.PublicKey); ok {
+		return KeyID(rsaKey), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	sum := sha1.Sum(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// storedSigningKey is the form a signing key is persisted in.
+type storedSigningKey struct {
+	Id          string    `json:"kid"`
+	Algorithm   string    `json:"alg"`
+	State       KeyState  `json:"state"`
+	PrivateKey  string    `json:"private_key"` // PEM encoded PKCS #8 private key
+	CreatedAt   time.Time `json:"created_at"`
+	ActivatedAt time.Time `json:"activated_at"`
+	RetiredAt   time.Time `json:"retired_at"`
+}
+
+// load reads the ring from SigningKeysFile and brings it in line with the configured algorithms. A server upgraded
+// from a single key pair adopts jwt_private_key.pem as its RS256 key, so the tokens it already signed stay valid.
+func (r *KeyRing) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	bytes, err := os.ReadFile(SigningKeysFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		r.adoptLegacyKey(now)
+	case err != nil:
+		return fmt.Errorf("failed to read file %s: %w", SigningKeysFile, err)
+	default:
+		if r.keys, err = decodeSigningKeys(bytes); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.update(now); err != nil {
+		return err
+	}
+	r.version++
+	return r.save()
+}
+
+// adoptLegacyKey adds the RSA key of jwt_private_key.pem, if there is one, as the next RS256 key, or as a retiring
+// key when RS256 is not enabled. The caller holds the write lock.
+func (r *KeyRing) adoptLegacyKey(now time.Time) {
+	var legacy *rsa.PrivateKey
+	if err := loadPrivateKeyFromFile("jwt_private_key.pem", &legacy); err != nil {
+		return
+	}
+
+	key := &SigningKey{
+		Id:         KeyID(&legacy.PublicKey),
+		Algorithm:  signingtype.RS256,
+		State:      KeyStateNext,
+		PrivateKey: legacy,
+		CreatedAt:  now,
+	}
+	if !slices.Contains(SigningAlgorithms, signingtype.RS256) {
+		key.State = KeyStateRetiring
+		key.RetiredAt = now
+	}
+	r.keys = append(r.keys, key)
+}
+
+// decodeSigningKeys parses the keys persisted by save. Keys written before other algorithms were supported are RS256
+// keys in PKCS #1 form.
+func decodeSigningKeys(bytes []byte) ([]*SigningKey, error) {
+	var stored []storedSigningKey
+	if err := json.Unmarshal(bytes, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode signing keys from %s: %w", SigningKeysFile, err)
+	}
+
+	keys := make([]*SigningKey, 0, len(stored))
+	for _, s := range stored {
+		block, _ := pem.Decode([]byte(s.PrivateKey))
+		if block == nil {
+			return nil, fmt.Errorf("invalid or missing PEM block for signing key %s", s.Id)
+		}
+
+		var parsed interface{}
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		default:
+			err = fmt.Errorf("unexpected PEM block %s", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", s.Id, err)
+		}
+		privateKey, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s cannot sign", s.Id)
+		}
+
+		alg := s.Algorithm
+		if alg == "" {
+			alg = signingtype.RS256
+		}
+		keys = append(keys, &SigningKey{
+			Id:          s.Id,
+			Algorithm:   alg,
+			State:       s.State,
+			PrivateKey:  privateKey,
+			CreatedAt:   s.CreatedAt,
+			ActivatedAt: s.ActivatedAt,
+			RetiredAt:   s.RetiredAt,
+		})
+	}
+	return keys, nil
+}
+
+// save writes the ring to SigningKeysFile, readable by the server's user only. The caller holds the lock.
+func (r *KeyRing) save() error {
+	stored := make([]storedSigningKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to marshal signing key %s: %w", key.Id, err)
+		}
+		stored = append(stored, storedSigningKey{
+			Id:          key.Id,
+			Algorithm:   key.Algorithm,
+			State:       key.State,
+			PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+			CreatedAt:   key.CreatedAt,
+			ActivatedAt: key.ActivatedAt,
+			RetiredAt:   key.RetiredAt,
+		})
+	}
+
+	bytes, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode signing keys: %w", err)
+	}
+	if err := os.WriteFile(SigningKeysFile, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", SigningKeysFile, err)
+	}
+	return nil
+}
+
+// keyRotationCheckInterval is how often the scheduler checks whether the active key is due for rotation.
+const keyRotationCheckInterval = time.Minute
+
+// ScheduleKeyRotation rotates the signing keys in the background for as long as the application runs.
+func ScheduleKeyRotation(lc fx.Lifecycle, logger *zap.Logger) {
+	stop := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				ticker := time.NewTicker(keyRotationCheckInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case now := <-ticker.C:
+						changed, err := SigningKeys.RotateIfDue(now)
+						if err != nil {
+							logger.Error("Scheduled signing key rotation failed", zap.Error(err))
+							continue
+						}
+						if changed {
+							logger.Info("Signing key ring updated", zap.Int("publishedKeys", len(SigningKeys.Published())))
+						}
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+}
@@ -37,5 +37,8 @@ var Module = fx.Options(
 
 		// Initialize cryptographic keys
 		InitializeKeys,
+
+		// Rotate the signing keys on schedule
+		ScheduleKeyRotation,
 	),
 )
@@ -4,4 +4,27 @@ import "time"
 
 const (
 	AuthCodeExpireTime = 10 * time.Minute
+
+	// AccessTokenLifetime and IDTokenLifetime are how long the JWTs signed by the key ring stay valid. A retiring key
+	// must stay published for at least as long.
+	AccessTokenLifetime = 1 * time.Hour
+	IDTokenLifetime     = 1 * time.Hour
+
+	// DeviceCodeExpireTime is how long a device_code/user_code pair stays valid (RFC 8628, section 3.2).
+	DeviceCodeExpireTime = 10 * time.Minute
+	// DeviceCodePollInterval is the minimum time a device must wait between token polls.
+	DeviceCodePollInterval = 5 * time.Second
+	// DeviceCodeSlowDownIncrement is added to the polling interval every time a device polls too fast.
+	DeviceCodeSlowDownIncrement = 5 * time.Second
+
+	// AssertionMaxLifetime caps how far in the future a JWT bearer assertion may expire, bounding the replay cache.
+	AssertionMaxLifetime = 1 * time.Hour
+
+	// PushedAuthorizationRequestExpireTime is how long a request_uri from the PAR endpoint can be used (RFC 9126, section 2.2).
+	PushedAuthorizationRequestExpireTime = 60 * time.Second
+
+	// DPoPProofMaxAge is how long after its iat a DPoP proof is accepted, and how long its jti is remembered (RFC 9449, section 11.1).
+	DPoPProofMaxAge = 60 * time.Second
+	// DPoPProofClockSkew tolerates DPoP proofs issued slightly in the future by clients with a fast clock.
+	DPoPProofClockSkew = 10 * time.Second
 )
@@ -1,7 +1,14 @@
 package configuration
 
 import (
+	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 )
 
 var (
@@ -16,15 +23,111 @@ var (
 	GoogleTokenURL     string
 	GoogleUserInfoURL  string
 	Scopes             string
+	IssuerURL          string
+	ClientSecretKey    string
+	TLSAddr            string
+	TLSCertFile        string
+	TLSKeyFile         string
+	TLSClientCAFile    string
+
+	AccessTokenAudience     string
+	AccessTokenLegacyClaims bool
+
+	SigningAlgorithms          []string
+	DefaultSigningAlgorithm    string
+	SigningKeysFile            string
+	SigningKeyRotationInterval time.Duration
+	SigningKeyRetirementPeriod time.Duration
+	AdminApiKey                string
 )
 
 func LoadSecrets() error {
 	loadGoogleSecrets()
 	loadDbSecrets()
 	loadRedisSecrets()
+	loadServerSecrets()
+	loadTLSSecrets()
+	return loadKeyRotationSecrets()
+}
+
+func loadServerSecrets() {
+	// IssuerURL is the public base URL of this server, used to build absolute URLs handed out to clients
+	IssuerURL = strings.TrimRight(os.Getenv("ISSUER_URL"), "/")
+	if IssuerURL == "" {
+		IssuerURL = "http://localhost:8080"
+	}
+	// ClientSecretKey encrypts the secrets of client_secret_jwt clients, which must be read back to verify their assertions
+	ClientSecretKey = os.Getenv("CLIENT_SECRET_KEY")
+	// AccessTokenAudience is the aud of access tokens requested without resource indicators
+	AccessTokenAudience = os.Getenv("ACCESS_TOKEN_AUDIENCE")
+	if AccessTokenAudience == "" {
+		AccessTokenAudience = IssuerURL
+	}
+	// AccessTokenLegacyClaims adds the clientId, userId and type claims of the pre-RFC 9068 access tokens, for
+	// resource servers that still read them
+	AccessTokenLegacyClaims, _ = strconv.ParseBool(os.Getenv("ACCESS_TOKEN_LEGACY_CLAIMS"))
+}
+
+func loadKeyRotationSecrets() error {
+	// DefaultSigningAlgorithm signs the tokens of clients that registered no algorithm of their own
+	DefaultSigningAlgorithm = os.Getenv("SIGNING_ALG")
+	if !slices.Contains(signingtype.Supported, DefaultSigningAlgorithm) {
+		DefaultSigningAlgorithm = signingtype.RS256
+	}
+	// SigningAlgorithms are the algorithms the key ring keeps keys for, and so the ones clients may register
+	SigningAlgorithms = nil
+	for _, alg := range strings.Split(os.Getenv("SIGNING_ALGS"), ",") {
+		alg = strings.TrimSpace(alg)
+		if slices.Contains(signingtype.Supported, alg) && !slices.Contains(SigningAlgorithms, alg) {
+			SigningAlgorithms = append(SigningAlgorithms, alg)
+		}
+	}
+	if len(SigningAlgorithms) == 0 {
+		SigningAlgorithms = slices.Clone(signingtype.Supported)
+	}
+	if !slices.Contains(SigningAlgorithms, DefaultSigningAlgorithm) {
+		SigningAlgorithms = append(SigningAlgorithms, DefaultSigningAlgorithm)
+	}
+	// SigningKeysFile persists the key ring, private keys included, so it must only be readable by the server
+	SigningKeysFile = os.Getenv("SIGNING_KEYS_FILE")
+	if SigningKeysFile == "" {
+		SigningKeysFile = "signing_keys.json"
+	}
+	// SigningKeyRotationInterval is how long a key signs before the next one takes over
+	SigningKeyRotationInterval = durationEnv("SIGNING_KEY_ROTATION_INTERVAL", 30*24*time.Hour)
+	// SigningKeyRetirementPeriod is how long a key stays published after it stops signing; it must exceed the
+	// lifetime of the tokens it signed
+	SigningKeyRetirementPeriod = durationEnv("SIGNING_KEY_RETIREMENT_PERIOD", 24*time.Hour)
+	if longest := max(AccessTokenLifetime, IDTokenLifetime); SigningKeyRetirementPeriod < longest {
+		return fmt.Errorf("SIGNING_KEY_RETIREMENT_PERIOD %s is shorter than the %s lifetime of the tokens a key signs",
+			SigningKeyRetirementPeriod, longest)
+	}
+	// AdminApiKey authenticates calls to the /admin endpoints, which are disabled while it is unset
+	AdminApiKey = os.Getenv("ADMIN_API_KEY")
 	return nil
 }
 
+// durationEnv parses a duration such as "720h" from an environment variable, falling back to fallback when it is
+// unset or malformed.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func loadTLSSecrets() {
+	// The TLS listener, which accepts client certificates for mutual TLS, only starts when a certificate is configured
+	TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	TLSClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	TLSAddr = os.Getenv("TLS_ADDR")
+	if TLSAddr == "" {
+		TLSAddr = ":8443"
+	}
+}
+
 func loadRedisSecrets() {
 	RedisAddr = os.Getenv("REDIS_URL")
 	RedisPassword = os.Getenv("REDIS_PASSWORD")
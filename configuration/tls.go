@@ -0,0 +1,55 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	clientCAPool     *x509.CertPool
+	clientCAPoolErr  error
+	clientCAPoolOnce sync.Once
+)
+
+// TLSEnabled reports whether a server certificate is configured for the mutual TLS listener.
+func TLSEnabled() bool {
+	return TLSCertFile != "" && TLSKeyFile != ""
+}
+
+// NewTLSConfig builds the configuration of the TLS listener. Client certificates are requested but not verified
+// during the handshake: each client is checked against its own registration when it authenticates (RFC 8705).
+func NewTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(TLSCertFile, TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequestClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// GetClientCAPool returns the certificate authorities trusted to issue certificates for tls_client_auth clients,
+// read from TLS_CLIENT_CA_FILE or, when it is not set, the system roots.
+func GetClientCAPool() (*x509.CertPool, error) {
+	clientCAPoolOnce.Do(func() {
+		if TLSClientCAFile == "" {
+			clientCAPool, clientCAPoolErr = x509.SystemCertPool()
+			return
+		}
+		pem, err := os.ReadFile(TLSClientCAFile)
+		if err != nil {
+			clientCAPoolErr = fmt.Errorf("failed to read client CA file: %w", err)
+			return
+		}
+		clientCAPool = x509.NewCertPool()
+		if !clientCAPool.AppendCertsFromPEM(pem) {
+			clientCAPoolErr = fmt.Errorf("no certificates found in %s", TLSClientCAFile)
+		}
+	})
+	return clientCAPool, clientCAPoolErr
+}
@@ -1,8 +1,11 @@
 package errors
 
 const (
-	ErrUserNotAuthenticated    = "user not authenticated"
-	ErrConsentRequired         = "user consent required"
-	ErrUnsupportedResponseType = "the authorization server does not support obtaining an authorization code using this method"
-	ErrInvalidRedirectUri      = "redirect URI is not registered for client"
+	ErrUserNotAuthenticated        = "user not authenticated"
+	ErrConsentRequired             = "user consent required"
+	ErrUnsupportedResponseType     = "the authorization server does not support obtaining an authorization code using this method"
+	ErrInvalidRedirectUri          = "redirect URI is not registered for client"
+	ErrUnregisteredResponseType    = "response type is not registered for client"
+	ErrPushedAuthorizationRequired = "client must use a pushed authorization request"
+	ErrSignedRequestObjectRequired = "client must use a signed request object"
 )
@@ -30,6 +30,8 @@ func (h *acceptConsentHandler) AcceptConsent(w http.ResponseWriter, r *http.Requ
 	scope := r.FormValue("scope")
 	redirectUri := r.FormValue("redirect_uri")
 	responseType := r.FormValue("response_type")
+	authorizationDetails := r.FormValue("authorization_details")
+	resources := r.Form["resource"]
 	consent := r.FormValue("consent")
 
 	// URL-encode parameters for redirect
@@ -42,6 +44,12 @@ func (h *acceptConsentHandler) AcceptConsent(w http.ResponseWriter, r *http.Requ
 		// Redirect back to the original authorization endpoint with original parameters
 		redirectURL := fmt.Sprintf("/oauth/authorize?client_id=%s&scope=%s&redirect_uri=%s&response_type=%s",
 			encodedClientId, encodedScope, encodedRedirectUri, encodedResponseType)
+		if authorizationDetails != "" {
+			redirectURL += "&authorization_details=" + url.QueryEscape(authorizationDetails)
+		}
+		for _, resource := range resources {
+			redirectURL += "&resource=" + url.QueryEscape(resource)
+		}
 		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 	} else {
 		// Handle consent denial (e.g., redirect with an access_denied error)
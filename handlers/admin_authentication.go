@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+// authenticateAdmin checks the request carries the admin API key as a bearer token and writes the error response
+// when it does not. The admin endpoints answer 404 while no ADMIN_API_KEY is configured.
+func authenticateAdmin(w http.ResponseWriter, r *http.Request, log *zap.Logger) bool {
+	if configuration.AdminApiKey == "" {
+		log.Warn("Admin endpoint called while the admin API is disabled", zap.String("url", r.URL.Path))
+		http.NotFound(w, r)
+		return false
+	}
+
+	key, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(key), []byte(configuration.AdminApiKey)) != 1 {
+		log.Warn("Admin endpoint called without a valid admin API key", zap.String("url", r.URL.Path))
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		utils.RespondWithJSON(w, http.StatusUnauthorized, api.ErrorResponseBody(api.ErrInvalidToken))
+		return false
+	}
+	return true
+}
@@ -1,34 +1,52 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/api"
-	"github.com/manuelrojas19/go-oauth2-server/errors"
+	oautherrors "github.com/manuelrojas19/go-oauth2-server/errors"
 	"github.com/manuelrojas19/go-oauth2-server/oauth"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
 	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
 	"go.uber.org/zap"
 )
 
 type authorizeHandler struct {
-	authorizationService services.AuthorizationService
-	log                  *zap.Logger
+	authorizationService       services.AuthorizationService
+	pushedAuthorizationService services.PushedAuthorizationService
+	requestObjectService       services.RequestObjectService
+	log                        *zap.Logger
 }
 
-func NewAuthorizeHandler(authorizationService services.AuthorizationService, logger *zap.Logger) AuthorizeHandler {
+func NewAuthorizeHandler(authorizationService services.AuthorizationService,
+	pushedAuthorizationService services.PushedAuthorizationService,
+	requestObjectService services.RequestObjectService,
+	logger *zap.Logger,
+) AuthorizeHandler {
 	return &authorizeHandler{
-		authorizationService: authorizationService,
-		log:                  logger,
+		authorizationService:       authorizationService,
+		pushedAuthorizationService: pushedAuthorizationService,
+		requestObjectService:       requestObjectService,
+		log:                        logger,
 	}
 }
 
 func (a authorizeHandler) Authorize(w http.ResponseWriter, r *http.Request) {
 	a.log.Info("Entered Authorize handler", zap.String("method", r.Method), zap.String("url", r.URL.String()))
 
-	// Decode the authorization request
-	authRequest, err := api.DecodeAuthorizeRequest(r)
+	// Decode the authorization request, either inline, in a signed request object or pushed beforehand
+	authRequest, err := a.decodeAuthorizeRequest(r)
+	if errors.Is(err, api.ErrInvalidRequestUri) || errors.Is(err, api.ErrInvalidRequestObject) {
+		// The redirect URI cannot be trusted without a valid request, so the error is not redirected
+		utils.HandleErrorResponse(w, a.log, err)
+		return
+	}
 	if err != nil {
 		a.log.Error("Failed to decode authorization request", zap.Error(err))
 		handleAuthError(w, r, "", "", api.ErrorResponseBody(api.ErrInvalidRequest), a.log)
@@ -39,20 +57,25 @@ func (a authorizeHandler) Authorize(w http.ResponseWriter, r *http.Request) {
 	// Validate the authorization request
 	if err := authRequest.Validate(); err != nil {
 		a.log.Error("Invalid authorization request", zap.Error(err))
-		handleAuthError(w, r, authRequest.RedirectUri, authRequest.State, api.ErrorResponseBody(api.ErrInvalidRequest), a.log)
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, usesFragment(authRequest), api.ErrorResponseBody(api.ErrInvalidRequest), a.log)
 		return
 	}
 	a.log.Info("Authorization request validated", zap.Any("clientId", authRequest))
 
 	// Create AuthorizeCommand from the request
 	command := &services.AuthorizeCommand{
-		ClientId:            authRequest.ClientId,
-		Scope:               authRequest.Scope,
-		RedirectUri:         authRequest.RedirectUri,
-		ResponseType:        authRequest.ResponseType,
-		State:               authRequest.State,
-		CodeChallenge:       authRequest.CodeChallenge,
-		CodeChallengeMethod: authRequest.CodeChallengeMethod,
+		ClientId:             authRequest.ClientId,
+		Scope:                authRequest.Scope,
+		RedirectUri:          authRequest.RedirectUri,
+		ResponseType:         authRequest.ResponseType,
+		State:                authRequest.State,
+		CodeChallenge:        authRequest.CodeChallenge,
+		CodeChallengeMethod:  authRequest.CodeChallengeMethod,
+		Nonce:                authRequest.Nonce,
+		RequestUri:           authRequest.RequestUri,
+		SignedRequestObject:  authRequest.Request != "",
+		AuthorizationDetails: authRequest.AuthorizationDetails,
+		Resources:            authRequest.Resources,
 	}
 	a.log.Info("AuthorizeCommand created", zap.Any("command", command))
 
@@ -66,66 +89,190 @@ func (a authorizeHandler) Authorize(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Authorize the request
-	authCode, err := a.authorizationService.Authorize(command)
+	authResponse, err := a.authorizationService.Authorize(command)
 	if err != nil {
 		a.log.Error("Authorization service error", zap.Error(err), zap.Stack("stacktrace"))
 		handleAuthorizationError(err, w, r, authRequest, command, a.log)
 		return
 	}
-	a.log.Info("Authorization successful", zap.String("authCode", authCode.Code))
+	a.log.Info("Authorization successful", zap.String("responseType", string(authResponse.ResponseType)))
+
+	// A pushed request is answered only once
+	if authRequest.RequestUri != "" {
+		if err := a.pushedAuthorizationService.Complete(authRequest.RequestUri); err != nil {
+			a.log.Warn("Failed to invalidate request_uri", zap.Error(err))
+		}
+	}
 
 	// Build the redirect URL
-	redirectURL := getRedirectURL(authRequest, authCode)
-	a.log.Info("Redirect URL built", zap.String("getRedirectURL", redirectURL))
+	redirectURL := getRedirectURL(authRequest, authResponse)
+	a.log.Info("Redirect URL built", zap.Bool("fragment", authResponse.UsesFragment()))
 
-	// Redirect to the redirect_uri with the authorization code
+	// Redirect to the redirect_uri with the authorization response
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// decodeAuthorizeRequest reads the authorization parameters from the request. A request_uri issued by the PAR
+// endpoint replaces any other inline parameters (RFC 9126, section 4), while a request object sent by value or by
+// reference is verified and merged with them (RFC 9101).
+func (a authorizeHandler) decodeAuthorizeRequest(r *http.Request) (*api.AuthorizeRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse form data: %w", err)
+	}
+
+	params := r.Form
+	requestUri := params.Get("request_uri")
+
+	switch {
+	case strings.HasPrefix(requestUri, utils.RequestUriPrefix):
+		resolved, err := a.pushedAuthorizationService.Resolve(requestUri, params.Get("client_id"))
+		if err != nil {
+			return nil, err
+		}
+		a.log.Info("Pushed authorization request resolved", zap.String("clientId", resolved.Get("client_id")))
+		params = resolved
+	case requestUri != "" || params.Get("request") != "":
+		resolved, err := a.requestObjectService.Resolve(params)
+		if err != nil {
+			return nil, err
+		}
+		a.log.Info("Request object resolved", zap.String("clientId", resolved.Get("client_id")))
+		params = resolved
+	}
+
+	return api.DecodeAuthorizeRequestFromValues(params)
+}
+
 func handleAuthorizationError(err error, w http.ResponseWriter, r *http.Request, authRequest *api.AuthorizeRequest, command *services.AuthorizeCommand, log *zap.Logger) {
-	queryParams := fmt.Sprintf("client_id=%s&scope=%s&redirect_uri=%s&response_type=%s",
-		authRequest.ClientId, authRequest.Scope, authRequest.RedirectUri, string(authRequest.ResponseType))
+	// Carry the whole authorization request through login and consent so it can be resumed afterwards
+	queryParams := url.Values{}
+	for key, value := range map[string]string{
+		"client_id":             authRequest.ClientId,
+		"scope":                 authRequest.Scope,
+		"redirect_uri":          authRequest.RedirectUri,
+		"response_type":         string(authRequest.ResponseType),
+		"state":                 authRequest.State,
+		"code_challenge":        authRequest.CodeChallenge,
+		"code_challenge_method": authRequest.CodeChallengeMethod,
+		"nonce":                 authRequest.Nonce,
+		"authorization_details": authRequest.AuthorizationDetails,
+	} {
+		if value != "" {
+			queryParams.Set(key, value)
+		}
+	}
+	for _, resource := range authRequest.Resources {
+		queryParams.Add("resource", resource)
+	}
+
+	// A pushed request is resumed by reference, as its parameters must not travel through the browser, and a signed
+	// one is resumed with its request object, so it is verified again
+	switch {
+	case authRequest.RequestUri != "":
+		queryParams = url.Values{
+			"client_id":   {authRequest.ClientId},
+			"request_uri": {authRequest.RequestUri},
+		}
+	case authRequest.Request != "":
+		queryParams = url.Values{
+			"client_id": {authRequest.ClientId},
+			"request":   {authRequest.Request},
+		}
+	}
+
+	fragment := usesFragment(authRequest)
 
-	switch err.Error() {
-	case errors.ErrUserNotAuthenticated:
-		loginURL := fmt.Sprintf("/oauth/login?%s", queryParams)
+	switch {
+	case err.Error() == oautherrors.ErrUserNotAuthenticated:
+		loginURL := fmt.Sprintf("/oauth/login?%s", queryParams.Encode())
 		log.Warn("User not authenticated, redirecting to login", zap.String("loginURL", loginURL))
 		http.Redirect(w, r, loginURL, http.StatusSeeOther)
-	case errors.ErrConsentRequired:
-		consentURL := fmt.Sprintf("/oauth/consent?%s", queryParams)
+	case err.Error() == oautherrors.ErrConsentRequired:
+		consentURL := fmt.Sprintf("/oauth/consent?%s", queryParams.Encode())
 		log.Warn("User consent required, redirecting to consent", zap.String("consentURL", consentURL))
 		http.Redirect(w, r, consentURL, http.StatusSeeOther)
-	case errors.ErrUnsupportedResponseType:
-		handleAuthError(w, r, authRequest.RedirectUri, authRequest.State, api.ErrorResponseBody(api.ErrUnsupportedResponseType), log)
+	case err.Error() == oautherrors.ErrUnsupportedResponseType:
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, false, api.ErrorResponseBody(api.ErrUnsupportedResponseType), log)
+	case err.Error() == oautherrors.ErrPushedAuthorizationRequired:
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrInvalidRequest, "pushed authorization request required"), log)
+	case err.Error() == oautherrors.ErrSignedRequestObjectRequired:
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrInvalidRequest, "signed request object required"), log)
+	case strings.HasPrefix(err.Error(), oautherrors.ErrUnregisteredResponseType):
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrUnauthorizedClient), log)
+	case errors.Is(err, api.ErrInvalidScope):
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrInvalidScope), log)
+	case errors.Is(err, api.ErrInvalidAuthorizationDetails):
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrInvalidAuthorizationDetails), log)
+	case errors.Is(err, api.ErrInvalidTarget):
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrInvalidTarget), log)
 	default:
-		handleAuthError(w, r, authRequest.RedirectUri, authRequest.State, api.ErrorResponseBody(api.ErrServerError), log)
+		redirectWithAuthError(w, r, authRequest.RedirectUri, authRequest.State, fragment, api.ErrorResponseBody(api.ErrServerError), log)
 	}
 }
 
-func getRedirectURL(authRequest *api.AuthorizeRequest, authCode *oauth.AuthCode) string {
-	redirectURL := authRequest.RedirectUri
+// usesFragment reports whether responses to the request belong in the redirect URI fragment.
+// Requests for an unsupported response type are answered in the query.
+func usesFragment(authRequest *api.AuthorizeRequest) bool {
+	return responsetype.IsSupported(authRequest.ResponseType) && responsetype.UsesFragment(authRequest.ResponseType)
+}
+
+// getRedirectURL encodes the authorization response into the redirect URI, using the query for the code flow and
+// the fragment for implicit and hybrid flows.
+func getRedirectURL(authRequest *api.AuthorizeRequest, authResponse *oauth.AuthorizationResponse) string {
+	params := url.Values{}
+	if authResponse.Code != nil {
+		params.Set("code", authResponse.Code.Code)
+	}
+	if authResponse.Token != nil {
+		params.Set("access_token", authResponse.Token.AccessToken)
+		params.Set("token_type", authResponse.Token.TokenType)
+		params.Set("expires_in", strconv.Itoa(authResponse.Token.AccessTokenExpiresIn))
+		if len(authResponse.Token.Scope) > 0 {
+			params.Set("scope", strings.Join(authResponse.Token.Scope, " "))
+		}
+	}
+	if authResponse.IDToken != "" {
+		params.Set("id_token", authResponse.IDToken)
+	}
 	if authRequest.State != "" {
-		redirectURL = fmt.Sprintf("%s?code=%s&state=%s", redirectURL, authCode.Code, authRequest.State)
-	} else {
-		redirectURL = fmt.Sprintf("%s?code=%s", redirectURL, authCode.Code)
+		params.Set("state", authRequest.State)
 	}
-	return redirectURL
+	return appendResponseParams(authRequest.RedirectUri, params, authResponse.UsesFragment())
+}
+
+// appendResponseParams adds params to the fragment or the query of redirectURI.
+func appendResponseParams(redirectURI string, params url.Values, fragment bool) string {
+	if fragment {
+		return redirectURI + "#" + params.Encode()
+	}
+	if strings.Contains(redirectURI, "?") {
+		return redirectURI + "&" + params.Encode()
+	}
+	return redirectURI + "?" + params.Encode()
 }
 
 func handleAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state string, errorResponse api.ErrorResponse, log *zap.Logger) {
+	redirectWithAuthError(w, r, redirectURI, state, false, errorResponse, log)
+}
+
+// redirectWithAuthError returns an authorization error to the client, in the fragment when the request asked for an
+// implicit or hybrid response.
+func redirectWithAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state string, fragment bool, errorResponse api.ErrorResponse, log *zap.Logger) {
 	// Default redirect URI if not provided
 	if redirectURI == "" {
 		redirectURI = "default/error/page" // Replace with your default error page
 	}
 
 	// Construct the error response URL
-	errorResponseURL := fmt.Sprintf("%s?error=%s", redirectURI, errorResponse.Error)
+	params := url.Values{}
+	params.Set("error", errorResponse.Error)
 	if errorResponse.ErrorDescription != "" {
-		errorResponseURL += fmt.Sprintf("&error_description=%s", url.QueryEscape(errorResponse.ErrorDescription))
+		params.Set("error_description", errorResponse.ErrorDescription)
 	}
 	if state != "" {
-		errorResponseURL += fmt.Sprintf("&state=%s", url.QueryEscape(state))
+		params.Set("state", state)
 	}
+	errorResponseURL := appendResponseParams(redirectURI, params, fragment)
 
 	// Log the error for debugging purposes
 	log.Error("Redirecting with error", zap.String("error_response", errorResponseURL))
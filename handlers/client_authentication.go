@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+)
+
+// clientAuthenticationCommand maps the credentials decoded from a request to the command of the ClientAuthenticator.
+func clientAuthenticationCommand(credentials api.ClientCredentials) *services.ClientAuthenticationCommand {
+	return &services.ClientAuthenticationCommand{
+		ClientId:           credentials.ClientId,
+		ClientSecret:       credentials.ClientSecret,
+		ClientAssertion:    credentials.ClientAssertion,
+		ClientCertificates: credentials.ClientCertificates,
+	}
+}
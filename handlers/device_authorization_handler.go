@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type deviceAuthorizationHandler struct {
+	deviceAuthorizationService services.DeviceAuthorizationService
+	clientAuthenticator        services.ClientAuthenticator
+	logger                     *zap.Logger
+}
+
+// NewDeviceAuthorizationHandler creates a new instance of the handler.
+func NewDeviceAuthorizationHandler(deviceAuthorizationService services.DeviceAuthorizationService,
+	clientAuthenticator services.ClientAuthenticator,
+	logger *zap.Logger,
+) DeviceAuthorizationHandler {
+	return &deviceAuthorizationHandler{
+		deviceAuthorizationService: deviceAuthorizationService,
+		clientAuthenticator:        clientAuthenticator,
+		logger:                     logger,
+	}
+}
+
+// DeviceAuthorization issues a device code and user code for input-constrained devices (RFC 8628, section 3.1).
+func (handler *deviceAuthorizationHandler) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	handler.logger.Info("Received device authorization request")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := api.DecodeDeviceAuthorizationRequest(r)
+	if err != nil {
+		handler.logger.Error("Error decoding device authorization request", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		handler.logger.Error("Invalid device authorization request", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
+		return
+	}
+
+	client, err := handler.clientAuthenticator.Authenticate(clientAuthenticationCommand(req.ClientCredentials))
+	if err != nil {
+		utils.HandleErrorResponse(w, handler.logger, err)
+		return
+	}
+
+	deviceAuthorization, err := handler.deviceAuthorizationService.RequestDeviceAuthorization(&services.DeviceAuthorizationCommand{
+		Client: client,
+		Scope:  req.Scope,
+	})
+	if err != nil {
+		utils.HandleErrorResponse(w, handler.logger, err)
+		return
+	}
+
+	handler.logger.Info("Device authorization issued successfully", zap.String("clientId", req.ClientId))
+	res := api.NewDeviceAuthorizationResponse(
+		deviceAuthorization.DeviceCode,
+		deviceAuthorization.UserCode,
+		deviceAuthorization.VerificationUri,
+		deviceAuthorization.VerificationUriComplete,
+		deviceAuthorization.ExpiresIn(),
+		deviceAuthorization.Interval,
+	)
+
+	utils.RespondWithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	oautherrors "github.com/manuelrojas19/go-oauth2-server/errors"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"go.uber.org/zap"
+)
+
+var deviceTmpl *template.Template
+
+func init() {
+	var err error
+	deviceTmpl, err = template.ParseFiles("templates/device.html")
+	if err != nil {
+		panic(fmt.Sprintf("FATAL: Error parsing device template: %v", err))
+	}
+}
+
+// deviceVerificationHandler serves the page where end users enter and approve device user codes.
+type deviceVerificationHandler struct {
+	deviceAuthorizationService services.DeviceAuthorizationService
+	sessionService             services.SessionService
+	log                        *zap.Logger
+}
+
+// NewDeviceVerificationHandler creates and returns a new instance of deviceVerificationHandler.
+func NewDeviceVerificationHandler(deviceAuthorizationService services.DeviceAuthorizationService,
+	sessionService services.SessionService,
+	logger *zap.Logger,
+) DeviceVerificationHandler {
+	return &deviceVerificationHandler{
+		deviceAuthorizationService: deviceAuthorizationService,
+		sessionService:             sessionService,
+		log:                        logger,
+	}
+}
+
+// DevicePageData holds data that will be passed to the device HTML template.
+type DevicePageData struct {
+	UserCode string // The user code being verified, if any.
+	ClientId string // The client that requested the device authorization.
+	Scope    string // The scope requested by the device.
+	Message  string // A final or error message shown to the end user.
+	Confirm  bool   // Whether the page asks the end user to approve or deny the request.
+	Done     bool   // Whether the end user's decision has been recorded.
+}
+
+// Verify handles GET requests by asking for (or confirming) a user code and POST requests by recording the decision.
+// Users without a session are sent through the login flow and returned here afterwards.
+func (h *deviceVerificationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Received device verification request", zap.String("method", r.Method))
+
+	if err := r.ParseForm(); err != nil {
+		h.log.Error("Failed to parse device verification form", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	userCode := r.FormValue("user_code")
+
+	sessionId := ""
+	if cookie, err := r.Cookie("session_id"); err == nil {
+		sessionId = cookie.Value
+	}
+	if !h.sessionService.SessionExists(sessionId) {
+		h.redirectToLogin(w, r, userCode)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if userCode == "" {
+			h.render(w, DevicePageData{})
+			return
+		}
+		deviceAuthorization, err := h.deviceAuthorizationService.FindPendingByUserCode(userCode)
+		if err != nil {
+			h.log.Warn("Device user code cannot be verified", zap.String("userCode", userCode), zap.Error(err))
+			h.render(w, DevicePageData{UserCode: userCode, Message: userCodeErrorMessage(err)})
+			return
+		}
+		h.render(w, DevicePageData{
+			UserCode: deviceAuthorization.UserCode,
+			ClientId: deviceAuthorization.ClientId,
+			Scope:    deviceAuthorization.Scope,
+			Confirm:  true,
+		})
+	case http.MethodPost:
+		approved := r.FormValue("consent") == "approve"
+		err := h.deviceAuthorizationService.Decide(&services.DeviceVerificationCommand{
+			UserCode:  userCode,
+			SessionId: sessionId,
+			Approved:  approved,
+		})
+		if err != nil {
+			if err.Error() == oautherrors.ErrUserNotAuthenticated {
+				h.redirectToLogin(w, r, userCode)
+				return
+			}
+			h.log.Warn("Device verification decision failed", zap.String("userCode", userCode), zap.Error(err))
+			h.render(w, DevicePageData{UserCode: userCode, Message: userCodeErrorMessage(err)})
+			return
+		}
+		message := "Access denied. You can close this window."
+		if approved {
+			message = "Your device is now connected. You can return to it and close this window."
+		}
+		h.render(w, DevicePageData{Message: message, Done: true})
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *deviceVerificationHandler) redirectToLogin(w http.ResponseWriter, r *http.Request, userCode string) {
+	returnTo := services.DeviceVerificationPath
+	if userCode != "" {
+		returnTo += "?user_code=" + url.QueryEscape(userCode)
+	}
+	loginURL := "/oauth/login?" + url.Values{"return_to": {returnTo}}.Encode()
+	h.log.Warn("User not authenticated, redirecting to login", zap.String("loginURL", loginURL))
+	http.Redirect(w, r, loginURL, http.StatusSeeOther)
+}
+
+func (h *deviceVerificationHandler) render(w http.ResponseWriter, data DevicePageData) {
+	if err := deviceTmpl.Execute(w, data); err != nil {
+		h.log.Error("Error rendering device template", zap.Error(err))
+		http.Error(w, "Error rendering the device page", http.StatusInternalServerError)
+	}
+}
+
+func userCodeErrorMessage(err error) string {
+	if errors.Is(err, api.ErrExpiredToken) {
+		return "This code has expired. Please start again on your device."
+	}
+	return "This code is not valid. Please check it and try again."
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type discoveryHandler struct {
+	wellKnownService services.WellKnownService
+	logger           *zap.Logger
+}
+
+func NewDiscoveryHandler(wellKnownService services.WellKnownService, logger *zap.Logger) DiscoveryHandler {
+	return &discoveryHandler{
+		wellKnownService: wellKnownService,
+		logger:           logger,
+	}
+}
+
+// RegisterRoutes publishes the server's route table, so the metadata documents only advertise live endpoints.
+func (d discoveryHandler) RegisterRoutes(routes map[string]http.HandlerFunc) {
+	paths := make([]string, 0, len(routes))
+	for path := range routes {
+		paths = append(paths, path)
+	}
+	d.wellKnownService.RegisterEndpoints(paths)
+	d.logger.Info("Discovery metadata endpoints registered", zap.Int("routes", len(paths)))
+}
+
+// OpenIDConfiguration serves the OpenID Provider configuration document (OpenID Connect Discovery 1.0).
+func (d discoveryHandler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	d.logger.Info("Received OpenID configuration request", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	utils.RespondWithJSON(w, http.StatusOK, d.wellKnownService.GetOpenIDConfiguration())
+}
+
+// AuthorizationServerMetadata serves the OAuth 2.0 authorization server metadata document (RFC 8414).
+func (d discoveryHandler) AuthorizationServerMetadata(w http.ResponseWriter, r *http.Request) {
+	d.logger.Info("Received authorization server metadata request", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	utils.RespondWithJSON(w, http.StatusOK, d.wellKnownService.GetAuthorizationServerMetadata())
+}
@@ -276,14 +276,21 @@ func decodeState(encodedState string) (map[string]string, error) {
 }
 
 // buildRedirectURL constructs the final redirect URL for the client application
-// by appending the original authorization parameters to the base URL.
+// by appending the original authorization parameters to the base URL, unless the login
+// was started by another local page that asked to be returned to.
 func buildRedirectURL(params map[string]string) string {
+	if returnTo := params["return_to"]; isLocalPath(returnTo) {
+		return returnTo
+	}
+
 	baseURL := "/oauth/authorize" // Change this to your final redirect endpoint
 
-	// Encode parameters for query string
+	// Encode parameters for query string, leaving out the optional ones the client did not send
 	queryParams := url.Values{}
 	for key, value := range params {
-		queryParams.Add(key, value)
+		if value != "" {
+			queryParams.Add(key, value)
+		}
 	}
 
 	// Construct full URL with query parameters
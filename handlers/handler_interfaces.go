@@ -52,4 +52,28 @@ type IntrospectionHandler interface {
 
 type RevocationHandler interface {
 	Revoke(http.ResponseWriter, *http.Request)
+	RevokedTokens(http.ResponseWriter, *http.Request)
+}
+
+type DeviceAuthorizationHandler interface {
+	DeviceAuthorization(http.ResponseWriter, *http.Request)
+}
+
+type DeviceVerificationHandler interface {
+	Verify(http.ResponseWriter, *http.Request)
+}
+
+type DiscoveryHandler interface {
+	RegisterRoutes(routes map[string]http.HandlerFunc)
+	OpenIDConfiguration(http.ResponseWriter, *http.Request)
+	AuthorizationServerMetadata(http.ResponseWriter, *http.Request)
+}
+
+type PushedAuthorizationHandler interface {
+	PushAuthorization(http.ResponseWriter, *http.Request)
+}
+
+type SigningKeysHandler interface {
+	Keys(http.ResponseWriter, *http.Request)
+	Rotate(http.ResponseWriter, *http.Request)
 }
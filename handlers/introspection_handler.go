@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/api"
 	"github.com/manuelrojas19/go-oauth2-server/services"
@@ -11,12 +13,14 @@ import (
 
 type introspectionHandler struct {
 	introspectionService services.IntrospectionService
+	clientAuthenticator  services.ClientAuthenticator
 	log                  *zap.Logger
 }
 
-func NewIntrospectionHandler(introspectionService services.IntrospectionService, logger *zap.Logger) IntrospectionHandler {
+func NewIntrospectionHandler(introspectionService services.IntrospectionService, clientAuthenticator services.ClientAuthenticator, logger *zap.Logger) IntrospectionHandler {
 	return &introspectionHandler{
 		introspectionService: introspectionService,
+		clientAuthenticator:  clientAuthenticator,
 		log:                  logger,
 	}
 }
@@ -30,12 +34,27 @@ func (h *introspectionHandler) Introspect(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		h.log.Error("Failed to parse form data", zap.Error(err))
+	credentials, err := api.DecodeClientCredentials(r)
+	if err != nil {
+		h.log.Error("Failed to decode client credentials", zap.Error(err))
 		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
 		return
 	}
 
+	// Only authenticated clients may use the introspection endpoint
+	client, err := h.clientAuthenticator.Authenticate(clientAuthenticationCommand(credentials))
+	if err != nil {
+		utils.HandleErrorResponse(w, h.log, err)
+		return
+	}
+
+	// and only those the operator granted the introspection permission, typically resource servers
+	if !client.IntrospectionAllowed {
+		h.log.Warn("Client is not allowed to introspect tokens", zap.String("clientId", client.ClientId))
+		utils.HandleErrorResponse(w, h.log, fmt.Errorf("%w: client is not allowed to introspect tokens", api.ErrUnauthorizedClient))
+		return
+	}
+
 	token := r.Form.Get("token")
 	if token == "" {
 		h.log.Warn("Missing token parameter in introspection request")
@@ -48,6 +67,26 @@ func (h *introspectionHandler) Introspect(w http.ResponseWriter, r *http.Request
 	command := &services.IntrospectCommand{
 		Token:         token,
 		TokenTypeHint: tokenTypeHint,
+		ClientId:      client.ClientId,
+	}
+
+	// Resource servers asking for a JWT response get the introspection result signed, and possibly encrypted, for them
+	if strings.Contains(r.Header.Get("Accept"), utils.IntrospectionJWTContentType) {
+		introspectionJWT, err := h.introspectionService.IntrospectJWT(command, client)
+		if err != nil {
+			h.log.Error("Error performing JWT introspection", zap.Error(err))
+			utils.HandleErrorResponse(w, h.log, err)
+			return
+		}
+
+		h.log.Info("JWT introspection successful", zap.String("clientId", client.ClientId))
+		w.Header().Set("Content-Type", utils.IntrospectionJWTContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(introspectionJWT)); err != nil {
+			h.log.Error("Failed to write JWT introspection response", zap.Error(err))
+		}
+		return
 	}
 
 	introspectionResponse, err := h.introspectionService.Introspect(command)
@@ -57,6 +96,6 @@ func (h *introspectionHandler) Introspect(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.log.Info("Introspection successful", zap.Any("response", introspectionResponse))
+	h.log.Info("Introspection successful", zap.Bool("active", introspectionResponse.Active), zap.String("clientId", client.ClientId))
 	utils.RespondWithJSON(w, http.StatusOK, introspectionResponse)
 }
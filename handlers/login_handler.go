@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/manuelrojas19/go-oauth2-server/configuration"
 	"go.uber.org/zap"
@@ -46,11 +47,21 @@ type LoginData struct {
 func (l loginHandler) Login(writer http.ResponseWriter, request *http.Request) {
 	// Extract original authorization parameters from the request URL query.
 	originalParams := map[string]string{
-		"client_id":     request.URL.Query().Get("client_id"),
-		"scope":         request.URL.Query().Get("scope"),
-		"redirect_uri":  request.URL.Query().Get("redirect_uri"),
-		"response_type": request.URL.Query().Get("response_type"),
-		"state":         request.URL.Query().Get("state"),
+		"client_id":             request.URL.Query().Get("client_id"),
+		"scope":                 request.URL.Query().Get("scope"),
+		"redirect_uri":          request.URL.Query().Get("redirect_uri"),
+		"response_type":         request.URL.Query().Get("response_type"),
+		"state":                 request.URL.Query().Get("state"),
+		"code_challenge":        request.URL.Query().Get("code_challenge"),
+		"code_challenge_method": request.URL.Query().Get("code_challenge_method"),
+		"nonce":                 request.URL.Query().Get("nonce"),
+		"request_uri":           request.URL.Query().Get("request_uri"),
+		"request":               request.URL.Query().Get("request"),
+	}
+
+	// Flows other than /oauth/authorize (e.g. device verification) ask to be returned to their own page
+	if returnTo := request.URL.Query().Get("return_to"); isLocalPath(returnTo) {
+		originalParams = map[string]string{"return_to": returnTo}
 	}
 
 	// Encode the original parameters into a state string for round-tripping through Google OAuth.
@@ -87,3 +98,12 @@ func encodeState(state map[string]string) (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(stateJSON), nil
 }
+
+// isLocalPath reports whether target is a path on this server, so it is safe to redirect to after login.
+func isLocalPath(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return false
+	}
+	parsed, err := url.Parse(target)
+	return err == nil && parsed.Scheme == "" && parsed.Host == ""
+}
@@ -19,5 +19,10 @@ var Module = fx.Options(
 		NewIntrospectionHandler,
 		NewRevocationHandler,
 		NewAcceptConsentHandler,
+		NewDeviceAuthorizationHandler,
+		NewDeviceVerificationHandler,
+		NewDiscoveryHandler,
+		NewPushedAuthorizationHandler,
+		NewSigningKeysHandler,
 	),
 )
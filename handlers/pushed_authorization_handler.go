@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type pushedAuthorizationHandler struct {
+	pushedAuthorizationService services.PushedAuthorizationService
+	requestObjectService       services.RequestObjectService
+	clientAuthenticator        services.ClientAuthenticator
+	logger                     *zap.Logger
+}
+
+// NewPushedAuthorizationHandler creates a new instance of the handler.
+func NewPushedAuthorizationHandler(pushedAuthorizationService services.PushedAuthorizationService,
+	requestObjectService services.RequestObjectService,
+	clientAuthenticator services.ClientAuthenticator,
+	logger *zap.Logger,
+) PushedAuthorizationHandler {
+	return &pushedAuthorizationHandler{
+		pushedAuthorizationService: pushedAuthorizationService,
+		requestObjectService:       requestObjectService,
+		clientAuthenticator:        clientAuthenticator,
+		logger:                     logger,
+	}
+}
+
+// PushAuthorization stores an authorization request sent over the back channel and returns the request_uri the
+// client passes to /oauth/authorize instead of the parameters (RFC 9126, section 2).
+func (handler *pushedAuthorizationHandler) PushAuthorization(w http.ResponseWriter, r *http.Request) {
+	handler.logger.Info("Received pushed authorization request")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := api.DecodePushedAuthorizationRequest(r)
+	if err != nil {
+		handler.logger.Error("Error decoding pushed authorization request", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest, err.Error()))
+		return
+	}
+
+	// The client authenticates before anything it sent is acted upon
+	client, err := handler.clientAuthenticator.Authenticate(clientAuthenticationCommand(req.ClientCredentials))
+	if err != nil {
+		utils.HandleErrorResponse(w, handler.logger, err)
+		return
+	}
+	req.Params.Set("client_id", client.ClientId)
+
+	// A pushed request object is verified now, so only its merged parameters are stored (RFC 9126, section 3). It
+	// must be the authenticated client's
+	if req.Params.Get("request") != "" {
+		params, err := handler.requestObjectService.Resolve(req.Params)
+		if err != nil {
+			utils.HandleErrorResponse(w, handler.logger, err)
+			return
+		}
+		if params.Get("client_id") != client.ClientId {
+			handler.logger.Warn("Request object was not issued by the authenticated client", zap.String("clientId", client.ClientId))
+			utils.HandleErrorResponse(w, handler.logger, fmt.Errorf("%w: client_id does not match the authenticated client", api.ErrInvalidRequestObject))
+			return
+		}
+		req.Params = params
+	}
+
+	if err := req.DecodeAuthorizeRequest(); err != nil {
+		handler.logger.Error("Error decoding pushed authorization parameters", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest, err.Error()))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		handler.logger.Error("Invalid pushed authorization request", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest, err.Error()))
+		return
+	}
+
+	pushedAuthorization, err := handler.pushedAuthorizationService.Push(&services.PushAuthorizationCommand{
+		Client:               client,
+		RedirectUri:          req.AuthorizeRequest.RedirectUri,
+		ResponseType:         req.AuthorizeRequest.ResponseType,
+		Params:               req.Params,
+		AuthorizationDetails: req.AuthorizeRequest.AuthorizationDetails,
+	})
+	if err != nil {
+		utils.HandleErrorResponse(w, handler.logger, err)
+		return
+	}
+
+	handler.logger.Info("Pushed authorization request stored successfully", zap.String("clientId", client.ClientId))
+	res := api.NewPushedAuthorizationResponse(pushedAuthorization.RequestUri, pushedAuthorization.ExpiresIn())
+
+	utils.RespondWithJSON(w, http.StatusCreated, res)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -59,6 +60,18 @@ func (handler *registerHandler) Register(w http.ResponseWriter, r *http.Request)
 		TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
 		RedirectUris:            req.RedirectUris,
 		Scopes:                  req.Scopes,
+
+		Jwks:                        string(req.Jwks),
+		JwksUri:                     req.JwksUri,
+		TokenExchangeSubjectClients: req.TokenExchangeSubjectClients,
+		TokenExchangeAudiences:      req.TokenExchangeAudiences,
+		RequestUris:                 req.RequestUris,
+
+		RequirePushedAuthorizationRequests: req.RequirePushedAuthorizationRequests,
+		RequireSignedRequestObject:         req.RequireSignedRequestObject,
+		TLSClientAuth:                      req.CertificateSubject,
+		TokenEncryption:                    req.TokenEncryption,
+		TokenSigning:                       req.TokenSigning,
 	}
 
 	client, err := handler.oauthClientService.CreateOauthClient(&command)
@@ -79,6 +92,18 @@ func (handler *registerHandler) Register(w http.ResponseWriter, r *http.Request)
 		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
 		RedirectUris:            client.RedirectUris,
 		Scopes:                  client.Scopes,
+
+		Jwks:                        json.RawMessage(client.Jwks),
+		JwksUri:                     client.JwksUri,
+		TokenExchangeSubjectClients: client.TokenExchangeSubjectClients,
+		TokenExchangeAudiences:      client.TokenExchangeAudiences,
+		RequestUris:                 client.RequestUris,
+
+		RequirePushedAuthorizationRequests: client.RequirePushedAuthorizationRequests,
+		RequireSignedRequestObject:         client.RequireSignedRequestObject,
+		CertificateSubject:                 client.TLSClientAuth,
+		TokenEncryption:                    client.TokenEncryption,
+		TokenSigning:                       client.TokenSigning,
 	}
 
 	utils.RespondWithJSON(w, http.StatusCreated, res)
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/url"
+	"sort"
 
+	"github.com/manuelrojas19/go-oauth2-server/oauth"
 	"go.uber.org/zap"
 )
 
@@ -33,6 +36,21 @@ type ConsentPageData struct {
 	RedirectUri    string
 	ResponseType   string
 	ConsentPageURL string
+	// AuthorizationDetails are the fine-grained permissions (RFC 9396) the user is asked to grant
+	AuthorizationDetails []ConsentAuthorizationDetail
+	// Resources are the resource indicators (RFC 8707) of the resources the client asks to access
+	Resources []string
+}
+
+// ConsentAuthorizationDetail is an authorization detail as shown on the consent page: its type and its other fields.
+type ConsentAuthorizationDetail struct {
+	Type   string
+	Fields []ConsentAuthorizationDetailField
+}
+
+type ConsentAuthorizationDetailField struct {
+	Name  string
+	Value string
 }
 
 func (h *requestConsentHandler) RequestConsent(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +62,15 @@ func (h *requestConsentHandler) RequestConsent(w http.ResponseWriter, r *http.Re
 	scope := r.URL.Query().Get("scope")
 	redirectUri := r.URL.Query().Get("redirect_uri")
 	responseType := r.URL.Query().Get("response_type")
+	authorizationDetails := r.URL.Query().Get("authorization_details")
+	resources := r.URL.Query()["resource"]
+
+	details, err := oauth.ParseAuthorizationDetails(authorizationDetails)
+	if err != nil {
+		h.logger.Warn("Malformed authorization details on consent request", zap.Error(err))
+		http.Error(w, "Invalid authorization_details", http.StatusBadRequest)
+		return
+	}
 
 	// URL-encode parameters to include in the consent page link
 	encodedClientId := url.QueryEscape(clientId)
@@ -54,13 +81,21 @@ func (h *requestConsentHandler) RequestConsent(w http.ResponseWriter, r *http.Re
 	// Construct the consent page URL with query parameters
 	consentPageURL := fmt.Sprintf("/consent/?client_id=%s&scope=%s&redirect_uri=%s&response_type=%s",
 		encodedClientId, encodedScope, encodedRedirectUri, encodedResponseType)
+	if authorizationDetails != "" {
+		consentPageURL += "&authorization_details=" + url.QueryEscape(authorizationDetails)
+	}
+	for _, resource := range resources {
+		consentPageURL += "&resource=" + url.QueryEscape(resource)
+	}
 
 	data := ConsentPageData{
-		ClientId:       clientId,
-		Scope:          scope,
-		RedirectUri:    redirectUri,
-		ResponseType:   responseType,
-		ConsentPageURL: consentPageURL,
+		ClientId:             clientId,
+		Scope:                scope,
+		RedirectUri:          redirectUri,
+		ResponseType:         responseType,
+		ConsentPageURL:       consentPageURL,
+		AuthorizationDetails: consentAuthorizationDetails(details),
+		Resources:            resources,
 	}
 
 	// Redirect the user to the consent page
@@ -69,3 +104,31 @@ func (h *requestConsentHandler) RequestConsent(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Error rendering template", http.StatusInternalServerError)
 	}
 }
+
+// consentAuthorizationDetails lists the fields of each authorization detail in name order, so the user sees exactly
+// what the client asks for. Values that are not strings are shown as JSON.
+func consentAuthorizationDetails(details oauth.AuthorizationDetails) []ConsentAuthorizationDetail {
+	var consentDetails []ConsentAuthorizationDetail
+	for _, detail := range details {
+		consentDetail := ConsentAuthorizationDetail{Type: detail.Type()}
+		for name, value := range detail {
+			if name == "type" {
+				continue
+			}
+			text, ok := value.(string)
+			if !ok {
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					continue
+				}
+				text = string(encoded)
+			}
+			consentDetail.Fields = append(consentDetail.Fields, ConsentAuthorizationDetailField{Name: name, Value: text})
+		}
+		sort.Slice(consentDetail.Fields, func(i, j int) bool {
+			return consentDetail.Fields[i].Name < consentDetail.Fields[j].Name
+		})
+		consentDetails = append(consentDetails, consentDetail)
+	}
+	return consentDetails
+}
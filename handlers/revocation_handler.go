@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/manuelrojas19/go-oauth2-server/api"
@@ -10,14 +11,16 @@ import (
 )
 
 type revocationHandler struct {
-	revocationService services.RevocationService
-	log               *zap.Logger
+	revocationService   services.RevocationService
+	clientAuthenticator services.ClientAuthenticator
+	log                 *zap.Logger
 }
 
-func NewRevocationHandler(revocationService services.RevocationService, logger *zap.Logger) RevocationHandler {
+func NewRevocationHandler(revocationService services.RevocationService, clientAuthenticator services.ClientAuthenticator, logger *zap.Logger) RevocationHandler {
 	return &revocationHandler{
-		revocationService: revocationService,
-		log:               logger,
+		revocationService:   revocationService,
+		clientAuthenticator: clientAuthenticator,
+		log:                 logger,
 	}
 }
 
@@ -30,12 +33,20 @@ func (h *revocationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		h.log.Error("Failed to parse form data", zap.Error(err))
+	credentials, err := api.DecodeClientCredentials(r)
+	if err != nil {
+		h.log.Error("Failed to decode client credentials", zap.Error(err))
 		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
 		return
 	}
 
+	// Only authenticated clients may use the revocation endpoint
+	client, err := h.clientAuthenticator.Authenticate(clientAuthenticationCommand(credentials))
+	if err != nil {
+		utils.HandleErrorResponse(w, h.log, err)
+		return
+	}
+
 	token := r.Form.Get("token")
 	if token == "" {
 		h.log.Warn("Missing token parameter in revocation request")
@@ -48,15 +59,57 @@ func (h *revocationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
 	command := &services.RevokeCommand{
 		Token:         token,
 		TokenTypeHint: tokenTypeHint,
+		ClientId:      client.ClientId,
 	}
 
-	err := h.revocationService.Revoke(command)
+	err = h.revocationService.Revoke(command)
 	if err != nil {
 		h.log.Error("Error revoking token", zap.Error(err))
-		utils.RespondWithJSON(w, http.StatusInternalServerError, api.ErrorResponseBody(api.ErrServerError))
+		utils.HandleErrorResponse(w, h.log, err)
+		return
+	}
+
+	// RFC 7009 answers 200 whether or not the token was known, so that invalid tokens cannot be told apart
+	h.log.Info("Token revocation request processed", zap.String("clientId", client.ClientId))
+	w.WriteHeader(http.StatusOK)
+}
+
+// RevokedTokens serves the revocation list to resource servers that validate access tokens locally. Like
+// introspection, it is only available to clients granted the introspection permission.
+func (h *revocationHandler) RevokedTokens(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Entered Revocation List handler", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		h.log.Warn("Invalid request method for Revocation List endpoint", zap.String("method", r.Method))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	credentials, err := api.DecodeClientCredentials(r)
+	if err != nil {
+		h.log.Error("Failed to decode client credentials", zap.Error(err))
+		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
+		return
+	}
+
+	client, err := h.clientAuthenticator.Authenticate(clientAuthenticationCommand(credentials))
+	if err != nil {
+		utils.HandleErrorResponse(w, h.log, err)
+		return
+	}
+	if !client.IntrospectionAllowed {
+		h.log.Warn("Client is not allowed to read the revocation list", zap.String("clientId", client.ClientId))
+		utils.HandleErrorResponse(w, h.log, fmt.Errorf("%w: client is not allowed to read the revocation list", api.ErrUnauthorizedClient))
+		return
+	}
+
+	revoked, err := h.revocationService.RevokedTokens()
+	if err != nil {
+		utils.HandleErrorResponse(w, h.log, err)
 		return
 	}
 
-	h.log.Info("Token revoked successfully")
-	w.WriteHeader(http.StatusNoContent)
+	h.log.Info("Revocation list served", zap.String("clientId", client.ClientId), zap.Int("count", len(revoked)))
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"revoked": revoked})
 }
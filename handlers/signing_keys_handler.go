@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type signingKeysHandler struct {
+	signingKeyService services.SigningKeyService
+	log               *zap.Logger
+}
+
+func NewSigningKeysHandler(signingKeyService services.SigningKeyService, logger *zap.Logger) SigningKeysHandler {
+	return &signingKeysHandler{
+		signingKeyService: signingKeyService,
+		log:               logger,
+	}
+}
+
+// Keys lists the keys of the signing key ring with their state.
+func (h *signingKeysHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Entered Signing Keys handler", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+	if r.Method != http.MethodGet {
+		h.log.Warn("Invalid request method for Signing Keys endpoint", zap.String("method", r.Method))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authenticateAdmin(w, r, h.log) {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, h.signingKeyService.ListKeys())
+}
+
+// Rotate performs an emergency rotation, revoking the active signing key, and returns the resulting key ring.
+func (h *signingKeysHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Entered Signing Key Rotation handler", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+	if r.Method != http.MethodPost {
+		h.log.Warn("Invalid request method for Signing Key Rotation endpoint", zap.String("method", r.Method))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authenticateAdmin(w, r, h.log) {
+		return
+	}
+
+	keys, err := h.signingKeyService.EmergencyRotate()
+	if err != nil {
+		utils.HandleErrorResponse(w, h.log, err)
+		return
+	}
+
+	h.log.Info("Signing keys rotated by an administrator")
+	utils.RespondWithJSON(w, http.StatusOK, keys)
+}
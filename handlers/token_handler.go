@@ -1,24 +1,36 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
 	"github.com/manuelrojas19/go-oauth2-server/utils"
 	"go.uber.org/zap"
 )
 
 type tokenHandler struct {
-	tokenService services.TokenService // A wellKnownService for generating and managing tokens
-	logger       *zap.Logger
+	tokenService        services.TokenService // A wellKnownService for generating and managing tokens
+	clientAuthenticator services.ClientAuthenticator
+	dpopService         services.DPoPService
+	logger              *zap.Logger
 }
 
 // NewTokenHandler creates a new instance of the handler.
-func NewTokenHandler(tokenService services.TokenService, logger *zap.Logger) TokenHandler {
+func NewTokenHandler(tokenService services.TokenService,
+	clientAuthenticator services.ClientAuthenticator,
+	dpopService services.DPoPService,
+	logger *zap.Logger,
+) TokenHandler {
 	return &tokenHandler{
-		tokenService: tokenService,
-		logger:       logger,
+		tokenService:        tokenService,
+		clientAuthenticator: clientAuthenticator,
+		dpopService:         dpopService,
+		logger:              logger,
 	}
 }
 
@@ -32,7 +44,8 @@ func (handler *tokenHandler) Token(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req api.TokenRequest
-	if err := api.DecodeTokenRequest(r, &req); err != nil {
+	err := api.DecodeTokenRequest(r, &req)
+	if err != nil {
 		handler.logger.Error("Error decoding request body", zap.Error(err))
 		utils.RespondWithJSON(w, http.StatusBadRequest, api.ErrorResponseBody(api.ErrInvalidRequest))
 		return
@@ -45,14 +58,60 @@ func (handler *tokenHandler) Token(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Authenticate the client with its registered method. The JWT bearer grant and refresh requests of public clients
+	// may leave the client out entirely.
+	var client *store.OauthClient
+	if req.ClientId != "" || (req.GrantType != granttype.JWTBearer && req.GrantType != granttype.RefreshToken) {
+		client, err = handler.clientAuthenticator.Authenticate(clientAuthenticationCommand(req.ClientCredentials))
+		if err != nil {
+			utils.HandleErrorResponse(w, handler.logger, err)
+			return
+		}
+	}
+
 	grantAccessTokenCommand := services.NewGrantAccessTokenCommand(req.ClientId,
-		req.ClientSecret,
+		client,
 		req.GrantType,
 		req.RefreshToken,
 		req.AuthCode,
 		req.RedirectUri,
 		req.CodeVerifier,
 	)
+	grantAccessTokenCommand.Username = req.Username
+	grantAccessTokenCommand.Password = req.Password
+	grantAccessTokenCommand.Scope = req.Scope
+	grantAccessTokenCommand.DeviceCode = req.DeviceCode
+	grantAccessTokenCommand.SubjectToken = req.SubjectToken
+	grantAccessTokenCommand.SubjectTokenType = req.SubjectTokenType
+	grantAccessTokenCommand.ActorToken = req.ActorToken
+	grantAccessTokenCommand.ActorTokenType = req.ActorTokenType
+	grantAccessTokenCommand.RequestedTokenType = req.RequestedTokenType
+	grantAccessTokenCommand.Audience = req.Audience
+	grantAccessTokenCommand.Assertion = req.Assertion
+	grantAccessTokenCommand.AuthorizationDetails = req.AuthorizationDetails
+	grantAccessTokenCommand.Resources = req.Resources
+	grantAccessTokenCommand.ClientCertificates = req.ClientCertificates
+
+	// Bind the issued tokens to the client's key when the request carries a DPoP proof
+	proof, err := dpopProof(r)
+	if err != nil {
+		handler.logger.Error("Invalid DPoP header", zap.Error(err))
+		utils.HandleErrorResponse(w, handler.logger, err)
+		return
+	}
+	if proof != "" {
+		jkt, err := handler.dpopService.ValidateProof(&services.DPoPProofCommand{
+			Proof:  proof,
+			Method: r.Method,
+			Uri:    requestUri(r),
+		})
+		if err != nil {
+			utils.HandleErrorResponse(w, handler.logger, err)
+			return
+		}
+		grantAccessTokenCommand.DPoPJkt = jkt
+		handler.logger.Debug("DPoP proof accepted, tokens will be sender-constrained", zap.String("jkt", jkt))
+	}
 
 	// Generate an access token
 	token, err := handler.tokenService.GrantAccessToken(grantAccessTokenCommand)
@@ -68,7 +127,27 @@ func (handler *tokenHandler) Token(w http.ResponseWriter, r *http.Request) {
 		token.AccessTokenExpiresIn,
 		token.RefreshToken,
 		utils.JoinStringSlice(token.Scope, " "))
+	res.IssuedTokenType = token.IssuedTokenType
+	res.IDToken = token.IDToken
+	res.AuthorizationDetails = token.AuthorizationDetails
 
 	// Send the response with the token
 	utils.RespondWithJSON(w, http.StatusOK, res)
 }
+
+// dpopProof returns the DPoP proof sent with the request, if any. At most one DPoP header is allowed.
+func dpopProof(r *http.Request) (string, error) {
+	proofs := r.Header.Values("DPoP")
+	if len(proofs) > 1 {
+		return "", fmt.Errorf("%w: multiple DPoP headers", api.ErrInvalidDPoPProof)
+	}
+	if len(proofs) == 0 {
+		return "", nil
+	}
+	return proofs[0], nil
+}
+
+// requestUri returns the URI a DPoP proof for this request must name in htu: the endpoint under the issuer URL.
+func requestUri(r *http.Request) string {
+	return configuration.IssuerURL + r.URL.Path
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -31,28 +33,60 @@ func (h *userinfoHandler) Userinfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Access tokens come with the Bearer scheme, or with the DPoP scheme and a proof when sender-constrained
 	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+	scheme, accessToken, found := strings.Cut(authHeader, " ")
+	if !found || (scheme != "Bearer" && scheme != services.DPoPTokenType) {
 		h.log.Warn("Missing or invalid Authorization header")
 		utils.RespondWithJSON(w, http.StatusUnauthorized, api.ErrorResponseBody(api.ErrInvalidRequest))
 		return
 	}
+	h.log.Debug("Extracted access token from header", zap.String("scheme", scheme), zap.String("accessToken", accessToken))
 
-	accessToken := strings.TrimPrefix(authHeader, "Bearer ")
-	h.log.Debug("Extracted access token from header", zap.String("accessToken", accessToken))
+	proof, err := dpopProof(r)
+	if err != nil {
+		h.log.Warn("Invalid DPoP header", zap.Error(err))
+		respondWithTokenError(w, scheme, err)
+		return
+	}
 
 	command := &services.GetUserinfoCommand{
 		AccessToken: accessToken,
+		Scheme:      scheme,
+		DPoPProof: &services.DPoPProofCommand{
+			Proof:       proof,
+			Method:      r.Method,
+			Uri:         requestUri(r),
+			AccessToken: accessToken,
+		},
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		command.ClientCertificate = r.TLS.PeerCertificates[0]
 	}
 	h.log.Debug("Created GetUserinfoCommand", zap.Any("command", command))
 
 	userinfo, err := h.userinfoService.GetUserinfo(command)
 	if err != nil {
 		h.log.Error("Error retrieving user info", zap.Error(err), zap.String("accessToken", accessToken))
-		utils.RespondWithJSON(w, http.StatusUnauthorized, api.ErrorResponseBody(api.ErrInvalidToken))
+		respondWithTokenError(w, scheme, err)
 		return
 	}
 
 	h.log.Info("Userinfo retrieved successfully", zap.Any("userinfo", userinfo))
 	utils.RespondWithJSON(w, http.StatusOK, userinfo)
 }
+
+// respondWithTokenError rejects a request to a protected resource with 401 and a WWW-Authenticate challenge for
+// the scheme the token was presented with (RFC 6750, section 3 and RFC 9449, section 7.1).
+func respondWithTokenError(w http.ResponseWriter, scheme string, err error) {
+	apiErr := api.ErrInvalidToken
+	if errors.Is(err, api.ErrInvalidDPoPProof) {
+		apiErr = api.ErrInvalidDPoPProof
+	}
+	if scheme == services.DPoPTokenType {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`DPoP error="%s", algs="%s"`, apiErr, strings.Join(utils.DPoPSigningAlgorithms, " ")))
+	} else {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s"`, apiErr))
+	}
+	utils.RespondWithJSON(w, http.StatusUnauthorized, api.ErrorResponseBody(apiErr))
+}
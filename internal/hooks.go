@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
 	"go.uber.org/fx"
 )
 
@@ -38,6 +39,27 @@ func SetupHTTPServer(
 				}
 			}()
 
+			// Serve the same routes over TLS, where clients can present certificates for mutual TLS (RFC 8705)
+			var tlsServer *http.Server
+			if configuration.TLSEnabled() {
+				tlsConfig, err := configuration.NewTLSConfig()
+				if err != nil {
+					return err
+				}
+				log.Info("Starting HTTPS server with client certificate requests", zap.String("addr", configuration.TLSAddr))
+				tlsServer = &http.Server{
+					Addr:      configuration.TLSAddr,
+					Handler:   mux,
+					TLSConfig: tlsConfig,
+				}
+
+				go func() {
+					if err := tlsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+						log.Error("Error starting HTTPS server", zap.Error(err))
+					}
+				}()
+			}
+
 			go func() {
 				stop := make(chan os.Signal, 1)
 				signal.Notify(stop, os.Interrupt)
@@ -50,6 +72,11 @@ func SetupHTTPServer(
 				if err := server.Shutdown(ctx); err != nil {
 					log.Error("Server forced to shutdown", zap.Error(err))
 				}
+				if tlsServer != nil {
+					if err := tlsServer.Shutdown(ctx); err != nil {
+						log.Error("HTTPS server forced to shutdown", zap.Error(err))
+					}
+				}
 			}()
 
 			return nil
@@ -6,4 +6,34 @@ const (
 	ClientSecretBasic TokenEndpointAuthMethod = "client_secret_basic"
 	ClientSecretPost  TokenEndpointAuthMethod = "client_secret_post"
 	None              TokenEndpointAuthMethod = "none"
+
+	// Mutual TLS client authentication methods (RFC 8705, section 2)
+	TLSClientAuth           TokenEndpointAuthMethod = "tls_client_auth"
+	SelfSignedTLSClientAuth TokenEndpointAuthMethod = "self_signed_tls_client_auth"
+
+	// JWT assertion client authentication methods (RFC 7523, section 2.2, and OpenID Connect Core, section 9)
+	PrivateKeyJWT   TokenEndpointAuthMethod = "private_key_jwt"
+	ClientSecretJWT TokenEndpointAuthMethod = "client_secret_jwt"
 )
+
+// ClientAssertionType is the only client_assertion_type accepted with JWT client assertions (RFC 7523, section 2.2).
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Supported lists every client authentication method the token endpoint accepts.
+var Supported = []TokenEndpointAuthMethod{ClientSecretBasic, ClientSecretPost, None, TLSClientAuth, SelfSignedTLSClientAuth, PrivateKeyJWT, ClientSecretJWT}
+
+// IsMutualTLS reports whether the method authenticates the client with its TLS client certificate.
+func IsMutualTLS(method TokenEndpointAuthMethod) bool {
+	return method == TLSClientAuth || method == SelfSignedTLSClientAuth
+}
+
+// IsJWTAssertion reports whether the method authenticates the client with a signed client_assertion.
+func IsJWTAssertion(method TokenEndpointAuthMethod) bool {
+	return method == PrivateKeyJWT || method == ClientSecretJWT
+}
+
+// IssuesClientSecret reports whether a client registered with the method is handed a client_secret. Clients that
+// authenticate with a private key or a certificate never receive a shared secret.
+func IssuesClientSecret(method TokenEndpointAuthMethod) bool {
+	return method != PrivateKeyJWT && !IsMutualTLS(method)
+}
@@ -0,0 +1,48 @@
+package authmethodtype
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"slices"
+)
+
+// CertificateSubject is the registered value a tls_client_auth client's certificate must carry: its subject
+// distinguished name or exactly one subject alternative name (RFC 8705, section 2.1.2).
+type CertificateSubject struct {
+	SubjectDN string `json:"tls_client_auth_subject_dn,omitempty"`
+	SanDNS    string `json:"tls_client_auth_san_dns,omitempty"`
+	SanURI    string `json:"tls_client_auth_san_uri,omitempty"`
+	SanIP     string `json:"tls_client_auth_san_ip,omitempty"`
+	SanEmail  string `json:"tls_client_auth_san_email,omitempty"`
+}
+
+// Count returns how many of the subject values are set; a valid registration sets exactly one.
+func (s CertificateSubject) Count() int {
+	count := 0
+	for _, value := range []string{s.SubjectDN, s.SanDNS, s.SanURI, s.SanIP, s.SanEmail} {
+		if value != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// MatchesCertificate reports whether cert carries the expected subject DN or subject alternative name.
+func (s CertificateSubject) MatchesCertificate(cert *x509.Certificate) bool {
+	switch {
+	case s.SubjectDN != "":
+		return cert.Subject.String() == s.SubjectDN
+	case s.SanDNS != "":
+		return slices.Contains(cert.DNSNames, s.SanDNS)
+	case s.SanURI != "":
+		return slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == s.SanURI })
+	case s.SanIP != "":
+		ip := net.ParseIP(s.SanIP)
+		return ip != nil && slices.ContainsFunc(cert.IPAddresses, ip.Equal)
+	case s.SanEmail != "":
+		return slices.Contains(cert.EmailAddresses, s.SanEmail)
+	default:
+		return false
+	}
+}
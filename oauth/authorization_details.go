@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// AuthorizationDetail is one entry of an authorization_details request parameter (RFC 9396, section 2). Besides its
+// type, the fields it may carry are defined by the schema registered for the type.
+type AuthorizationDetail map[string]interface{}
+
+// Type returns the type field every authorization detail must have.
+func (d AuthorizationDetail) Type() string {
+	detailType, _ := d["type"].(string)
+	return detailType
+}
+
+// AuthorizationDetails is the list of authorization details requested by or granted to a client.
+type AuthorizationDetails []AuthorizationDetail
+
+// ParseAuthorizationDetails decodes the JSON array sent in an authorization_details parameter. An empty value yields
+// no details.
+func ParseAuthorizationDetails(value string) (AuthorizationDetails, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var details AuthorizationDetails
+	if err := json.Unmarshal([]byte(value), &details); err != nil {
+		return nil, fmt.Errorf("authorization_details must be a JSON array of objects: %w", err)
+	}
+	if len(details) == 0 {
+		return nil, errors.New("authorization_details must not be empty")
+	}
+	for i, detail := range details {
+		if detail.Type() == "" {
+			return nil, fmt.Errorf("authorization_details[%d] has no type", i)
+		}
+	}
+	return details, nil
+}
+
+// String encodes the details as the JSON array they are stored and exchanged as, or returns an empty string when
+// there are none.
+func (d AuthorizationDetails) String() string {
+	if len(d) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// Types returns the distinct types of the details, in order of appearance.
+func (d AuthorizationDetails) Types() []string {
+	var types []string
+	for _, detail := range d {
+		if !slices.Contains(types, detail.Type()) {
+			types = append(types, detail.Type())
+		}
+	}
+	return types
+}
+
+// Covers reports whether every detail of requested was granted as is, so a token request may narrow the details of
+// a grant but never extend them (RFC 9396, section 6.1).
+func (d AuthorizationDetails) Covers(requested AuthorizationDetails) bool {
+	for _, detail := range requested {
+		if !slices.ContainsFunc(d, func(granted AuthorizationDetail) bool {
+			return reflect.DeepEqual(granted, detail)
+		}) {
+			return false
+		}
+	}
+	return true
+}
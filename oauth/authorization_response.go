@@ -0,0 +1,51 @@
+package oauth
+
+import "github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+
+// AuthorizationResponse holds the values the authorization endpoint returns to the client's redirect URI.
+// Which of them are set depends on the requested response type: a code, an access token, an ID token or a
+// combination of them.
+type AuthorizationResponse struct {
+	ResponseType responsetype.ResponseType
+	Code         *AuthCode
+	Token        *Token
+	IDToken      string
+}
+
+// UsesFragment reports whether the response must be delivered in the redirect URI fragment rather than its query.
+func (a *AuthorizationResponse) UsesFragment() bool {
+	return responsetype.UsesFragment(a.ResponseType)
+}
+
+// AuthorizationResponseBuilder helps in constructing AuthorizationResponse instances
+type AuthorizationResponseBuilder struct {
+	authorizationResponse AuthorizationResponse
+}
+
+func NewAuthorizationResponseBuilder() *AuthorizationResponseBuilder {
+	return &AuthorizationResponseBuilder{}
+}
+
+func (b *AuthorizationResponseBuilder) WithResponseType(responseType responsetype.ResponseType) *AuthorizationResponseBuilder {
+	b.authorizationResponse.ResponseType = responseType
+	return b
+}
+
+func (b *AuthorizationResponseBuilder) WithCode(code *AuthCode) *AuthorizationResponseBuilder {
+	b.authorizationResponse.Code = code
+	return b
+}
+
+func (b *AuthorizationResponseBuilder) WithToken(token *Token) *AuthorizationResponseBuilder {
+	b.authorizationResponse.Token = token
+	return b
+}
+
+func (b *AuthorizationResponseBuilder) WithIDToken(idToken string) *AuthorizationResponseBuilder {
+	b.authorizationResponse.IDToken = idToken
+	return b
+}
+
+func (b *AuthorizationResponseBuilder) Build() *AuthorizationResponse {
+	return &b.authorizationResponse
+}
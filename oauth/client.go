@@ -2,8 +2,10 @@ package oauth
 
 import (
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 )
 
 type Client struct {
@@ -17,6 +19,18 @@ type Client struct {
 	TokenEndpointAuthMethod authmethodtype.TokenEndpointAuthMethod
 	RedirectUris            []string
 	Scopes                  []Scope
+
+	Jwks                        string
+	JwksUri                     string
+	TokenExchangeSubjectClients []string
+	TokenExchangeAudiences      []string
+	RequestUris                 []string
+
+	RequirePushedAuthorizationRequests bool
+	RequireSignedRequestObject         bool
+	TLSClientAuth                      authmethodtype.CertificateSubject
+	TokenEncryption                    encryptiontype.TokenEncryption
+	TokenSigning                       signingtype.TokenSigning
 }
 
 type ClientBuilder struct {
@@ -88,6 +102,56 @@ func (b *ClientBuilder) WithScopes(scopes []Scope) *ClientBuilder {
 	return b
 }
 
+// WithTokenExchangePolicy sets the subject clients and audiences allowed for token exchange.
+func (b *ClientBuilder) WithTokenExchangePolicy(subjectClients, audiences []string) *ClientBuilder {
+	b.client.TokenExchangeSubjectClients = subjectClients
+	b.client.TokenExchangeAudiences = audiences
+	return b
+}
+
+// WithRequestUris sets the URLs the client may pass request objects by reference from.
+func (b *ClientBuilder) WithRequestUris(requestUris []string) *ClientBuilder {
+	b.client.RequestUris = requestUris
+	return b
+}
+
+// WithJwks sets the client's public keys, either inline or by reference.
+func (b *ClientBuilder) WithJwks(jwks, jwksUri string) *ClientBuilder {
+	b.client.Jwks = jwks
+	b.client.JwksUri = jwksUri
+	return b
+}
+
+// WithRequirePushedAuthorizationRequests sets whether the client must use pushed authorization requests.
+func (b *ClientBuilder) WithRequirePushedAuthorizationRequests(required bool) *ClientBuilder {
+	b.client.RequirePushedAuthorizationRequests = required
+	return b
+}
+
+// WithRequireSignedRequestObject sets whether the client must send its parameters in a signed request object.
+func (b *ClientBuilder) WithRequireSignedRequestObject(required bool) *ClientBuilder {
+	b.client.RequireSignedRequestObject = required
+	return b
+}
+
+// WithTLSClientAuth sets the certificate subject the client authenticates with when using tls_client_auth.
+func (b *ClientBuilder) WithTLSClientAuth(subject authmethodtype.CertificateSubject) *ClientBuilder {
+	b.client.TLSClientAuth = subject
+	return b
+}
+
+// WithTokenEncryption sets the algorithms the client's ID tokens and access tokens are encrypted with.
+func (b *ClientBuilder) WithTokenEncryption(encryption encryptiontype.TokenEncryption) *ClientBuilder {
+	b.client.TokenEncryption = encryption
+	return b
+}
+
+// WithTokenSigning sets the algorithms the client's ID tokens and access tokens are signed with.
+func (b *ClientBuilder) WithTokenSigning(signing signingtype.TokenSigning) *ClientBuilder {
+	b.client.TokenSigning = signing
+	return b
+}
+
 // Build constructs and returns the Client instance.
 func (b *ClientBuilder) Build() *Client {
 	return &b.client
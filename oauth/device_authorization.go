@@ -0,0 +1,73 @@
+package oauth
+
+import "time"
+
+// DeviceAuthorization is the outcome of a device authorization request (RFC 8628, section 3.2).
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	ClientId                string
+	Scope                   string
+	VerificationUri         string
+	VerificationUriComplete string
+	Interval                int
+	ExpiresAt               time.Time
+}
+
+// ExpiresIn returns the remaining lifetime of the device code in seconds.
+func (d *DeviceAuthorization) ExpiresIn() int {
+	return int(time.Until(d.ExpiresAt).Seconds())
+}
+
+// DeviceAuthorizationBuilder helps in constructing DeviceAuthorization instances
+type DeviceAuthorizationBuilder struct {
+	deviceAuthorization DeviceAuthorization
+}
+
+func NewDeviceAuthorizationBuilder() *DeviceAuthorizationBuilder {
+	return &DeviceAuthorizationBuilder{}
+}
+
+func (b *DeviceAuthorizationBuilder) WithDeviceCode(deviceCode string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.DeviceCode = deviceCode
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithUserCode(userCode string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.UserCode = userCode
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithClientId(clientId string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.ClientId = clientId
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithScope(scope string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.Scope = scope
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithVerificationUri(verificationUri string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.VerificationUri = verificationUri
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithVerificationUriComplete(verificationUriComplete string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.VerificationUriComplete = verificationUriComplete
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithInterval(interval int) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.Interval = interval
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithExpiresAt(expiresAt time.Time) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.ExpiresAt = expiresAt
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) Build() *DeviceAuthorization {
+	return &b.deviceAuthorization
+}
@@ -0,0 +1,78 @@
+package encryptiontype
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Tokens are encrypted as nested JWTs (RFC 7519, section 5.2): the content encryption key is wrapped with
+// RSA-OAEP-256 and the signed token is encrypted with AES-256-GCM.
+const (
+	RSAOAEP256 = "RSA-OAEP-256"
+	A256GCM    = "A256GCM"
+)
+
+// SupportedAlgorithms lists the key management algorithms tokens can be encrypted with.
+var SupportedAlgorithms = []string{RSAOAEP256}
+
+// SupportedEncryptionMethods lists the content encryption algorithms tokens can be encrypted with.
+var SupportedEncryptionMethods = []string{A256GCM}
+
+// TokenEncryption holds the JWE algorithms a client registers to receive its ID tokens (OpenID Connect Dynamic
+// Client Registration 1.0, section 2), access tokens and, for resource servers, JWT introspection responses
+// (RFC 9701, section 6) encrypted to one of its keys.
+type TokenEncryption struct {
+	IdTokenEncryptedResponseAlg       string `json:"id_token_encrypted_response_alg,omitempty"`
+	IdTokenEncryptedResponseEnc       string `json:"id_token_encrypted_response_enc,omitempty"`
+	AccessTokenEncryptedResponseAlg   string `json:"access_token_encrypted_response_alg,omitempty"`
+	AccessTokenEncryptedResponseEnc   string `json:"access_token_encrypted_response_enc,omitempty"`
+	IntrospectionEncryptedResponseAlg string `json:"introspection_encrypted_response_alg,omitempty"`
+	IntrospectionEncryptedResponseEnc string `json:"introspection_encrypted_response_enc,omitempty"`
+}
+
+// Normalize fills in the content encryption of every token the client asked to be encrypted without naming one.
+func (e *TokenEncryption) Normalize() {
+	if e.IdTokenEncryptedResponseAlg != "" && e.IdTokenEncryptedResponseEnc == "" {
+		e.IdTokenEncryptedResponseEnc = A256GCM
+	}
+	if e.AccessTokenEncryptedResponseAlg != "" && e.AccessTokenEncryptedResponseEnc == "" {
+		e.AccessTokenEncryptedResponseEnc = A256GCM
+	}
+	if e.IntrospectionEncryptedResponseAlg != "" && e.IntrospectionEncryptedResponseEnc == "" {
+		e.IntrospectionEncryptedResponseEnc = A256GCM
+	}
+}
+
+// Validate checks that every requested encryption uses supported algorithms, and that no content encryption is
+// registered without a key management algorithm.
+func (e TokenEncryption) Validate() error {
+	if err := validateAlgorithms("id_token", e.IdTokenEncryptedResponseAlg, e.IdTokenEncryptedResponseEnc); err != nil {
+		return err
+	}
+	if err := validateAlgorithms("access_token", e.AccessTokenEncryptedResponseAlg, e.AccessTokenEncryptedResponseEnc); err != nil {
+		return err
+	}
+	return validateAlgorithms("introspection", e.IntrospectionEncryptedResponseAlg, e.IntrospectionEncryptedResponseEnc)
+}
+
+// Requested reports whether the client asked for any of its tokens to be encrypted.
+func (e TokenEncryption) Requested() bool {
+	return e.IdTokenEncryptedResponseAlg != "" || e.AccessTokenEncryptedResponseAlg != "" ||
+		e.IntrospectionEncryptedResponseAlg != ""
+}
+
+func validateAlgorithms(token, alg, enc string) error {
+	if alg == "" {
+		if enc != "" {
+			return fmt.Errorf("%s_encrypted_response_enc requires %s_encrypted_response_alg", token, token)
+		}
+		return nil
+	}
+	if !slices.Contains(SupportedAlgorithms, alg) {
+		return fmt.Errorf("unsupported %s_encrypted_response_alg: %s", token, alg)
+	}
+	if !slices.Contains(SupportedEncryptionMethods, enc) {
+		return fmt.Errorf("unsupported %s_encrypted_response_enc: %s", token, enc)
+	}
+	return nil
+}
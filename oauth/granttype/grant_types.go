@@ -1,6 +1,9 @@
 package granttype
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 type GrantType string
 
@@ -10,8 +13,14 @@ const (
 	Password          GrantType = "password"
 	ClientCredentials GrantType = "client_credentials"
 	RefreshToken      GrantType = "refresh_token"
+	DeviceCode        GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	TokenExchange     GrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	JWTBearer         GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
 )
 
+// Supported lists every grant type the server implements, in the order it advertises them.
+var Supported = []GrantType{AuthorizationCode, Implicit, Password, ClientCredentials, RefreshToken, DeviceCode, TokenExchange, JWTBearer}
+
 // EnumListToStringList Convert a list of GrantType to a list of strings
 func EnumListToStringList(grantTypes []GrantType) []string {
 	var strings []string
@@ -25,10 +34,9 @@ func EnumListToStringList(grantTypes []GrantType) []string {
 func StringListToEnumList(strings []string) []GrantType {
 	var grantTypes []GrantType
 	for _, s := range strings {
-		switch GrantType(s) {
-		case AuthorizationCode, Implicit, Password, ClientCredentials, RefreshToken:
+		if slices.Contains(Supported, GrantType(s)) {
 			grantTypes = append(grantTypes, GrantType(s))
-		default:
+		} else {
 			_ = fmt.Errorf("invalid GrantType: %s", s)
 		}
 	}
@@ -0,0 +1,15 @@
+package oauth
+
+import "time"
+
+// PushedAuthorization is the outcome of a pushed authorization request (RFC 9126, section 2.2).
+type PushedAuthorization struct {
+	RequestUri string
+	ClientId   string
+	ExpiresAt  time.Time
+}
+
+// ExpiresIn returns the remaining lifetime of the request_uri in seconds.
+func (p *PushedAuthorization) ExpiresIn() int {
+	return int(time.Until(p.ExpiresAt).Round(time.Second).Seconds())
+}
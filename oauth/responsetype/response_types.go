@@ -1,6 +1,10 @@
 package responsetype
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 type ResponseType string
 
@@ -8,8 +12,68 @@ const (
 	Code    ResponseType = "code"
 	Token   ResponseType = "token"
 	IDToken ResponseType = "id_token"
+
+	// Combined response types defined by OAuth 2.0 Multiple Response Type Encoding Practices,
+	// written in their canonical order.
+	IDTokenToken     ResponseType = "id_token token"
+	CodeIDToken      ResponseType = "code id_token"
+	CodeToken        ResponseType = "code token"
+	CodeIDTokenToken ResponseType = "code id_token token"
 )
 
+// Supported lists every response type the authorization endpoint accepts.
+var Supported = []ResponseType{Code, Token, IDToken, IDTokenToken, CodeIDToken, CodeToken, CodeIDTokenToken}
+
+// componentOrder is the canonical order of the values that make up a combined response type.
+var componentOrder = []ResponseType{Code, IDToken, Token}
+
+// Components splits a space-delimited response type, such as "code id_token", into its individual values.
+func Components(rt ResponseType) []ResponseType {
+	var components []ResponseType
+	for _, value := range strings.Fields(string(rt)) {
+		components = append(components, ResponseType(value))
+	}
+	return components
+}
+
+// Normalize returns rt with its values in canonical order, so that "token code" and "code token" compare equal.
+// Unknown or repeated values are left in place, which makes the result fail IsSupported.
+func Normalize(rt ResponseType) ResponseType {
+	components := Components(rt)
+	slices.SortStableFunc(components, func(a, b ResponseType) int {
+		return rank(a) - rank(b)
+	})
+
+	values := make([]string, 0, len(components))
+	for _, component := range components {
+		values = append(values, string(component))
+	}
+	return ResponseType(strings.Join(values, " "))
+}
+
+// IsSupported reports whether rt, in any value order, is one of the Supported response types.
+func IsSupported(rt ResponseType) bool {
+	return slices.Contains(Supported, Normalize(rt))
+}
+
+// Includes reports whether the response type asks for the given individual value, e.g. whether it issues an ID token.
+func Includes(rt ResponseType, component ResponseType) bool {
+	return slices.Contains(Components(rt), component)
+}
+
+// UsesFragment reports whether the authorization response for rt is returned in the redirect URI fragment.
+// Only the plain code response type uses the query component.
+func UsesFragment(rt ResponseType) bool {
+	return Normalize(rt) != Code
+}
+
+func rank(rt ResponseType) int {
+	if idx := slices.Index(componentOrder, rt); idx >= 0 {
+		return idx
+	}
+	return len(componentOrder)
+}
+
 // EnumListToStringList Convert a list of ResponseType to a list of strings
 func EnumListToStringList(responseTypes []ResponseType) []string {
 	var strings []string
@@ -23,10 +87,9 @@ func EnumListToStringList(responseTypes []ResponseType) []string {
 func StringListToEnumList(strings []string) []ResponseType {
 	var responseTypes []ResponseType
 	for _, s := range strings {
-		switch ResponseType(s) {
-		case Code, Token, IDToken:
-			responseTypes = append(responseTypes, ResponseType(s))
-		default:
+		if IsSupported(ResponseType(s)) {
+			responseTypes = append(responseTypes, Normalize(ResponseType(s)))
+		} else {
 			_ = fmt.Errorf("invalid GrantType: %s", s)
 		}
 	}
@@ -0,0 +1,52 @@
+package signingtype
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Algorithms access tokens and ID tokens can be signed with (RFC 7518, section 3.1, and RFC 8037 for EdDSA).
+const (
+	RS256 = "RS256"
+	PS256 = "PS256"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	EdDSA = "EdDSA"
+)
+
+// Supported lists the signing algorithms the server can maintain keys for.
+var Supported = []string{RS256, PS256, ES256, ES384, EdDSA}
+
+// TokenSigning holds the algorithms a client registers for the signatures of its ID tokens (OpenID Connect Dynamic
+// Client Registration 1.0, section 2), access tokens and, for resource servers, JWT introspection responses
+// (RFC 9701, section 6). Tokens of a client that registers none are signed with the server's default algorithm.
+type TokenSigning struct {
+	IdTokenSignedResponseAlg       string `json:"id_token_signed_response_alg,omitempty"`
+	AccessTokenSignedResponseAlg   string `json:"access_token_signed_response_alg,omitempty"`
+	IntrospectionSignedResponseAlg string `json:"introspection_signed_response_alg,omitempty"`
+}
+
+// Validate checks that every registered algorithm is supported.
+func (s TokenSigning) Validate() error {
+	if s.IdTokenSignedResponseAlg != "" && !slices.Contains(Supported, s.IdTokenSignedResponseAlg) {
+		return fmt.Errorf("unsupported id_token_signed_response_alg: %s", s.IdTokenSignedResponseAlg)
+	}
+	if s.AccessTokenSignedResponseAlg != "" && !slices.Contains(Supported, s.AccessTokenSignedResponseAlg) {
+		return fmt.Errorf("unsupported access_token_signed_response_alg: %s", s.AccessTokenSignedResponseAlg)
+	}
+	if s.IntrospectionSignedResponseAlg != "" && !slices.Contains(Supported, s.IntrospectionSignedResponseAlg) {
+		return fmt.Errorf("unsupported introspection_signed_response_alg: %s", s.IntrospectionSignedResponseAlg)
+	}
+	return nil
+}
+
+// Algorithms returns the algorithms the client registered.
+func (s TokenSigning) Algorithms() []string {
+	var algorithms []string
+	for _, alg := range []string{s.IdTokenSignedResponseAlg, s.AccessTokenSignedResponseAlg, s.IntrospectionSignedResponseAlg} {
+		if alg != "" && !slices.Contains(algorithms, alg) {
+			algorithms = append(algorithms, alg)
+		}
+	}
+	return algorithms
+}
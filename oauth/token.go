@@ -19,6 +19,9 @@ type Token struct {
 	RefreshTokenCreatedAt time.Time
 	RefreshTokenExpiresAt time.Time
 	Extension             url.Values
+	IssuedTokenType       string
+	IDToken               string
+	AuthorizationDetails  AuthorizationDetails
 }
 
 type TokenBuilder struct {
@@ -35,6 +38,9 @@ type TokenBuilder struct {
 	refreshTokenCreatedAt time.Time
 	refreshTokenExpiresAt time.Time
 	extension             url.Values
+	issuedTokenType       string
+	idToken               string
+	authorizationDetails  AuthorizationDetails
 }
 
 func NewTokenBuilder() *TokenBuilder {
@@ -106,6 +112,24 @@ func (b *TokenBuilder) WithExtension(extension url.Values) *TokenBuilder {
 	return b
 }
 
+// WithIssuedTokenType sets the token type identifier returned by a token exchange (RFC 8693, section 2.2.1).
+func (b *TokenBuilder) WithIssuedTokenType(issuedTokenType string) *TokenBuilder {
+	b.issuedTokenType = issuedTokenType
+	return b
+}
+
+// WithIDToken sets the OpenID Connect ID token returned alongside the access token.
+func (b *TokenBuilder) WithIDToken(idToken string) *TokenBuilder {
+	b.idToken = idToken
+	return b
+}
+
+// WithAuthorizationDetails sets the authorization details (RFC 9396) the access token was issued for.
+func (b *TokenBuilder) WithAuthorizationDetails(authorizationDetails AuthorizationDetails) *TokenBuilder {
+	b.authorizationDetails = authorizationDetails
+	return b
+}
+
 func (b *TokenBuilder) Build() *Token {
 	return &Token{
 		ClientId:              b.clientId,
@@ -121,5 +145,8 @@ func (b *TokenBuilder) Build() *Token {
 		RefreshTokenCreatedAt: b.refreshTokenCreatedAt,
 		RefreshTokenExpiresAt: b.refreshTokenExpiresAt,
 		Extension:             b.extension,
+		IssuedTokenType:       b.issuedTokenType,
+		IDToken:               b.idToken,
+		AuthorizationDetails:  b.authorizationDetails,
 	}
 }
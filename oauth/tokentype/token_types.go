@@ -0,0 +1,31 @@
+package tokentype
+
+// TokenType identifies the kind of security token exchanged at the token endpoint (RFC 8693, section 3).
+type TokenType string
+
+const (
+	AccessToken  TokenType = "urn:ietf:params:oauth:token-type:access_token"
+	RefreshToken TokenType = "urn:ietf:params:oauth:token-type:refresh_token"
+	IdToken      TokenType = "urn:ietf:params:oauth:token-type:id_token"
+	JWT          TokenType = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// IsExchangeable reports whether tokens of this type can be presented as subject_token or actor_token.
+func IsExchangeable(tokenType TokenType) bool {
+	switch tokenType {
+	case AccessToken, JWT:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsIssuable reports whether this server can issue tokens of this type from a token exchange.
+func IsIssuable(tokenType TokenType) bool {
+	switch tokenType {
+	case AccessToken, JWT:
+		return true
+	default:
+		return false
+	}
+}
@@ -18,19 +18,37 @@ func Routes(registerHandler handlers.RegisterHandler,
 	logoutHandler handlers.LogoutHandler,
 	introspectionHandler handlers.IntrospectionHandler,
 	revocationHandler handlers.RevocationHandler,
+	deviceAuthorizationHandler handlers.DeviceAuthorizationHandler,
+	deviceVerificationHandler handlers.DeviceVerificationHandler,
+	discoveryHandler handlers.DiscoveryHandler,
+	pushedAuthorizationHandler handlers.PushedAuthorizationHandler,
+	signingKeysHandler handlers.SigningKeysHandler,
 ) map[string]http.HandlerFunc {
-	return map[string]http.HandlerFunc{
-		"/oauth/register":            registerHandler.Register,
-		"/oauth/token":               tokenHandler.Token,
-		"/oauth/authorize":           authorizeHandler.Authorize,
-		"/oauth/consent":             requestConsentHandler.RequestConsent,
-		"/oauth/login":               loginHandler.Login,
-		"/.well-known/jwks.json":     jwksHandler.Jwks,
-		"/oauth/userinfo":            userinfoHandler.Userinfo,
-		"/oauth/logout":              logoutHandler.Logout,
-		"/oauth/introspect":          introspectionHandler.Introspect,
-		"/oauth/revoke":              revocationHandler.Revoke,
-		"/google/authorize/callback": authorizeCallbackHandler.ProcessCallback,
-		"/health":                    healthHandler.Health,
+	routes := map[string]http.HandlerFunc{
+		"/oauth/register":                         registerHandler.Register,
+		"/oauth/token":                            tokenHandler.Token,
+		"/oauth/authorize":                        authorizeHandler.Authorize,
+		"/oauth/par":                              pushedAuthorizationHandler.PushAuthorization,
+		"/oauth/consent":                          requestConsentHandler.RequestConsent,
+		"/oauth/login":                            loginHandler.Login,
+		"/.well-known/jwks.json":                  jwksHandler.Jwks,
+		"/.well-known/openid-configuration":       discoveryHandler.OpenIDConfiguration,
+		"/.well-known/oauth-authorization-server": discoveryHandler.AuthorizationServerMetadata,
+		"/oauth/userinfo":                         userinfoHandler.Userinfo,
+		"/oauth/logout":                           logoutHandler.Logout,
+		"/oauth/introspect":                       introspectionHandler.Introspect,
+		"/oauth/revoke":                           revocationHandler.Revoke,
+		"/oauth/revoked":                          revocationHandler.RevokedTokens,
+		"/oauth/device_authorization":             deviceAuthorizationHandler.DeviceAuthorization,
+		"/oauth/device":                           deviceVerificationHandler.Verify,
+		"/google/authorize/callback":              authorizeCallbackHandler.ProcessCallback,
+		"/health":                                 healthHandler.Health,
+		"/admin/keys":                             signingKeysHandler.Keys,
+		"/admin/keys/rotate":                      signingKeysHandler.Rotate,
 	}
+
+	// The discovery documents are generated from the routes above, so they stay in sync as endpoints are added
+	discoveryHandler.RegisterRoutes(routes)
+
+	return routes
 }
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/oauth"
+	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type authorizationDetailsService struct {
+	authorizationDetailTypeRepository repositories.AuthorizationDetailTypeRepository
+	logger                            *zap.Logger
+}
+
+// NewAuthorizationDetailsService initializes a new AuthorizationDetailsService
+func NewAuthorizationDetailsService(authorizationDetailTypeRepository repositories.AuthorizationDetailTypeRepository,
+	logger *zap.Logger,
+) AuthorizationDetailsService {
+	return &authorizationDetailsService{
+		authorizationDetailTypeRepository: authorizationDetailTypeRepository,
+		logger:                            logger,
+	}
+}
+
+// Validate decodes an authorization_details parameter and checks each entry against the JSON schema registered for
+// its type (RFC 9396, section 5). An empty parameter yields no details.
+func (s *authorizationDetailsService) Validate(authorizationDetails string) (oauth.AuthorizationDetails, error) {
+	// Step 1: Decode the details
+	details, err := oauth.ParseAuthorizationDetails(authorizationDetails)
+	if err != nil {
+		s.logger.Warn("Malformed authorization details", zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidAuthorizationDetails, err)
+	}
+	if len(details) == 0 {
+		return nil, nil
+	}
+
+	// Step 2: Load the schema of every type once
+	schemas := make(map[string]map[string]interface{})
+	for _, detailType := range details.Types() {
+		registered, err := s.authorizationDetailTypeRepository.FindByType(detailType)
+		if err != nil {
+			s.logger.Warn("Unknown authorization details type", zap.String("type", detailType), zap.Error(err))
+			return nil, fmt.Errorf("%w: unknown type %s", api.ErrInvalidAuthorizationDetails, detailType)
+		}
+		schema, err := utils.ParseJSONSchema(registered.Schema)
+		if err != nil {
+			s.logger.Error("Invalid schema registered for authorization details type", zap.String("type", detailType), zap.Error(err))
+			return nil, fmt.Errorf("failed to load schema of authorization details type %s: %w", detailType, err)
+		}
+		schemas[detailType] = schema
+	}
+
+	// Step 3: Validate each detail against the schema of its type
+	for i, detail := range details {
+		if err := utils.ValidateJSONSchema(schemas[detail.Type()], map[string]interface{}(detail)); err != nil {
+			s.logger.Warn("Authorization detail does not match its schema", zap.Int("index", i), zap.String("type", detail.Type()), zap.Error(err))
+			return nil, fmt.Errorf("%w: authorization_details[%d]: %s", api.ErrInvalidAuthorizationDetails, i, err)
+		}
+	}
+
+	s.logger.Debug("Authorization details validated", zap.Strings("types", details.Types()))
+	return details, nil
+}
+
+// SupportedTypes returns the authorization details types clients may request.
+func (s *authorizationDetailsService) SupportedTypes() ([]string, error) {
+	return s.authorizationDetailTypeRepository.FindAllTypes()
+}
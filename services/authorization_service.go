@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/manuelrojas19/go-oauth2-server/configuration"
@@ -24,15 +25,26 @@ type AuthorizeCommand struct {
 	State               string
 	CodeChallenge       string
 	CodeChallengeMethod string
+	Nonce               string
+	RequestUri          string // set when the parameters came from a pushed authorization request
+	SignedRequestObject bool   // set when the parameters came from a verified request object
+	// AuthorizationDetails is the authorization_details parameter (RFC 9396), a JSON array of requested permissions
+	AuthorizationDetails string
+	// Resources are the resource indicators (RFC 8707) of the resources the client wants access to
+	Resources []string
 }
 
 type authorizationService struct {
-	oauthClientService OauthClientService
-	consentService     UserConsentService
-	authRepository     repositories.AuthorizationRepository
-	sessionService     SessionService
-	userRepository     repositories.UserRepository
-	logger             *zap.Logger
+	oauthClientService          OauthClientService
+	consentService              UserConsentService
+	authRepository              repositories.AuthorizationRepository
+	sessionService              SessionService
+	userRepository              repositories.UserRepository
+	tokenService                TokenService
+	authorizationDetailsService AuthorizationDetailsService
+	resourceService             ResourceService
+	tokenEncryptionService      TokenEncryptionService
+	logger                      *zap.Logger
 }
 
 // NewAuthorizationService initializes a new AuthorizationService
@@ -41,19 +53,29 @@ func NewAuthorizationService(oauthClientService OauthClientService,
 	authRepository repositories.AuthorizationRepository,
 	userSessionService SessionService,
 	userRepository repositories.UserRepository,
+	tokenService TokenService,
+	authorizationDetailsService AuthorizationDetailsService,
+	resourceService ResourceService,
+	tokenEncryptionService TokenEncryptionService,
 	logger *zap.Logger,
 ) AuthorizationService {
 	return &authorizationService{
-		oauthClientService: oauthClientService,
-		consentService:     consentService,
-		authRepository:     authRepository,
-		sessionService:     userSessionService,
-		userRepository:     userRepository,
-		logger:             logger,
+		oauthClientService:          oauthClientService,
+		consentService:              consentService,
+		authRepository:              authRepository,
+		sessionService:              userSessionService,
+		userRepository:              userRepository,
+		tokenService:                tokenService,
+		authorizationDetailsService: authorizationDetailsService,
+		resourceService:             resourceService,
+		tokenEncryptionService:      tokenEncryptionService,
+		logger:                      logger,
 	}
 }
 
-func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.AuthCode, error) {
+// Authorize authenticates the resource owner's session and builds the authorization response for the requested
+// response type: an authorization code, an access token, an ID token or a combination of them.
+func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.AuthorizationResponse, error) {
 	clientId := command.ClientId
 
 	a.logger.Info("Authorize request will be processed",
@@ -68,7 +90,7 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 	start := time.Now()
 
 	// Validate response type
-	if command.ResponseType != responsetype.Code {
+	if !responsetype.IsSupported(command.ResponseType) {
 		a.logger.Error("Unsupported response type",
 			zap.String("responseType", string(command.ResponseType)),
 			zap.String("clientId", clientId),
@@ -77,7 +99,8 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 		)
 		return nil, fmt.Errorf(errors.ErrUnsupportedResponseType)
 	}
-	a.logger.Debug("Response type validated", zap.String("responseType", string(command.ResponseType)))
+	responseType := responsetype.Normalize(command.ResponseType)
+	a.logger.Debug("Response type validated", zap.String("responseType", string(responseType)))
 
 	// Retrieve the OAuth client
 	client, err := a.oauthClientService.FindOauthClient(clientId)
@@ -97,7 +120,7 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 	a.logger.Debug("Retrieved OAuth client details", zap.Any("client", client))
 
 	// Validate redirect URI
-	if !isRegisteredRedirectUri(command, client) {
+	if !isRegisteredRedirectUri(command.RedirectUri, client) {
 		a.logger.Error("Invalid redirect URI",
 			zap.String("redirectUri", command.RedirectUri),
 			zap.String("clientId", clientId),
@@ -108,6 +131,35 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 	}
 	a.logger.Debug("Redirect URI validated", zap.String("redirectUri", command.RedirectUri))
 
+	// Clients registered for PAR may only send parameters that were pushed beforehand
+	if client.RequirePushedAuthorizationRequests && command.RequestUri == "" {
+		a.logger.Error("Pushed authorization request required for client",
+			zap.String("clientId", clientId),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return nil, fmt.Errorf(errors.ErrPushedAuthorizationRequired)
+	}
+
+	// Clients registered for JAR may only send parameters inside a signed request object
+	if client.RequireSignedRequestObject && !command.SignedRequestObject {
+		a.logger.Error("Signed request object required for client",
+			zap.String("clientId", clientId),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return nil, fmt.Errorf(errors.ErrSignedRequestObjectRequired)
+	}
+
+	// Validate the client registered the requested response type
+	if !client.SupportsResponseType(responseType) {
+		a.logger.Error("Response type not registered for client",
+			zap.String("responseType", string(responseType)),
+			zap.String("clientId", clientId),
+			zap.Strings("registeredResponseTypes", client.ResponseTypes),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return nil, fmt.Errorf("%s: %s", errors.ErrUnregisteredResponseType, responseType)
+	}
+
 	// Check if user is authenticated
 	if !a.sessionService.SessionExists(command.SessionId) {
 		a.logger.Warn("User not authenticated",
@@ -149,6 +201,17 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 	}
 	a.logger.Debug("User found in database", zap.String("userId", user.Id))
 
+	// Retrieve when the user authenticated, reported in ID tokens as auth_time
+	var authTime *time.Time
+	if authenticatedAt, err := a.sessionService.GetAuthTimeFromSession(command.SessionId); err != nil {
+		a.logger.Warn("Auth time not available for session, auth_time will be omitted",
+			zap.String("sessionId", command.SessionId),
+			zap.Error(err),
+		)
+	} else {
+		authTime = &authenticatedAt
+	}
+
 	// // Validate access consent, not required for Idp
 	// if !a.consentService.HasUserConsented(user.Id, client.ClientId, command.Scope) {
 	// 	a.logger.Warn("User consent required",
@@ -163,12 +226,127 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 
 	// a.logger.Debug("User consent confirmed")
 
+	// Resolve the requested scopes against the client's registered scopes
+	if err := a.oauthClientService.PreloadOauthClientScopes(client); err != nil {
+		a.logger.Error("Error preloading client scopes",
+			zap.String("clientId", clientId),
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
+	}
+	scopes, err := resolveRequestedScopes(client.Scopes, command.Scope)
+	if err != nil {
+		a.logger.Warn("Requested scope not registered for client",
+			zap.String("clientId", clientId),
+			zap.String("scope", command.Scope),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	// Restrict the scopes to the ones the requested resources own
+	scopes, err = a.resourceService.RestrictScopes(command.Resources, scopes)
+	if err != nil {
+		a.logger.Warn("Invalid resources requested",
+			zap.String("clientId", clientId),
+			zap.Strings("resources", command.Resources),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	// Validate the requested authorization details against the schemas of their types
+	authorizationDetails, err := a.authorizationDetailsService.Validate(command.AuthorizationDetails)
+	if err != nil {
+		a.logger.Warn("Invalid authorization details requested",
+			zap.String("clientId", clientId),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	responseBuilder := oauth.NewAuthorizationResponseBuilder().WithResponseType(responseType)
+
+	var code string
+	if responsetype.Includes(responseType, responsetype.Code) {
+		authCode, err := a.issueAuthorizationCode(command, client, user, scopes, authorizationDetails, authTime)
+		if err != nil {
+			return nil, err
+		}
+		code = authCode.Code
+		responseBuilder.WithCode(authCode)
+	}
+
+	var accessToken string
+	if responsetype.Includes(responseType, responsetype.Token) {
+		token, err := a.tokenService.GrantImplicitAccessToken(&ImplicitGrantCommand{
+			Client:               client,
+			UserId:               user.Id,
+			Scopes:               scopes,
+			AuthorizationDetails: authorizationDetails,
+			Resources:            command.Resources,
+			AuthTime:             authTime,
+		})
+		if err != nil {
+			a.logger.Error("Error issuing implicit access token",
+				zap.String("clientId", clientId),
+				zap.String("userId", user.Id),
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return nil, fmt.Errorf("failed to issue access token: %w", err)
+		}
+		accessToken = token.AccessToken
+		responseBuilder.WithToken(token)
+	}
+
+	if responsetype.Includes(responseType, responsetype.IDToken) {
+		idToken, err := generateIDToken(&idTokenGrant{
+			alg:         client.TokenSigning.IdTokenSignedResponseAlg,
+			clientId:    client.ClientId,
+			userId:      user.Id,
+			nonce:       command.Nonce,
+			authTime:    authTime,
+			accessToken: accessToken,
+			code:        code,
+		})
+		if err != nil {
+			a.logger.Error("Error generating ID token",
+				zap.String("clientId", clientId),
+				zap.String("userId", user.Id),
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return nil, fmt.Errorf("failed to generate ID token: %w", err)
+		}
+		idToken, err = a.tokenEncryptionService.EncryptIDToken(idToken, client)
+		if err != nil {
+			return nil, err
+		}
+		responseBuilder.WithIDToken(idToken)
+	}
+
+	a.logger.Info("Successfully built authorization response",
+		zap.String("clientId", client.ClientId),
+		zap.String("userId", userId),
+		zap.String("responseType", string(responseType)),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	return responseBuilder.Build(), nil
+}
+
+// issueAuthorizationCode generates and persists an authorization code for the authenticated user.
+func (a *authorizationService) issueAuthorizationCode(command *AuthorizeCommand, client *store.OauthClient, user *store.User, scopes []store.Scope, authorizationDetails oauth.AuthorizationDetails, authTime *time.Time) (*oauth.AuthCode, error) {
+	start := time.Now()
+
 	// Generate authorization code
-	code, err := utils.GenerateAuthCode(client.ClientId, userId)
+	code, err := utils.GenerateAuthCode(client.ClientId, user.Id)
 	if err != nil {
 		a.logger.Error("Error generating authorization code",
 			zap.String("clientId", client.ClientId),
-			zap.String("userId", userId),
+			zap.String("userId", user.Id),
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 			zap.Stack("stacktrace"),
@@ -184,8 +362,13 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 		WithClient(client).
 		WithUserId(&user.Id).
 		WithRedirectURI(command.RedirectUri).
+		WithScopes(scopes).
 		WithCodeChallenge(command.CodeChallenge).
 		WithCodeChallengeMethod(command.CodeChallengeMethod).
+		WithNonce(command.Nonce).
+		WithAuthTime(authTime).
+		WithAuthorizationDetails(authorizationDetails.String()).
+		WithResources(command.Resources).
 		WithExpiresAt(time.Now().Add(configuration.AuthCodeExpireTime)).
 		Build()
 	a.logger.Debug("Authorization code entity built", zap.Any("authCodeEntity", authCodeEntity))
@@ -197,30 +380,23 @@ func (a *authorizationService) Authorize(command *AuthorizeCommand) (*oauth.Auth
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 			zap.Stack("stacktrace"),
-			zap.String("authCode", authCodeEntity.Code),
+			zap.String("authCode", code),
 		)
 		return nil, fmt.Errorf("failed to save authorization code entity: %w", err)
 	}
 	a.logger.Info("Authorization code entity saved successfully", zap.String("authCode", authCodeEntity.Code))
 
 	// Build the OAuth authorization code response
-	oauthCode := oauth.NewAuthCodeBuilder().
+	return oauth.NewAuthCodeBuilder().
 		WithCode(authCodeEntity.Code).
 		WithClientId(*authCodeEntity.ClientId).
 		WithRedirectURI(authCodeEntity.RedirectURI).
+		WithScope(strings.Join(utils.ScopesToStringSlice(scopes), " ")).
 		WithCreatedAt(authCodeEntity.CreatedAt).
 		WithExpiresAt(authCodeEntity.ExpiresAt).
-		Build()
-
-	a.logger.Info("Successfully generated authorization code",
-		zap.String("clientId", client.ClientId),
-		zap.String("userId", userId),
-		zap.Duration("duration", time.Since(start)),
-	)
-
-	return oauthCode, nil
+		Build(), nil
 }
 
-func isRegisteredRedirectUri(command *AuthorizeCommand, client *store.OauthClient) bool {
-	return slices.Contains(client.RedirectURIs, command.RedirectUri)
+func isRegisteredRedirectUri(redirectUri string, client *store.OauthClient) bool {
+	return slices.Contains(client.RedirectURIs, redirectUri)
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+// ClientAuthenticationCommand holds the credentials a client sent to an endpoint that requires client authentication.
+type ClientAuthenticationCommand struct {
+	ClientId        string
+	ClientSecret    string
+	ClientAssertion string
+	// ClientCertificates is the certificate chain the client presented during the TLS handshake, leaf first
+	ClientCertificates []*x509.Certificate
+}
+
+type clientAuthenticator struct {
+	oauthClientService OauthClientService
+	replayCache        ReplayCache
+	logger             *zap.Logger
+}
+
+// NewClientAuthenticator initializes a new ClientAuthenticator
+func NewClientAuthenticator(oauthClientService OauthClientService, replayCache ReplayCache, logger *zap.Logger) ClientAuthenticator {
+	return &clientAuthenticator{
+		oauthClientService: oauthClientService,
+		replayCache:        replayCache,
+		logger:             logger,
+	}
+}
+
+// Authenticate resolves the client that sent the credentials and checks them with the token_endpoint_auth_method the
+// client registered. Credentials of any other method are rejected, so a client cannot fall back to a weaker one.
+func (a *clientAuthenticator) Authenticate(command *ClientAuthenticationCommand) (*store.OauthClient, error) {
+	// Step 1: Resolve the client
+	if command.ClientId == "" {
+		a.logger.Warn("Client authentication attempted without a client_id")
+		return nil, fmt.Errorf("%w: client_id is required", api.ErrInvalidClient)
+	}
+
+	client, err := a.oauthClientService.FindOauthClient(command.ClientId)
+	if err != nil {
+		a.logger.Warn("Client authentication attempted for an unknown client", zap.String("clientId", command.ClientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: unknown client", api.ErrInvalidClient)
+	}
+
+	// Step 2: Check the credentials with the registered method
+	method := authmethodtype.TokenEndpointAuthMethod(client.TokenEndpointAuthMethod)
+	if err := a.authenticate(client, method, command); err != nil {
+		a.logger.Warn("Client authentication failed", zap.String("clientId", client.ClientId), zap.String("method", string(method)), zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidClient, err)
+	}
+
+	a.logger.Debug("Client authenticated", zap.String("clientId", client.ClientId), zap.String("method", string(method)))
+	return client, nil
+}
+
+// authenticate checks the credentials of command against the given method of client.
+func (a *clientAuthenticator) authenticate(client *store.OauthClient, method authmethodtype.TokenEndpointAuthMethod, command *ClientAuthenticationCommand) error {
+	switch {
+	case method == authmethodtype.None:
+		if command.ClientSecret != "" || command.ClientAssertion != "" {
+			return errors.New("public client must not send credentials")
+		}
+		return nil
+	case authmethodtype.IsJWTAssertion(method):
+		if command.ClientSecret != "" {
+			return fmt.Errorf("client must authenticate with %s", method)
+		}
+		return a.authenticateClientAssertion(client, command.ClientAssertion)
+	case authmethodtype.IsMutualTLS(method):
+		if command.ClientSecret != "" || command.ClientAssertion != "" {
+			return fmt.Errorf("client must authenticate with %s", method)
+		}
+		return authenticateTLSClient(client, command.ClientCertificates)
+	default:
+		// client_secret_basic and client_secret_post clients may send the secret either way (RFC 6749, section 2.3.1)
+		if command.ClientAssertion != "" {
+			return fmt.Errorf("client must authenticate with %s", method)
+		}
+		if command.ClientSecret == "" {
+			return errors.New("client secret is required")
+		}
+		return client.ValidateSecret(command.ClientSecret)
+	}
+}
+
+// authenticateClientAssertion authenticates a private_key_jwt or client_secret_jwt client with the JWT it sent in
+// client_assertion (RFC 7523, section 3). The assertion is signed with one of the client's registered keys or with its
+// client secret, names the client in iss and sub, and can only be used once.
+func (a *clientAuthenticator) authenticateClientAssertion(client *store.OauthClient, assertion string) error {
+	if assertion == "" {
+		return errors.New("client assertion is required")
+	}
+
+	// Step 1: Verify the signature with the registered keys or the client secret
+	var verified jwt.Token
+	switch authmethodtype.TokenEndpointAuthMethod(client.TokenEndpointAuthMethod) {
+	case authmethodtype.PrivateKeyJWT:
+		keySet, err := utils.LoadJWKSet(client.Jwks, client.JwksUri)
+		if err != nil {
+			return fmt.Errorf("failed to load client keys: %w", err)
+		}
+		verified, err = utils.VerifyJWTWithKeySet(assertion, keySet)
+		if err != nil {
+			return err
+		}
+	case authmethodtype.ClientSecretJWT:
+		secret, err := utils.OpenText(client.SealedClientSecret, configuration.ClientSecretKey)
+		if err != nil {
+			return fmt.Errorf("failed to read client secret: %w", err)
+		}
+		verified, err = utils.VerifyJWTWithSecret(assertion, []byte(secret))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("client does not use client assertions: %s", client.TokenEndpointAuthMethod)
+	}
+
+	// Step 2: Check the iss, sub, aud and exp claims
+	if verified.Issuer() != client.ClientId || verified.Subject() != client.ClientId {
+		return errors.New("client assertion iss and sub must be the client_id")
+	}
+	if !slices.ContainsFunc(verified.Audience(), isTokenEndpointAudience) {
+		return errors.New("client assertion audience does not identify this server")
+	}
+	if verified.Expiration().After(time.Now().Add(configuration.AssertionMaxLifetime)) {
+		return errors.New("client assertion expires too far in the future")
+	}
+
+	// Step 3: Reject replayed assertions
+	fresh, err := a.replayCache.Remember("client-assertion:"+client.ClientId, verified.JwtID(), verified.Expiration())
+	if err != nil {
+		a.logger.Error("Error checking client assertion for replay", zap.Error(err))
+		return fmt.Errorf("failed to check client assertion for replay: %w", err)
+	}
+	if !fresh {
+		a.logger.Warn("Client assertion replayed", zap.String("clientId", client.ClientId), zap.String("jti", verified.JwtID()))
+		return errors.New("client assertion has already been used")
+	}
+	return nil
+}
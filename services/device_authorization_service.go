@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/errors"
+	"github.com/manuelrojas19/go-oauth2-server/oauth"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+// DeviceVerificationPath is the user-facing page where device user codes are entered and approved.
+const DeviceVerificationPath = "/oauth/device"
+
+type DeviceAuthorizationCommand struct {
+	Client *store.OauthClient // client the ClientAuthenticator authenticated for the request
+	Scope  string
+}
+
+type DeviceVerificationCommand struct {
+	UserCode  string
+	SessionId string
+	Approved  bool
+}
+
+type deviceAuthorizationService struct {
+	oauthClientService            OauthClientService
+	deviceAuthorizationRepository repositories.DeviceAuthorizationRepository
+	sessionService                SessionService
+	logger                        *zap.Logger
+}
+
+// NewDeviceAuthorizationService initializes a new DeviceAuthorizationService
+func NewDeviceAuthorizationService(oauthClientService OauthClientService,
+	deviceAuthorizationRepository repositories.DeviceAuthorizationRepository,
+	sessionService SessionService,
+	logger *zap.Logger,
+) DeviceAuthorizationService {
+	return &deviceAuthorizationService{
+		oauthClientService:            oauthClientService,
+		deviceAuthorizationRepository: deviceAuthorizationRepository,
+		sessionService:                sessionService,
+		logger:                        logger,
+	}
+}
+
+// RequestDeviceAuthorization validates the authenticated client and the requested scope, then issues a new device code and user code.
+func (d *deviceAuthorizationService) RequestDeviceAuthorization(command *DeviceAuthorizationCommand) (*oauth.DeviceAuthorization, error) {
+	client := command.Client
+	clientId := client.ClientId
+	d.logger.Info("Processing device authorization request", zap.String("clientId", clientId), zap.String("scope", command.Scope))
+
+	if !slices.Contains(client.GrantTypes, string(granttype.DeviceCode)) {
+		d.logger.Warn("Client is not allowed to use the device code grant", zap.String("clientId", clientId))
+		return nil, api.ErrUnauthorizedClient
+	}
+
+	if err := d.oauthClientService.PreloadOauthClientScopes(client); err != nil {
+		d.logger.Error("Error preloading client scopes for device authorization", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
+	}
+
+	if _, err := resolveRequestedScopes(client.Scopes, command.Scope); err != nil {
+		d.logger.Warn("Requested scope is not allowed for client", zap.String("clientId", clientId), zap.String("scope", command.Scope), zap.Error(err))
+		return nil, err
+	}
+
+	deviceCode, err := utils.GenerateDeviceCode()
+	if err != nil {
+		d.logger.Error("Error generating device code", zap.Error(err))
+		return nil, err
+	}
+
+	userCode, err := utils.GenerateUserCode()
+	if err != nil {
+		d.logger.Error("Error generating user code", zap.Error(err))
+		return nil, err
+	}
+
+	deviceAuthorization := store.NewDeviceAuthorizationBuilder().
+		WithDeviceCode(deviceCode).
+		WithUserCode(userCode).
+		WithClientId(&client.ClientId).
+		WithScope(command.Scope).
+		WithInterval(int(configuration.DeviceCodePollInterval.Seconds())).
+		WithExpiresAt(time.Now().Add(configuration.DeviceCodeExpireTime)).
+		Build()
+
+	saved, err := d.deviceAuthorizationRepository.Save(deviceAuthorization)
+	if err != nil {
+		d.logger.Error("Error saving device authorization", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
+	}
+	d.logger.Info("Device authorization issued", zap.String("clientId", clientId), zap.String("userCode", saved.UserCode))
+
+	verificationUri := configuration.IssuerURL + DeviceVerificationPath
+	return oauth.NewDeviceAuthorizationBuilder().
+		WithDeviceCode(saved.DeviceCode).
+		WithUserCode(saved.UserCode).
+		WithClientId(client.ClientId).
+		WithScope(saved.Scope).
+		WithVerificationUri(verificationUri).
+		WithVerificationUriComplete(verificationUri + "?user_code=" + url.QueryEscape(saved.UserCode)).
+		WithInterval(saved.Interval).
+		WithExpiresAt(saved.ExpiresAt).
+		Build(), nil
+}
+
+// FindPendingByUserCode looks up a device authorization that is still waiting for the end user's decision.
+func (d *deviceAuthorizationService) FindPendingByUserCode(userCode string) (*oauth.DeviceAuthorization, error) {
+	deviceAuthorization, err := d.findPending(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth.NewDeviceAuthorizationBuilder().
+		WithUserCode(deviceAuthorization.UserCode).
+		WithClientId(utils.StringDeref(deviceAuthorization.ClientId)).
+		WithScope(deviceAuthorization.Scope).
+		WithInterval(deviceAuthorization.Interval).
+		WithExpiresAt(deviceAuthorization.ExpiresAt).
+		Build(), nil
+}
+
+// Decide records the authenticated end user's approval or denial of a pending device authorization.
+func (d *deviceAuthorizationService) Decide(command *DeviceVerificationCommand) error {
+	d.logger.Info("Processing device verification decision", zap.String("userCode", command.UserCode), zap.Bool("approved", command.Approved))
+
+	if !d.sessionService.SessionExists(command.SessionId) {
+		d.logger.Warn("User is not authenticated for device verification", zap.String("userCode", command.UserCode))
+		return fmt.Errorf(errors.ErrUserNotAuthenticated)
+	}
+
+	userId, err := d.sessionService.GetUserIdFromSession(command.SessionId)
+	if err != nil {
+		d.logger.Error("Error retrieving user from session", zap.Error(err))
+		return fmt.Errorf(errors.ErrUserNotAuthenticated)
+	}
+
+	deviceAuthorization, err := d.findPending(command.UserCode)
+	if err != nil {
+		return err
+	}
+
+	deviceAuthorization.UserId = &userId
+	deviceAuthorization.Status = store.DeviceAuthorizationDenied
+	if command.Approved {
+		deviceAuthorization.Status = store.DeviceAuthorizationApproved
+	}
+
+	if err := d.deviceAuthorizationRepository.Update(deviceAuthorization); err != nil {
+		d.logger.Error("Error saving device verification decision", zap.String("userCode", command.UserCode), zap.Error(err))
+		return err
+	}
+	d.logger.Info("Device verification decision saved", zap.String("userCode", command.UserCode), zap.String("status", deviceAuthorization.Status))
+	return nil
+}
+
+func (d *deviceAuthorizationService) findPending(userCode string) (*store.DeviceAuthorization, error) {
+	userCode = utils.NormalizeUserCode(userCode)
+
+	deviceAuthorization, err := d.deviceAuthorizationRepository.FindByUserCode(userCode)
+	if err != nil {
+		d.logger.Warn("Unknown user code", zap.String("userCode", userCode), zap.Error(err))
+		return nil, fmt.Errorf("%w: unknown user code", api.ErrInvalidRequest)
+	}
+
+	if deviceAuthorization.IsExpired() {
+		d.logger.Warn("User code has expired", zap.String("userCode", userCode))
+		return nil, api.ErrExpiredToken
+	}
+
+	if deviceAuthorization.Status != store.DeviceAuthorizationPending {
+		d.logger.Warn("User code has already been used", zap.String("userCode", userCode), zap.String("status", deviceAuthorization.Status))
+		return nil, fmt.Errorf("%w: user code has already been used", api.ErrInvalidRequest)
+	}
+
+	return deviceAuthorization, nil
+}
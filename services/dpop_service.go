@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+// DPoPTokenType is the token_type of access tokens bound to a DPoP key, and the authorization scheme they are
+// presented with (RFC 9449, section 5).
+const DPoPTokenType = "DPoP"
+
+// DPoPProofCommand describes the request a DPoP proof was sent with.
+type DPoPProofCommand struct {
+	Proof       string
+	Method      string
+	Uri         string
+	AccessToken string // set at protected resources, where the proof must carry the token hash in ath
+}
+
+// TokenBindingCommand describes how an access token was presented to a protected resource.
+type TokenBindingCommand struct {
+	Jkt    string // DPoP key thumbprint the token is bound to, empty for bearer tokens
+	Scheme string // authorization scheme the token was presented with
+	Proof  *DPoPProofCommand
+}
+
+type dpopService struct {
+	replayCache ReplayCache
+	logger      *zap.Logger
+}
+
+// NewDPoPService initializes a new DPoPService
+func NewDPoPService(replayCache ReplayCache, logger *zap.Logger) DPoPService {
+	return &dpopService{
+		replayCache: replayCache,
+		logger:      logger,
+	}
+}
+
+// ValidateProof checks a DPoP proof against the request it came with and returns the thumbprint of the key that
+// signed it. Each proof is accepted once, for a short time after it was issued (RFC 9449, section 4.3).
+func (d *dpopService) ValidateProof(command *DPoPProofCommand) (string, error) {
+	proof, err := utils.ParseDPoPProof(command.Proof)
+	if err != nil {
+		d.logger.Warn("Invalid DPoP proof", zap.Error(err))
+		return "", fmt.Errorf("%w: %s", api.ErrInvalidDPoPProof, err)
+	}
+
+	if proof.Method != command.Method {
+		d.logger.Warn("DPoP proof htm does not match the request", zap.String("htm", proof.Method), zap.String("method", command.Method))
+		return "", fmt.Errorf("%w: htm does not match the request method", api.ErrInvalidDPoPProof)
+	}
+
+	htu, err := utils.NormalizeDPoPUri(proof.Uri)
+	if err != nil {
+		d.logger.Warn("Malformed DPoP proof htu", zap.String("htu", proof.Uri), zap.Error(err))
+		return "", fmt.Errorf("%w: %s", api.ErrInvalidDPoPProof, err)
+	}
+	requestUri, err := utils.NormalizeDPoPUri(command.Uri)
+	if err != nil || htu != requestUri {
+		d.logger.Warn("DPoP proof htu does not match the request", zap.String("htu", htu), zap.String("uri", command.Uri))
+		return "", fmt.Errorf("%w: htu does not match the request URI", api.ErrInvalidDPoPProof)
+	}
+
+	now := time.Now()
+	if proof.IssuedAt.Before(now.Add(-configuration.DPoPProofMaxAge)) || proof.IssuedAt.After(now.Add(configuration.DPoPProofClockSkew)) {
+		d.logger.Warn("DPoP proof iat is outside the acceptable window", zap.Time("iat", proof.IssuedAt))
+		return "", fmt.Errorf("%w: iat is too old or in the future", api.ErrInvalidDPoPProof)
+	}
+
+	if command.AccessToken != "" && proof.AccessTokenHash != utils.DPoPAccessTokenHash(command.AccessToken) {
+		d.logger.Warn("DPoP proof ath does not match the access token")
+		return "", fmt.Errorf("%w: ath does not match the access token", api.ErrInvalidDPoPProof)
+	}
+
+	fresh, err := d.replayCache.Remember("dpop:"+proof.Jkt, proof.JwtID, proof.IssuedAt.Add(configuration.DPoPProofMaxAge))
+	if err != nil {
+		d.logger.Error("Error checking DPoP proof for replay", zap.Error(err))
+		return "", api.ErrServerError
+	}
+	if !fresh {
+		d.logger.Warn("DPoP proof replayed", zap.String("jkt", proof.Jkt), zap.String("jti", proof.JwtID))
+		return "", fmt.Errorf("%w: proof has already been used", api.ErrInvalidDPoPProof)
+	}
+
+	d.logger.Debug("DPoP proof validated", zap.String("jkt", proof.Jkt))
+	return proof.Jkt, nil
+}
+
+// ValidateTokenBinding enforces the binding of an access token presented to a protected resource: bound tokens
+// must come with the DPoP scheme and a proof signed by their key, bearer tokens with the Bearer scheme.
+func (d *dpopService) ValidateTokenBinding(command *TokenBindingCommand) error {
+	if command.Jkt == "" {
+		if command.Scheme == DPoPTokenType {
+			d.logger.Warn("Bearer token presented with the DPoP scheme")
+			return fmt.Errorf("%w: token is not bound to a DPoP key", api.ErrInvalidToken)
+		}
+		return nil
+	}
+
+	if command.Scheme != DPoPTokenType {
+		d.logger.Warn("DPoP-bound token presented as a bearer token", zap.String("scheme", command.Scheme))
+		return fmt.Errorf("%w: token is bound to a DPoP key", api.ErrInvalidToken)
+	}
+	if command.Proof == nil || command.Proof.Proof == "" {
+		d.logger.Warn("DPoP-bound token presented without a proof")
+		return fmt.Errorf("%w: DPoP proof is required", api.ErrInvalidDPoPProof)
+	}
+
+	jkt, err := d.ValidateProof(command.Proof)
+	if err != nil {
+		return err
+	}
+	if jkt != command.Jkt {
+		d.logger.Warn("DPoP proof was signed by another key", zap.String("jkt", jkt), zap.String("boundJkt", command.Jkt))
+		return fmt.Errorf("%w: proof key does not match the token binding", api.ErrInvalidDPoPProof)
+	}
+	return nil
+}
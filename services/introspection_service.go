@@ -1,106 +1,348 @@
 package services
 
 import (
-	"time"
+	"encoding/json"
+	"fmt"
+	"slices"
 
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth"
+	"github.com/manuelrojas19/go-oauth2-server/store"
 	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
 	"go.uber.org/zap"
 )
 
 type IntrospectCommand struct {
 	Token         string
 	TokenTypeHint string
+	ClientId      string // client that authenticated to make the request
 }
 
 type IntrospectionResponse struct {
-	Active    bool   `json:"active"`
-	Scope     string `json:"scope,omitempty"`
-	ClientId  string `json:"client_id,omitempty"`
-	ExpiresAt int64  `json:"exp,omitempty"`
-	IssuedAt  int64  `json:"iat,omitempty"`
-	Subject   string `json:"sub,omitempty"`
-	Audience  string `json:"aud,omitempty"`
-	Issuer    string `json:"iss,omitempty"`
-	TokenType string `json:"token_type,omitempty"`
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientId  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  []string `json:"aud,omitempty"` // resources the token is restricted to (RFC 8707), if any
+	Issuer    string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	// Cnf carries the thumbprint of the DPoP key (RFC 9449, section 6.2) or TLS client certificate (RFC 8705,
+	// section 3.2) a sender-constrained token is bound to. Resource servers must check it against the DPoP proof or
+	// the client certificate presented with the token.
+	Cnf *Confirmation `json:"cnf,omitempty"`
+	// AuthorizationDetails lists the fine-grained permissions granted to the token (RFC 9396, section 9.2)
+	AuthorizationDetails oauth.AuthorizationDetails `json:"authorization_details,omitempty"`
+	// Extensions holds the claims of the token beyond the standard ones, such as act (RFC 8693, section 4.1) or
+	// auth_time, returned as top-level members of the response (RFC 7662, section 2.2)
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON adds the extension claims to the standard members of the response. An extension never replaces a
+// standard member.
+func (r IntrospectionResponse) MarshalJSON() ([]byte, error) {
+	type response IntrospectionResponse
+	encoded, err := json.Marshal(response(r))
+	if err != nil || len(r.Extensions) == 0 {
+		return encoded, err
+	}
+
+	members := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &members); err != nil {
+		return nil, err
+	}
+	for name, value := range r.Extensions {
+		if _, standard := members[name]; !standard {
+			members[name] = value
+		}
+	}
+	return json.Marshal(members)
+}
+
+// Confirmation is the cnf member of an introspection response.
+type Confirmation struct {
+	Jkt     string `json:"jkt,omitempty"`
+	X5tS256 string `json:"x5t#S256,omitempty"`
 }
 
 type introspectionService struct {
-	accessTokenRepository  repositories.AccessTokenRepository
-	refreshTokenRepository repositories.RefreshTokenRepository
-	logger                 *zap.Logger
+	accessTokenRepository   repositories.AccessTokenRepository
+	refreshTokenRepository  repositories.RefreshTokenRepository
+	oauthResourceRepository repositories.OauthResourceRepository
+	revocationList          RevocationList
+	encryption              TokenEncryptionService
+	logger                  *zap.Logger
 }
 
-func NewIntrospectionService(accessTokenRepository repositories.AccessTokenRepository, refreshTokenRepository repositories.RefreshTokenRepository, logger *zap.Logger) IntrospectionService {
+func NewIntrospectionService(accessTokenRepository repositories.AccessTokenRepository, refreshTokenRepository repositories.RefreshTokenRepository, oauthResourceRepository repositories.OauthResourceRepository, revocationList RevocationList, encryption TokenEncryptionService, logger *zap.Logger) IntrospectionService {
 	return &introspectionService{
-		accessTokenRepository:  accessTokenRepository,
-		refreshTokenRepository: refreshTokenRepository,
-		logger:                 logger,
+		accessTokenRepository:   accessTokenRepository,
+		refreshTokenRepository:  refreshTokenRepository,
+		oauthResourceRepository: oauthResourceRepository,
+		revocationList:          revocationList,
+		encryption:              encryption,
+		logger:                  logger,
 	}
 }
 
+// Introspect reports the state of a token to the resource server that asks for it (RFC 7662). Access tokens are
+// only described to a caller in their audience, and refresh tokens to the client they were issued to; any other
+// token, expired or revoked ones included, is reported as inactive.
 func (s *introspectionService) Introspect(command *IntrospectCommand) (*IntrospectionResponse, error) {
-	s.logger.Info("Attempting to introspect token", zap.String("token", command.Token), zap.String("tokenTypeHint", command.TokenTypeHint))
-	s.logger.Debug("Introspection command details", zap.Any("command", command))
+	s.logger.Info("Attempting to introspect token", zap.String("clientId", command.ClientId), zap.String("tokenTypeHint", command.TokenTypeHint))
 
-	// Try to find the token as an access token
-	s.logger.Debug("Attempting to find token as an access token")
-	accessTokenEntity, err := s.accessTokenRepository.FindByAccessToken(command.Token)
-	if err == nil && accessTokenEntity != nil {
-		s.logger.Debug("Access token found", zap.String("accessTokenId", accessTokenEntity.Id))
-		return s.buildIntrospectionResponse(*accessTokenEntity.UserId, *accessTokenEntity.ClientId, "", accessTokenEntity.CreatedAt, time.Until(accessTokenEntity.ExpiresAt), "access_token"), nil
+	// Step 1: Revoked access tokens are answered from the revocation list, without touching the database
+	if s.isRevoked(command.Token) {
+		s.logger.Info("Introspection complete: token is revoked", zap.String("clientId", command.ClientId), zap.Bool("active", false))
+		return inactiveIntrospectionResponse(), nil
 	}
-	// Log error if any, or if token not found as access token
+
+	// Step 2: Find the audiences the caller is entitled to introspect tokens for
+	audiences, err := s.callerAudiences(command.ClientId)
 	if err != nil {
-		s.logger.Debug("Error finding access token", zap.Error(err))
-	} else {
-		s.logger.Debug("Token not found as an access token")
+		s.logger.Error("Error finding the resources of the caller", zap.String("clientId", command.ClientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrServerError, err)
 	}
 
-	// If not an access token, try to find it as a refresh token
-	s.logger.Debug("Attempting to find token as a refresh token")
-	refreshTokenEntity, err := s.refreshTokenRepository.FindByRefreshToken(command.Token)
-	if err == nil && refreshTokenEntity != nil {
-		s.logger.Debug("Refresh token found", zap.String("refreshTokenId", refreshTokenEntity.Id))
-		return s.buildIntrospectionResponse(*refreshTokenEntity.UserId, *refreshTokenEntity.ClientId, "", refreshTokenEntity.CreatedAt, time.Until(refreshTokenEntity.ExpiresAt), "refresh_token"), nil
+	// Step 3: Look the token up, starting with the type the caller hinted at
+	lookups := []func(*IntrospectCommand, []string) (*IntrospectionResponse, bool){s.introspectAccessToken, s.introspectRefreshToken}
+	if command.TokenTypeHint == "refresh_token" {
+		slices.Reverse(lookups)
+	}
+	for _, lookup := range lookups {
+		if response, found := lookup(command, audiences); found {
+			s.logger.Info("Introspection complete", zap.String("clientId", command.ClientId), zap.Bool("active", response.Active))
+			return response, nil
+		}
 	}
 
+	s.logger.Info("Introspection complete: token is inactive or not found", zap.String("clientId", command.ClientId), zap.Bool("active", false))
+	return inactiveIntrospectionResponse(), nil
+}
+
+// introspectAccessToken describes the token if it is an access token. It reports whether the token was found.
+func (s *introspectionService) introspectAccessToken(command *IntrospectCommand, audiences []string) (*IntrospectionResponse, bool) {
+	s.logger.Debug("Attempting to find token as an access token")
+	token, err := s.accessTokenRepository.FindByAccessToken(command.Token)
 	if err != nil {
-		s.logger.Debug("Error finding refresh token", zap.Error(err))
-	} else {
-		s.logger.Debug("Token not found as a refresh token")
+		s.logger.Debug("Token not found as an active access token", zap.Error(err))
+		return nil, false
 	}
+	s.logger.Debug("Access token found", zap.String("accessTokenId", token.Id))
 
-	s.logger.Info("Introspection complete: token is inactive or not found",
-		zap.String("token", command.Token),
-		zap.Bool("active", false),
-	)
-	return &IntrospectionResponse{Active: false}, nil
+	if token.IsExpired() {
+		s.logger.Debug("Access token is expired", zap.String("accessTokenId", token.Id), zap.Time("expiresAt", token.ExpiresAt))
+		return inactiveIntrospectionResponse(), true
+	}
+	audience := tokenAudience(token.Audience)
+	if !audienceMatches(audience, audiences, command.ClientId, utils.StringDeref(token.ClientId)) {
+		s.logger.Warn("Access token is not meant for the caller",
+			zap.String("clientId", command.ClientId), zap.String("accessTokenId", token.Id), zap.Strings("audience", audience))
+		return inactiveIntrospectionResponse(), true
+	}
+
+	response := &IntrospectionResponse{
+		Active:               true,
+		Scope:                utils.JoinStringSlice(utils.ScopesToStringSlice(token.Scopes), " "),
+		ClientId:             utils.StringDeref(token.ClientId),
+		Username:             username(token.User),
+		TokenType:            token.TokenType,
+		ExpiresAt:            token.ExpiresAt.Unix(),
+		IssuedAt:             token.CreatedAt.Unix(),
+		NotBefore:            token.CreatedAt.Unix(),
+		Subject:              tokenSubject(token.UserId, token.ClientId),
+		Audience:             audience,
+		Issuer:               configuration.IssuerURL,
+		Jti:                  token.Jti,
+		AuthorizationDetails: s.authorizationDetails(token.AuthorizationDetails),
+		Extensions:           s.extensionClaims(token.ExtensionClaims),
+	}
+	if token.Jkt != "" || token.CertificateThumbprint != "" {
+		response.Cnf = &Confirmation{Jkt: token.Jkt, X5tS256: token.CertificateThumbprint}
+	}
+	return response, true
 }
 
-func (s *introspectionService) buildIntrospectionResponse(userId, clientId, scope string, createdAt time.Time, expiresIn time.Duration, tokenType string) *IntrospectionResponse {
-	s.logger.Debug("Building introspection response",
-		zap.String("userId", userId),
-		zap.String("clientId", clientId),
-		zap.String("scope", scope),
-		zap.String("tokenType", tokenType),
-	)
-	isActive := true
-	expiresAt := createdAt.Add(expiresIn)
+// introspectRefreshToken describes the token if it is a refresh token. It reports whether the token was found.
+func (s *introspectionService) introspectRefreshToken(command *IntrospectCommand, _ []string) (*IntrospectionResponse, bool) {
+	s.logger.Debug("Attempting to find token as a refresh token")
+	token, err := s.refreshTokenRepository.FindByRefreshToken(command.Token)
+	if err != nil {
+		s.logger.Debug("Token not found as an active refresh token", zap.Error(err))
+		return nil, false
+	}
+	s.logger.Debug("Refresh token found", zap.String("refreshTokenId", token.Id))
 
-	if time.Now().After(expiresAt) {
-		isActive = false
-		s.logger.Debug("Token is expired", zap.String("tokenType", tokenType), zap.Time("expiresAt", expiresAt))
+	if token.IsExpired() || token.IsUsed() {
+		s.logger.Debug("Refresh token is expired or already used", zap.String("refreshTokenId", token.Id), zap.Time("expiresAt", token.ExpiresAt))
+		return inactiveIntrospectionResponse(), true
+	}
+	// Refresh tokens are only ever presented to this server, so only the client holding one may introspect it
+	if utils.StringDeref(token.ClientId) != command.ClientId {
+		s.logger.Warn("Refresh token was issued to another client",
+			zap.String("clientId", command.ClientId), zap.String("refreshTokenId", token.Id))
+		return inactiveIntrospectionResponse(), true
 	}
 
 	response := &IntrospectionResponse{
-		Active:    isActive,
-		Scope:     scope,
-		ClientId:  clientId,
-		ExpiresAt: expiresAt.Unix(),
-		IssuedAt:  createdAt.Unix(),
-		Subject:   userId,
-		TokenType: tokenType,
-	}
-	s.logger.Debug("Introspection response built", zap.Any("response", response))
-	return response
+		Active:               true,
+		Scope:                utils.JoinStringSlice(utils.ScopesToStringSlice(token.Scopes), " "),
+		ClientId:             utils.StringDeref(token.ClientId),
+		Username:             username(token.User),
+		TokenType:            "refresh_token",
+		ExpiresAt:            token.ExpiresAt.Unix(),
+		IssuedAt:             token.CreatedAt.Unix(),
+		NotBefore:            token.CreatedAt.Unix(),
+		Subject:              tokenSubject(token.UserId, token.ClientId),
+		Audience:             token.Resources,
+		Issuer:               configuration.IssuerURL,
+		AuthorizationDetails: s.authorizationDetails(token.AuthorizationDetails),
+	}
+	if token.Jkt != "" {
+		response.Cnf = &Confirmation{Jkt: token.Jkt}
+	}
+	return response, true
+}
+
+// isRevoked reports whether the token is a signed access token whose jti is on the revocation list. Encrypted and
+// opaque tokens carry no readable jti and are left to the database lookup, as are all tokens when the list cannot
+// be reached, since revoked tokens are deleted from the database as well.
+func (s *introspectionService) isRevoked(token string) bool {
+	claims, err := utils.ParseJWT(token)
+	if err != nil {
+		return false
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return false
+	}
+
+	revoked, err := s.revocationList.Contains(jti)
+	if err != nil {
+		s.logger.Warn("Revocation list unavailable, falling back to the database", zap.Error(err))
+		return false
+	}
+	if revoked {
+		s.logger.Debug("Token found on the revocation list", zap.String("jti", jti))
+	}
+	return revoked
+}
+
+// callerAudiences returns the audiences a client may introspect tokens for: its own client ID and the URIs of the
+// resources it serves.
+func (s *introspectionService) callerAudiences(clientId string) ([]string, error) {
+	resources, err := s.oauthResourceRepository.FindByClientId(clientId)
+	if err != nil {
+		return nil, err
+	}
+	audiences := []string{clientId}
+	for _, resource := range resources {
+		audiences = append(audiences, resource.Uri)
+	}
+	return audiences, nil
+}
+
+// IntrospectJWT introspects a token for a resource server that asked for a JWT response (RFC 9701). The response is
+// signed with the algorithm the resource server registered as introspection_signed_response_alg, and encrypted to
+// its key when it registered introspection_encrypted_response_alg.
+func (s *introspectionService) IntrospectJWT(command *IntrospectCommand, client *store.OauthClient) (string, error) {
+	// Step 1: Introspect the token
+	response, err := s.Introspect(command)
+	if err != nil {
+		return "", err
+	}
+
+	// Step 2: Sign the response for the requesting resource server
+	alg := utils.SigningAlgorithm(client.TokenSigning.IntrospectionSignedResponseAlg)
+	signed, err := utils.GenerateIntrospectionJWT(alg, client.ClientId, response)
+	if err != nil {
+		s.logger.Error("Failed to sign introspection response", zap.String("clientId", client.ClientId), zap.Error(err))
+		return "", fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	// Step 3: Encrypt it if the resource server asked for it
+	encrypted, err := s.encryption.EncryptIntrospectionResponse(signed, client)
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Debug("JWT introspection response issued",
+		zap.String("clientId", client.ClientId), zap.String("alg", alg), zap.Bool("active", response.Active))
+	return encrypted, nil
+}
+
+// authorizationDetails decodes the authorization details stored with a token. Details that cannot be read are left
+// out of the response rather than failing the introspection.
+func (s *introspectionService) authorizationDetails(stored string) oauth.AuthorizationDetails {
+	details, err := oauth.ParseAuthorizationDetails(stored)
+	if err != nil {
+		s.logger.Error("Error reading stored authorization details", zap.Error(err))
+		return nil
+	}
+	return details
+}
+
+// extensionClaims decodes the extension claims stored with an access token. Claims that cannot be read are left out
+// of the response rather than failing the introspection.
+func (s *introspectionService) extensionClaims(stored string) map[string]interface{} {
+	if stored == "" {
+		return nil
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(stored), &claims); err != nil {
+		s.logger.Error("Error reading stored extension claims", zap.Error(err))
+		return nil
+	}
+	return claims
+}
+
+// tokenAudience returns the audience of an access token: the stored one, or the default audience of tokens issued
+// without resource indicators.
+func tokenAudience(stored []string) []string {
+	if len(stored) == 0 && configuration.AccessTokenAudience != "" {
+		return []string{configuration.AccessTokenAudience}
+	}
+	return stored
+}
+
+// audienceMatches reports whether a token issued to tokenClientId with the given audience may be described to
+// callerClientId, a caller entitled to audiences. A token without any audience is only described to its own client.
+func audienceMatches(audience, audiences []string, callerClientId, tokenClientId string) bool {
+	if len(audience) == 0 {
+		return callerClientId == tokenClientId
+	}
+	return slices.ContainsFunc(audience, func(value string) bool {
+		return slices.Contains(audiences, value)
+	})
+}
+
+// tokenSubject returns the sub of a token: its resource owner, or its client when issued on the client's own behalf.
+func tokenSubject(userId, clientId *string) string {
+	if userId != nil {
+		return *userId
+	}
+	return utils.StringDeref(clientId)
+}
+
+// username returns the human-readable identifier of a token's resource owner, if any.
+func username(user *store.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.Email != "" {
+		return user.Email
+	}
+	return user.Name
+}
+
+// inactiveIntrospectionResponse is the response for tokens that are unknown, expired, revoked or not meant for the
+// caller, which reveals nothing else about them (RFC 7662, section 2.2).
+func inactiveIntrospectionResponse() *IntrospectionResponse {
+	return &IntrospectionResponse{Active: false}
 }
@@ -8,6 +8,7 @@ var Module = fx.Options(
 	fx.Provide(
 		NewUserConsentService,
 		NewOauthClientService,
+		NewClientAuthenticator,
 		NewTokenService,
 		NewAuthorizationService,
 		NewWellKnownService,
@@ -15,5 +16,13 @@ var Module = fx.Options(
 		NewUserinfoService,
 		NewIntrospectionService,
 		NewRevocationService,
+		NewDeviceAuthorizationService,
+		NewPushedAuthorizationService,
+		NewRequestObjectService,
+		NewDPoPService,
+		NewAuthorizationDetailsService,
+		NewResourceService,
+		NewTokenEncryptionService,
+		NewSigningKeyService,
 	),
 )
@@ -2,15 +2,19 @@ package services
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
 	"github.com/manuelrojas19/go-oauth2-server/oauth"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 	"github.com/manuelrojas19/go-oauth2-server/store"
 	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
 	"github.com/manuelrojas19/go-oauth2-server/utils"
@@ -24,6 +28,18 @@ type RegisterOauthClientCommand struct {
 	TokenEndpointAuthMethod authmethodtype.TokenEndpointAuthMethod
 	RedirectUris            []string
 	Scopes                  string
+
+	Jwks                        string
+	JwksUri                     string
+	TokenExchangeSubjectClients []string
+	TokenExchangeAudiences      []string
+	RequestUris                 []string
+
+	RequirePushedAuthorizationRequests bool
+	RequireSignedRequestObject         bool
+	TLSClientAuth                      authmethodtype.CertificateSubject
+	TokenEncryption                    encryptiontype.TokenEncryption
+	TokenSigning                       signingtype.TokenSigning
 }
 
 type oauthClientService struct {
@@ -54,6 +70,46 @@ func (s *oauthClientService) CreateOauthClient(command *RegisterOauthClientComma
 		return nil, api.ErrServerError
 	}
 
+	// client_secret_jwt clients sign assertions with the secret itself, so keep it in a form that can be decrypted
+	var sealedClientSecret string
+	if command.TokenEndpointAuthMethod == authmethodtype.ClientSecretJWT {
+		sealedClientSecret, err = utils.SealText(clientSecret, configuration.ClientSecretKey)
+		if err != nil {
+			s.logger.Error("Error sealing client secret for client_secret_jwt",
+				zap.String("clientName", command.ClientName),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("%w: client_secret_jwt is not available: %s", api.ErrInvalidRequest, err)
+		}
+	}
+
+	// Tokens can only be encrypted for clients that registered an encryption key; keys behind a jwks_uri are
+	// fetched when a token is issued
+	if command.TokenEncryption.Requested() && command.Jwks != "" {
+		keySet, err := utils.LoadJWKSet(command.Jwks, "")
+		if err == nil {
+			_, err = utils.EncryptionKey(keySet)
+		}
+		if err != nil {
+			s.logger.Warn("Token encryption requested without an encryption key",
+				zap.String("clientName", command.ClientName),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("%w: jwks has no encryption key: %s", api.ErrInvalidRequest, err)
+		}
+	}
+
+	// Tokens can only be signed with the algorithms the server maintains keys for
+	for _, alg := range command.TokenSigning.Algorithms() {
+		if !slices.Contains(configuration.SigningAlgorithms, alg) {
+			s.logger.Warn("Token signing algorithm not enabled",
+				zap.String("clientName", command.ClientName),
+				zap.String("alg", alg),
+			)
+			return nil, fmt.Errorf("%w: signing algorithm %s is not enabled on this server", api.ErrInvalidRequest, alg)
+		}
+	}
+
 	// Validate and fetch scopes
 	var clientScopes []store.Scope
 	scopeNames := splitAndTrim(command.Scopes)
@@ -84,11 +140,20 @@ func (s *oauthClientService) CreateOauthClient(command *RegisterOauthClientComma
 	clientEntity := store.NewOauthClientBuilder().
 		WithClientName(command.ClientName).
 		WithClientSecret(encryptedClientSecret).
+		WithSealedClientSecret(sealedClientSecret).
 		WithResponseTypes(command.ResponseTypes).
 		WithGrantTypes(command.GrantTypes).
 		WithTokenEndpointAuthMethod(command.TokenEndpointAuthMethod).
 		WithRedirectURIs(command.RedirectUris).
 		WithScopes(clientScopes).
+		WithJwks(command.Jwks, command.JwksUri).
+		WithTokenExchangePolicy(command.TokenExchangeSubjectClients, command.TokenExchangeAudiences).
+		WithRequestUris(command.RequestUris).
+		WithRequirePushedAuthorizationRequests(command.RequirePushedAuthorizationRequests).
+		WithRequireSignedRequestObject(command.RequireSignedRequestObject).
+		WithTLSClientAuth(command.TLSClientAuth).
+		WithTokenEncryption(command.TokenEncryption).
+		WithTokenSigning(command.TokenSigning).
 		Build()
 
 	s.logger.Info("Client to be created", zap.Any("client", clientEntity))
@@ -105,6 +170,11 @@ func (s *oauthClientService) CreateOauthClient(command *RegisterOauthClientComma
 		return nil, api.ErrServerError
 	}
 
+	// Clients authenticating with a private key or a certificate are not handed the generated secret
+	if !authmethodtype.IssuesClientSecret(command.TokenEndpointAuthMethod) {
+		clientSecret = ""
+	}
+
 	// Map to Client model
 	clientModel := oauth.NewClientBuilder().
 		WithClientId(savedClient.ClientId).
@@ -117,6 +187,14 @@ func (s *oauthClientService) CreateOauthClient(command *RegisterOauthClientComma
 		WithTokenEndpointAuthMethod(authmethodtype.TokenEndpointAuthMethod(savedClient.TokenEndpointAuthMethod)).
 		WithRedirectUris(savedClient.RedirectURIs).
 		WithScopes(oauthScopesFromStoreScopes(savedClient.Scopes)).
+		WithJwks(savedClient.Jwks, savedClient.JwksUri).
+		WithTokenExchangePolicy(savedClient.TokenExchangeSubjectClients, savedClient.TokenExchangeAudiences).
+		WithRequestUris(savedClient.RequestUris).
+		WithRequirePushedAuthorizationRequests(savedClient.RequirePushedAuthorizationRequests).
+		WithRequireSignedRequestObject(savedClient.RequireSignedRequestObject).
+		WithTLSClientAuth(savedClient.TLSClientAuth).
+		WithTokenEncryption(savedClient.TokenEncryption).
+		WithTokenSigning(savedClient.TokenSigning).
 		Build()
 
 	s.logger.Info("Successfully created OAuth client",
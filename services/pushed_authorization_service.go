@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type PushAuthorizationCommand struct {
+	Client       *store.OauthClient // client the ClientAuthenticator authenticated for the request
+	RedirectUri  string
+	ResponseType responsetype.ResponseType
+	Params       url.Values
+	// AuthorizationDetails is the pushed authorization_details parameter (RFC 9396)
+	AuthorizationDetails string
+}
+
+type pushedAuthorizationService struct {
+	oauthClientService          OauthClientService
+	store                       PushedAuthorizationStore
+	authorizationDetailsService AuthorizationDetailsService
+	logger                      *zap.Logger
+}
+
+// NewPushedAuthorizationService initializes a new PushedAuthorizationService
+func NewPushedAuthorizationService(oauthClientService OauthClientService,
+	store PushedAuthorizationStore,
+	authorizationDetailsService AuthorizationDetailsService,
+	logger *zap.Logger,
+) PushedAuthorizationService {
+	return &pushedAuthorizationService{
+		oauthClientService:          oauthClientService,
+		store:                       store,
+		authorizationDetailsService: authorizationDetailsService,
+		logger:                      logger,
+	}
+}
+
+// Push checks the request of an authenticated client against the client's registration and stores it behind a
+// short-lived request_uri.
+func (p *pushedAuthorizationService) Push(command *PushAuthorizationCommand) (*oauth.PushedAuthorization, error) {
+	client := command.Client
+	clientId := client.ClientId
+	p.logger.Info("Processing pushed authorization request", zap.String("clientId", clientId))
+
+	if !isRegisteredRedirectUri(command.RedirectUri, client) {
+		p.logger.Warn("Redirect URI not registered for client", zap.String("clientId", clientId), zap.String("redirectUri", command.RedirectUri))
+		return nil, fmt.Errorf("%w: redirect_uri is not registered for the client", api.ErrInvalidRequest)
+	}
+
+	if !client.SupportsResponseType(command.ResponseType) {
+		p.logger.Warn("Response type not registered for client", zap.String("clientId", clientId), zap.String("responseType", string(command.ResponseType)))
+		return nil, api.ErrUnauthorizedClient
+	}
+
+	// Invalid authorization details are reported to the client now rather than after the user is redirected
+	if _, err := p.authorizationDetailsService.Validate(command.AuthorizationDetails); err != nil {
+		p.logger.Warn("Invalid authorization details pushed", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
+	}
+
+	requestUri, err := utils.GenerateRequestUri()
+	if err != nil {
+		p.logger.Error("Error generating request_uri", zap.Error(err))
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(configuration.PushedAuthorizationRequestExpireTime)
+	if err := p.store.Save(requestUri, command.Params, expiresAt); err != nil {
+		return nil, err
+	}
+	p.logger.Info("Pushed authorization request stored", zap.String("clientId", clientId), zap.Time("expiresAt", expiresAt))
+
+	return &oauth.PushedAuthorization{
+		RequestUri: requestUri,
+		ClientId:   client.ClientId,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Resolve returns the parameters pushed under requestUri, provided it was issued to clientId and has not expired.
+func (p *pushedAuthorizationService) Resolve(requestUri, clientId string) (url.Values, error) {
+	params, err := p.store.Find(requestUri)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		p.logger.Warn("Unknown or expired request_uri", zap.String("clientId", clientId))
+		return nil, api.ErrInvalidRequestUri
+	}
+	if params.Get("client_id") != clientId {
+		p.logger.Warn("request_uri was issued to another client", zap.String("clientId", clientId), zap.String("issuedTo", params.Get("client_id")))
+		return nil, api.ErrInvalidRequestUri
+	}
+
+	params.Set("request_uri", requestUri)
+	return params, nil
+}
+
+// Complete invalidates requestUri once the authorization request it refers to has been answered, making it
+// one-time use. It stays valid while the user logs in, so the request can be resumed.
+func (p *pushedAuthorizationService) Complete(requestUri string) error {
+	p.logger.Debug("Completing pushed authorization request")
+	return p.store.Delete(requestUri)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type requestObjectService struct {
+	oauthClientService OauthClientService
+	logger             *zap.Logger
+}
+
+// NewRequestObjectService initializes a new RequestObjectService
+func NewRequestObjectService(oauthClientService OauthClientService, logger *zap.Logger) RequestObjectService {
+	return &requestObjectService{
+		oauthClientService: oauthClientService,
+		logger:             logger,
+	}
+}
+
+// Resolve verifies the request object sent by value in the request parameter, or by reference in request_uri, and
+// returns its claims as the authorization parameters. Only the signed claims are used: a client_id sent alongside
+// must match the one in the request object, and any other plain parameter is ignored (RFC 9101, section 6.3). The raw
+// request object is kept under request so it can be carried through login.
+func (r *requestObjectService) Resolve(params url.Values) (url.Values, error) {
+	requestObject := params.Get("request")
+	requestUri := params.Get("request_uri")
+
+	if requestObject != "" && requestUri != "" {
+		r.logger.Warn("Both request and request_uri were sent")
+		return nil, fmt.Errorf("%w: request and request_uri must not both be present", api.ErrInvalidRequestObject)
+	}
+
+	// Step 1: Identify the client, from client_id or from the iss claim when client_id is not repeated outside. A
+	// request object passed by reference is only fetched for a known client, so client_id is required then
+	clientId := params.Get("client_id")
+	if clientId == "" {
+		if requestObject == "" {
+			r.logger.Warn("request_uri was sent without client_id")
+			return nil, fmt.Errorf("%w: client_id is required with request_uri", api.ErrInvalidRequest)
+		}
+		issuer, err := utils.UnverifiedJWTIssuer(requestObject)
+		if err != nil {
+			r.logger.Warn("Request object has no issuer", zap.Error(err))
+			return nil, fmt.Errorf("%w: %s", api.ErrInvalidRequestObject, err)
+		}
+		clientId = issuer
+	}
+
+	client, err := r.oauthClientService.FindOauthClient(clientId)
+	if err != nil {
+		r.logger.Error("Error retrieving client for request object", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: unknown client", api.ErrInvalidRequestObject)
+	}
+	if !client.HasKeys() {
+		r.logger.Warn("Client has no keys to verify request objects", zap.String("clientId", clientId))
+		return nil, fmt.Errorf("%w: client has no registered keys", api.ErrInvalidRequestObject)
+	}
+
+	// Step 2: Dereference request_uri when the request object was passed by reference, if the client registered it
+	if requestObject == "" {
+		if !client.AllowsRequestUri(requestUri) {
+			r.logger.Warn("request_uri is not registered for the client", zap.String("clientId", clientId), zap.String("requestUri", requestUri))
+			return nil, fmt.Errorf("%w: request_uri is not registered for the client", api.ErrInvalidRequestUri)
+		}
+		r.logger.Info("Fetching request object by reference", zap.String("requestUri", requestUri))
+		fetched, err := utils.FetchRequestObject(requestUri)
+		if err != nil {
+			r.logger.Warn("Failed to fetch request object", zap.String("requestUri", requestUri), zap.Error(err))
+			return nil, fmt.Errorf("%w: %s", api.ErrInvalidRequestUri, err)
+		}
+		requestObject = strings.TrimSpace(fetched)
+	}
+
+	// Step 3: Verify the signature and the iss, aud and exp claims
+	keySet, err := utils.LoadJWKSet(client.Jwks, client.JwksUri)
+	if err != nil {
+		r.logger.Error("Error loading keys to verify request object", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: client keys are unavailable", api.ErrInvalidRequestObject)
+	}
+
+	verified, err := utils.VerifyRequestObject(requestObject, keySet)
+	if err != nil {
+		r.logger.Warn("Request object failed verification", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidRequestObject, err)
+	}
+
+	if verified.Issuer() != client.ClientId {
+		r.logger.Warn("Request object was not issued by the client", zap.String("clientId", clientId), zap.String("iss", verified.Issuer()))
+		return nil, fmt.Errorf("%w: iss must be the client_id", api.ErrInvalidRequestObject)
+	}
+	if !slices.Contains(verified.Audience(), configuration.IssuerURL) {
+		r.logger.Warn("Request object is not intended for this server", zap.Strings("aud", verified.Audience()))
+		return nil, fmt.Errorf("%w: aud must identify this server", api.ErrInvalidRequestObject)
+	}
+
+	claims, err := utils.RequestObjectParams(verified)
+	if err != nil {
+		r.logger.Error("Error reading request object claims", zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidRequestObject, err)
+	}
+	if claims.Get("client_id") != client.ClientId {
+		r.logger.Warn("Request object client_id does not match", zap.String("clientId", clientId), zap.String("claimed", claims.Get("client_id")))
+		return nil, fmt.Errorf("%w: client_id does not match", api.ErrInvalidRequestObject)
+	}
+
+	// Step 4: Build the request from the signed claims alone; parameters sent outside the request object, other than
+	// the matching client_id, are ignored (RFC 9101, section 6.3)
+	claims.Set("request", requestObject)
+
+	r.logger.Info("Request object verified", zap.String("clientId", client.ClientId), zap.Int("claims", len(claims)))
+	return claims, nil
+}
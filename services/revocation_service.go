@@ -2,57 +2,167 @@ package services
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/store"
 	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
 	"go.uber.org/zap"
 )
 
 type RevokeCommand struct {
 	Token         string
 	TokenTypeHint string
+	ClientId      string // client that authenticated to make the request
+}
+
+// RevokedToken is an entry of the revocation list: the jti of a revoked access token, denied until it expires.
+type RevokedToken struct {
+	Jti       string `json:"jti"`
+	ExpiresAt int64  `json:"exp"`
 }
 
 type revocationService struct {
 	accessTokenRepository  repositories.AccessTokenRepository
 	refreshTokenRepository repositories.RefreshTokenRepository
+	revocationList         RevocationList
 	logger                 *zap.Logger
 }
 
-func NewRevocationService(accessTokenRepository repositories.AccessTokenRepository, refreshTokenRepository repositories.RefreshTokenRepository, logger *zap.Logger) RevocationService {
+func NewRevocationService(accessTokenRepository repositories.AccessTokenRepository, refreshTokenRepository repositories.RefreshTokenRepository, revocationList RevocationList, logger *zap.Logger) RevocationService {
 	return &revocationService{
 		accessTokenRepository:  accessTokenRepository,
 		refreshTokenRepository: refreshTokenRepository,
+		revocationList:         revocationList,
 		logger:                 logger,
 	}
 }
 
+// Revoke revokes a token issued to the requesting client (RFC 7009). Both token types are tried, the hinted one
+// first. Revoking a refresh token revokes the rest of its family and the access tokens issued in it. Unknown tokens
+// are not an error, as the client cannot do anything about them.
 func (s *revocationService) Revoke(command *RevokeCommand) error {
-	s.logger.Info("Attempting to revoke token", zap.String("token", command.Token), zap.String("tokenTypeHint", command.TokenTypeHint))
-	s.logger.Debug("Revocation command details", zap.Any("command", command))
+	s.logger.Info("Attempting to revoke token", zap.String("clientId", command.ClientId), zap.String("tokenTypeHint", command.TokenTypeHint))
 
-	if command.TokenTypeHint == "access_token" || command.TokenTypeHint == "" {
-		s.logger.Debug("Attempting to revoke as access token", zap.String("token", command.Token))
-		err := s.accessTokenRepository.DeleteByAccessToken(command.Token)
+	revokers := []func(*RevokeCommand) (bool, error){s.revokeAccessToken, s.revokeRefreshToken}
+	if command.TokenTypeHint == "refresh_token" {
+		slices.Reverse(revokers)
+	}
+	for _, revoke := range revokers {
+		found, err := revoke(command)
 		if err != nil {
-			s.logger.Error("Error revoking access token", zap.Error(err), zap.String("token", command.Token))
-			return fmt.Errorf(api.ErrServerError.Error())
+			return err
+		}
+		if found {
+			return nil
 		}
-		s.logger.Info("Access token revoked successfully", zap.String("token", command.Token))
-		return nil
 	}
 
-	if command.TokenTypeHint == "refresh_token" || command.TokenTypeHint == "" {
-		s.logger.Debug("Attempting to revoke as refresh token", zap.String("token", command.Token))
-		err := s.refreshTokenRepository.DeleteByRefreshToken(command.Token)
-		if err != nil {
-			s.logger.Error("Error revoking refresh token", zap.Error(err), zap.String("token", command.Token))
-			return fmt.Errorf(api.ErrServerError.Error())
-		}
-		s.logger.Info("Refresh token revoked successfully", zap.String("token", command.Token))
-		return nil
+	s.logger.Info("Token to revoke not found, nothing to do", zap.String("clientId", command.ClientId))
+	return nil
+}
+
+// revokeAccessToken revokes the token if it is an access token. It reports whether the token was found.
+func (s *revocationService) revokeAccessToken(command *RevokeCommand) (bool, error) {
+	s.logger.Debug("Attempting to revoke as access token")
+	token, err := s.accessTokenRepository.FindByAccessToken(command.Token)
+	if err != nil {
+		s.logger.Debug("Token not found as an active access token", zap.Error(err))
+		return false, nil
+	}
+
+	// Step 1: Clients may only revoke their own tokens
+	if utils.StringDeref(token.ClientId) != command.ClientId {
+		s.logger.Warn("Access token to revoke was issued to another client",
+			zap.String("clientId", command.ClientId), zap.String("accessTokenId", token.Id))
+		return true, fmt.Errorf("%w: token was issued to another client", api.ErrUnauthorizedClient)
 	}
 
-	s.logger.Warn("Unsupported token type hint for revocation", zap.String("tokenTypeHint", command.TokenTypeHint), zap.String("token", command.Token))
-	return api.ErrUnsupportedTokenType
+	// Step 2: Deny it to resource servers validating it locally, then revoke it
+	if err := s.deny([]store.AccessToken{*token}); err != nil {
+		return true, err
+	}
+	if err := s.accessTokenRepository.DeleteByAccessToken(command.Token); err != nil {
+		s.logger.Error("Error revoking access token", zap.String("accessTokenId", token.Id), zap.Error(err))
+		return true, fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	s.logger.Info("Access token revoked successfully", zap.String("clientId", command.ClientId), zap.String("accessTokenId", token.Id))
+	return true, nil
+}
+
+// revokeRefreshToken revokes the token and its family if it is a refresh token. It reports whether the token was
+// found.
+func (s *revocationService) revokeRefreshToken(command *RevokeCommand) (bool, error) {
+	s.logger.Debug("Attempting to revoke as refresh token")
+	token, err := s.refreshTokenRepository.FindByRefreshToken(command.Token)
+	if err != nil {
+		s.logger.Debug("Token not found as an active refresh token", zap.Error(err))
+		return false, nil
+	}
+
+	// Step 1: Clients may only revoke their own tokens
+	if utils.StringDeref(token.ClientId) != command.ClientId {
+		s.logger.Warn("Refresh token to revoke was issued to another client",
+			zap.String("clientId", command.ClientId), zap.String("refreshTokenId", token.Id))
+		return true, fmt.Errorf("%w: token was issued to another client", api.ErrUnauthorizedClient)
+	}
+
+	// Step 2: Revoke it with its family
+	if err := s.RevokeFamily(token); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// RevokeFamily revokes a refresh token, the rest of its family and the access tokens issued in it, denying those
+// access tokens to resource servers validating them locally.
+func (s *revocationService) RevokeFamily(refreshToken *store.RefreshToken) error {
+	// Step 1: Deny the access tokens of the family
+	accessTokens, err := s.refreshTokenRepository.FindFamilyAccessTokens(refreshToken)
+	if err != nil {
+		s.logger.Error("Error finding access tokens of refresh token family", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
+		return fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+	if err := s.deny(accessTokens); err != nil {
+		return err
+	}
+
+	// Step 2: Delete the family
+	if err := s.refreshTokenRepository.DeleteFamily(refreshToken); err != nil {
+		s.logger.Error("Error revoking refresh token family", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
+		return fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	s.logger.Info("Refresh token family revoked successfully",
+		zap.String("clientId", utils.StringDeref(refreshToken.ClientId)), zap.String("refreshTokenId", refreshToken.Id),
+		zap.String("familyId", refreshToken.FamilyId), zap.Int("accessTokens", len(accessTokens)))
+	return nil
+}
+
+// RevokedTokens returns the revocation list: the revoked access tokens that have not expired yet.
+func (s *revocationService) RevokedTokens() ([]RevokedToken, error) {
+	revoked, err := s.revocationList.List()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+	s.logger.Debug("Revocation list read", zap.Int("count", len(revoked)))
+	return revoked, nil
+}
+
+// deny adds the jti of access tokens about to be revoked to the revocation list. Tokens issued before jti values
+// were stored cannot be listed.
+func (s *revocationService) deny(accessTokens []store.AccessToken) error {
+	for _, accessToken := range accessTokens {
+		if accessToken.Jti == "" {
+			s.logger.Debug("Access token has no stored jti, not listing it", zap.String("accessTokenId", accessToken.Id))
+			continue
+		}
+		if err := s.revocationList.Add(accessToken.Jti, accessToken.ExpiresAt); err != nil {
+			s.logger.Error("Error adding access token to revocation list", zap.String("accessTokenId", accessToken.Id), zap.Error(err))
+			return fmt.Errorf("%w: %s", api.ErrServerError, err)
+		}
+	}
+	return nil
 }
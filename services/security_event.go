@@ -0,0 +1,17 @@
+package services
+
+import "time"
+
+// RefreshTokenReuseEvent is emitted when a refresh token that was already rotated is presented again, which means it
+// was stolen. The family of the token has been revoked when the event is emitted.
+const RefreshTokenReuseEvent = "refresh_token_reuse"
+
+// SecurityEvent reports a suspected attack to whoever monitors the server.
+type SecurityEvent struct {
+	Type           string    `json:"type"`
+	ClientId       string    `json:"client_id,omitempty"`
+	UserId         string    `json:"user_id,omitempty"`
+	FamilyId       string    `json:"family_id,omitempty"`
+	RefreshTokenId string    `json:"refresh_token_id,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/lestrrat-go/jwx/jwk"
 	oauth2 "github.com/manuelrojas19/go-oauth2-server/oauth"
 	"github.com/manuelrojas19/go-oauth2-server/store"
@@ -12,10 +15,47 @@ type GrantService interface {
 
 type TokenService interface {
 	GrantAccessToken(command *GrantAccessTokenCommand) (*oauth2.Token, error)
+	GrantImplicitAccessToken(command *ImplicitGrantCommand) (*oauth2.Token, error)
 }
 
 type AuthorizationService interface {
-	Authorize(command *AuthorizeCommand) (*oauth2.AuthCode, error)
+	Authorize(command *AuthorizeCommand) (*oauth2.AuthorizationResponse, error)
+}
+
+type PushedAuthorizationService interface {
+	Push(command *PushAuthorizationCommand) (*oauth2.PushedAuthorization, error)
+	Resolve(requestUri, clientId string) (url.Values, error)
+	Complete(requestUri string) error
+}
+
+type RequestObjectService interface {
+	Resolve(params url.Values) (url.Values, error)
+}
+
+type AuthorizationDetailsService interface {
+	Validate(authorizationDetails string) (oauth2.AuthorizationDetails, error)
+	SupportedTypes() ([]string, error)
+}
+
+type ResourceService interface {
+	RestrictScopes(resources []string, scopes []store.Scope) ([]store.Scope, error)
+}
+
+type TokenEncryptionService interface {
+	EncryptAccessToken(token string, client *store.OauthClient, audience []string) (string, error)
+	EncryptIDToken(token string, client *store.OauthClient) (string, error)
+	EncryptIntrospectionResponse(token string, client *store.OauthClient) (string, error)
+}
+
+type DPoPService interface {
+	ValidateProof(command *DPoPProofCommand) (string, error)
+	ValidateTokenBinding(command *TokenBindingCommand) error
+}
+
+type DeviceAuthorizationService interface {
+	RequestDeviceAuthorization(command *DeviceAuthorizationCommand) (*oauth2.DeviceAuthorization, error)
+	FindPendingByUserCode(userCode string) (*oauth2.DeviceAuthorization, error)
+	Decide(command *DeviceVerificationCommand) error
 }
 
 type OauthClientService interface {
@@ -24,8 +64,20 @@ type OauthClientService interface {
 	PreloadOauthClientScopes(client *store.OauthClient) error
 }
 
+type ClientAuthenticator interface {
+	Authenticate(command *ClientAuthenticationCommand) (*store.OauthClient, error)
+}
+
 type WellKnownService interface {
 	GetJwk() (*jwk.Set, error)
+	RegisterEndpoints(paths []string)
+	GetAuthorizationServerMetadata() *ServerMetadata
+	GetOpenIDConfiguration() *ServerMetadata
+}
+
+type SigningKeyService interface {
+	ListKeys() []SigningKeyStatus
+	EmergencyRotate() ([]SigningKeyStatus, error)
 }
 
 type UserConsentService interface {
@@ -37,6 +89,7 @@ type SessionService interface {
 	CreateSession(userId, email string) (string, error)
 	SessionExists(sessionID string) bool
 	GetUserIdFromSession(sessionID string) (string, error)
+	GetAuthTimeFromSession(sessionID string) (time.Time, error)
 	DeleteSession(sessionID string) error
 }
 
@@ -47,14 +100,37 @@ type ScopeService interface {
 	FindByName(name string) (*oauth2.Scope, error)
 }
 
+type ReplayCache interface {
+	Remember(namespace, id string, expiresAt time.Time) (bool, error)
+}
+
+type RevocationList interface {
+	Add(jti string, expiresAt time.Time) error
+	Contains(jti string) (bool, error)
+	List() ([]RevokedToken, error)
+}
+
+type SecurityEventPublisher interface {
+	Publish(event SecurityEvent) error
+}
+
+type PushedAuthorizationStore interface {
+	Save(requestUri string, params url.Values, expiresAt time.Time) error
+	Find(requestUri string) (url.Values, error)
+	Delete(requestUri string) error
+}
+
 type UserinfoService interface {
 	GetUserinfo(command *GetUserinfoCommand) (*UserinfoResponse, error)
 }
 
 type IntrospectionService interface {
 	Introspect(command *IntrospectCommand) (*IntrospectionResponse, error)
+	IntrospectJWT(command *IntrospectCommand, client *store.OauthClient) (string, error)
 }
 
 type RevocationService interface {
 	Revoke(command *RevokeCommand) error
+	RevokeFamily(refreshToken *store.RefreshToken) error
+	RevokedTokens() ([]RevokedToken, error)
 }
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"go.uber.org/zap"
+)
+
+// SigningKeyStatus describes a key of the signing key ring to administrators, without its private half.
+type SigningKeyStatus struct {
+	Kid         string `json:"kid"`
+	State       string `json:"state"`
+	Alg         string `json:"alg"`
+	CreatedAt   int64  `json:"created_at"`
+	ActivatedAt int64  `json:"activated_at,omitempty"`
+	RetiredAt   int64  `json:"retired_at,omitempty"`
+}
+
+type signingKeyService struct {
+	logger *zap.Logger
+}
+
+// NewSigningKeyService initializes a new SigningKeyService
+func NewSigningKeyService(logger *zap.Logger) SigningKeyService {
+	return &signingKeyService{logger: logger}
+}
+
+// ListKeys returns the state of every key of the ring, revoked ones included.
+func (s *signingKeyService) ListKeys() []SigningKeyStatus {
+	keys := configuration.SigningKeys.Keys()
+	statuses := make([]SigningKeyStatus, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, signingKeyStatus(key))
+	}
+	return statuses
+}
+
+// EmergencyRotate revokes the active and next signing keys of every algorithm after a suspected compromise and
+// activates fresh keys. Tokens signed with the revoked keys stop verifying immediately.
+func (s *signingKeyService) EmergencyRotate() ([]SigningKeyStatus, error) {
+	var revoked []string
+	for _, key := range configuration.SigningKeys.Keys() {
+		if key.State == configuration.KeyStateActive || key.State == configuration.KeyStateNext {
+			revoked = append(revoked, key.Id)
+		}
+	}
+
+	s.logger.Warn("Emergency signing key rotation requested", zap.Strings("revokedKids", revoked))
+	if err := configuration.SigningKeys.EmergencyRotate(time.Now()); err != nil {
+		s.logger.Error("Emergency signing key rotation failed", zap.Strings("revokedKids", revoked), zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	s.logger.Warn("Emergency signing key rotation completed", zap.Strings("revokedKids", revoked))
+	return s.ListKeys(), nil
+}
+
+func signingKeyStatus(key configuration.SigningKey) SigningKeyStatus {
+	status := SigningKeyStatus{
+		Kid:       key.Id,
+		State:     string(key.State),
+		Alg:       key.Algorithm,
+		CreatedAt: key.CreatedAt.Unix(),
+	}
+	if !key.ActivatedAt.IsZero() {
+		status.ActivatedAt = key.ActivatedAt.Unix()
+	}
+	if !key.RetiredAt.IsZero() {
+		status.RetiredAt = key.RetiredAt.Unix()
+	}
+	return status
+}
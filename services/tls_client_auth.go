@@ -0,0 +1,64 @@
+package services
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+)
+
+// authenticateTLSClient authenticates a client registered for mutual TLS with the certificate chain it presented
+// during the TLS handshake (RFC 8705, section 2). With tls_client_auth the certificate must chain to a trusted CA and
+// carry the registered subject; with self_signed_tls_client_auth it must be one of the client's registered keys.
+func authenticateTLSClient(client *store.OauthClient, certificates []*x509.Certificate) error {
+	if len(certificates) == 0 {
+		return errors.New("client certificate is required")
+	}
+	leaf := certificates[0]
+
+	switch authmethodtype.TokenEndpointAuthMethod(client.TokenEndpointAuthMethod) {
+	case authmethodtype.TLSClientAuth:
+		roots, err := configuration.GetClientCAPool()
+		if err != nil {
+			return fmt.Errorf("failed to load client CA certificates: %w", err)
+		}
+		if err := utils.VerifyClientCertificateChain(certificates, roots); err != nil {
+			return err
+		}
+		if !client.TLSClientAuth.MatchesCertificate(leaf) {
+			return errors.New("client certificate subject does not match the registered value")
+		}
+		return nil
+	case authmethodtype.SelfSignedTLSClientAuth:
+		keySet, err := utils.LoadJWKSet(client.Jwks, client.JwksUri)
+		if err != nil {
+			return fmt.Errorf("failed to load client keys: %w", err)
+		}
+		if !utils.CertificateInJWKSet(leaf, keySet) {
+			return errors.New("client certificate is not registered for the client")
+		}
+		return nil
+	default:
+		return fmt.Errorf("client does not use mutual TLS authentication: %s", client.TokenEndpointAuthMethod)
+	}
+}
+
+// validateCertificateBinding checks that an access token bound to the TLS client certificate with the given x5t#S256
+// thumbprint is presented over a connection authenticated with that certificate (RFC 8705, section 3).
+func validateCertificateBinding(thumbprint string, certificate *x509.Certificate) error {
+	if thumbprint == "" {
+		return nil
+	}
+	if certificate == nil {
+		return fmt.Errorf("%w: token is bound to a client certificate", api.ErrInvalidToken)
+	}
+	if utils.CertificateThumbprint(certificate) != thumbprint {
+		return fmt.Errorf("%w: client certificate does not match the token binding", api.ErrInvalidToken)
+	}
+	return nil
+}
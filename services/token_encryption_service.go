@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+)
+
+type tokenEncryptionService struct {
+	oauthResourceRepository repositories.OauthResourceRepository
+	logger                  *zap.Logger
+}
+
+// NewTokenEncryptionService initializes a new TokenEncryptionService
+func NewTokenEncryptionService(oauthResourceRepository repositories.OauthResourceRepository, logger *zap.Logger) TokenEncryptionService {
+	return &tokenEncryptionService{
+		oauthResourceRepository: oauthResourceRepository,
+		logger:                  logger,
+	}
+}
+
+// EncryptAccessToken nests a signed access token in a JWE when its audience or its client asks for it. A resource
+// in the audience that requires encryption takes precedence: the token is encrypted to the resource's key or, when
+// the resource registered none, to the server's own key so that only introspection can read it. Otherwise the
+// token is encrypted to the client's key if the client registered access_token_encrypted_response_alg, and
+// returned as is if not.
+func (s *tokenEncryptionService) EncryptAccessToken(token string, client *store.OauthClient, audience []string) (string, error) {
+	// Step 1: Look for a resource in the audience requiring encryption
+	var resource *store.OauthResource
+	for _, uri := range audience {
+		registered, err := s.oauthResourceRepository.FindByUri(uri)
+		if err != nil || !registered.EncryptsAccessTokens() {
+			continue
+		}
+		if resource != nil {
+			s.logger.Warn("Several requested resources require encrypted access tokens",
+				zap.String("resource", resource.Uri), zap.String("otherResource", registered.Uri))
+			return "", fmt.Errorf("%w: resources %s and %s both require access tokens encrypted to them",
+				api.ErrInvalidTarget, resource.Uri, registered.Uri)
+		}
+		resource = registered
+	}
+
+	// Step 2: Pick the key the token is encrypted to
+	var key jwk.Key
+	var err error
+	switch {
+	case resource != nil && resource.Jwks != "":
+		key, err = s.encryptionKey(resource.Jwks, "")
+	case resource != nil:
+		key, err = utils.ServerEncryptionKey()
+	case client.TokenEncryption.AccessTokenEncryptedResponseAlg != "":
+		key, err = s.encryptionKey(client.Jwks, client.JwksUri)
+	default:
+		return token, nil
+	}
+	if err != nil {
+		s.logger.Error("No key to encrypt the access token to", zap.String("clientId", client.ClientId), zap.Error(err))
+		return "", fmt.Errorf("%w: access token cannot be encrypted: %s", api.ErrServerError, err)
+	}
+
+	// Step 3: Encrypt the token
+	encrypted, err := utils.EncryptJWT(token, key)
+	if err != nil {
+		s.logger.Error("Failed to encrypt access token", zap.String("clientId", client.ClientId), zap.Error(err))
+		return "", fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	s.logger.Debug("Access token encrypted", zap.String("clientId", client.ClientId), zap.String("kid", key.KeyID()))
+	return encrypted, nil
+}
+
+// EncryptIDToken nests a signed ID token in a JWE encrypted to the client's key when the client registered
+// id_token_encrypted_response_alg, and returns it as is otherwise.
+func (s *tokenEncryptionService) EncryptIDToken(token string, client *store.OauthClient) (string, error) {
+	if client.TokenEncryption.IdTokenEncryptedResponseAlg == "" {
+		return token, nil
+	}
+	return s.encryptToClient(token, client, "ID token")
+}
+
+// EncryptIntrospectionResponse nests a signed JWT introspection response in a JWE encrypted to the requesting
+// client's key when the client registered introspection_encrypted_response_alg, and returns it as is otherwise.
+func (s *tokenEncryptionService) EncryptIntrospectionResponse(token string, client *store.OauthClient) (string, error) {
+	if client.TokenEncryption.IntrospectionEncryptedResponseAlg == "" {
+		return token, nil
+	}
+	return s.encryptToClient(token, client, "introspection response")
+}
+
+// encryptToClient nests a signed JWT in a JWE encrypted to the encryption key the client registered.
+func (s *tokenEncryptionService) encryptToClient(token string, client *store.OauthClient, kind string) (string, error) {
+	key, err := s.encryptionKey(client.Jwks, client.JwksUri)
+	if err != nil {
+		s.logger.Error("No key to encrypt the "+kind+" to", zap.String("clientId", client.ClientId), zap.Error(err))
+		return "", fmt.Errorf("%w: %s cannot be encrypted: %s", api.ErrServerError, kind, err)
+	}
+
+	encrypted, err := utils.EncryptJWT(token, key)
+	if err != nil {
+		s.logger.Error("Failed to encrypt "+kind, zap.String("clientId", client.ClientId), zap.Error(err))
+		return "", fmt.Errorf("%w: %s", api.ErrServerError, err)
+	}
+
+	s.logger.Debug("Encrypted "+kind, zap.String("clientId", client.ClientId), zap.String("kid", key.KeyID()))
+	return encrypted, nil
+}
+
+// encryptionKey loads a registered JWK Set and returns the key in it meant for encryption.
+func (s *tokenEncryptionService) encryptionKey(jwks, jwksUri string) (jwk.Key, error) {
+	set, err := utils.LoadJWKSet(jwks, jwksUri)
+	if err != nil {
+		return nil, err
+	}
+	return utils.EncryptionKey(set)
+}
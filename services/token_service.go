@@ -1,35 +1,78 @@
 package services
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
 	"github.com/manuelrojas19/go-oauth2-server/oauth"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/tokentype"
 	"github.com/manuelrojas19/go-oauth2-server/store"
 	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
 	"github.com/manuelrojas19/go-oauth2-server/utils"
 	"go.uber.org/zap"
 )
 
-const AccessTokenDuration = 1 * time.Hour
+const AccessTokenDuration = configuration.AccessTokenLifetime
 const RefreshTokenDuration = 30 * 24 * time.Hour
+const IDTokenDuration = configuration.IDTokenLifetime
+
+// OpenIDScope marks an authorization request as an OpenID Connect authentication request.
+const OpenIDScope = "openid"
 
 type GrantAccessTokenCommand struct {
-	ClientId     string
-	ClientSecret string
+	ClientId string
+	// Client is the client the ClientAuthenticator authenticated for the request. It is only missing for grants that
+	// do not require client authentication: the JWT bearer grant, and refresh requests of public clients.
+	Client       *store.OauthClient
 	RefreshToken string
 	GrantType    granttype.GrantType
 	Code         string
 	RedirectUri  string
 	CodeVerifier string
+	Username     string
+	Password     string
+	Scope        string
+	DeviceCode   string
+
+	SubjectToken       string
+	SubjectTokenType   tokentype.TokenType
+	ActorToken         string
+	ActorTokenType     tokentype.TokenType
+	RequestedTokenType tokentype.TokenType
+	Audience           []string
+
+	Assertion string
+
+	// AuthorizationDetails is the authorization_details parameter (RFC 9396) of the token request
+	AuthorizationDetails string
+	// Resources are the resource indicators (RFC 8707) of the resources the token is requested for
+	Resources []string
+
+	// DPoPJkt is the thumbprint of the key a validated DPoP proof was signed with; issued tokens are bound to it
+	DPoPJkt string
+	// ClientCertificates is the TLS client certificate chain, leaf first, the request was sent with; issued access
+	// tokens are bound to the leaf
+	ClientCertificates []*x509.Certificate
+}
+
+// binding returns what the tokens issued for the command are sender-constrained to.
+func (c *GrantAccessTokenCommand) binding() tokenBinding {
+	return tokenBinding{jkt: c.DPoPJkt, certificates: c.ClientCertificates}
 }
 
-func NewGrantAccessTokenCommand(clientId string, clientSecret string, grantType granttype.GrantType, refreshToken string, code string, redirectUri string, codeVerifier string) *GrantAccessTokenCommand {
+func NewGrantAccessTokenCommand(clientId string, client *store.OauthClient, grantType granttype.GrantType, refreshToken string, code string, redirectUri string, codeVerifier string) *GrantAccessTokenCommand {
 	return &GrantAccessTokenCommand{
 		ClientId:     clientId,
-		ClientSecret: clientSecret,
+		Client:       client,
 		GrantType:    grantType,
 		RefreshToken: refreshToken,
 		Code:         code,
@@ -38,26 +81,64 @@ func NewGrantAccessTokenCommand(clientId string, clientSecret string, grantType
 	}
 }
 
+// ImplicitGrantCommand requests an access token straight from the authorization endpoint, for a resource owner the
+// authorization service has already authenticated.
+type ImplicitGrantCommand struct {
+	Client               *store.OauthClient
+	UserId               string
+	Scopes               []store.Scope
+	AuthorizationDetails oauth.AuthorizationDetails
+	Resources            []string
+	AuthTime             *time.Time
+}
+
 type tokenService struct {
-	accessTokenRepository  repositories.AccessTokenRepository
-	refreshTokenRepository repositories.RefreshTokenRepository
-	authRepository         repositories.AuthorizationRepository
-	client                 OauthClientService
-	logger                 *zap.Logger
+	accessTokenRepository   repositories.AccessTokenRepository
+	refreshTokenRepository  repositories.RefreshTokenRepository
+	authRepository          repositories.AuthorizationRepository
+	userRepository          repositories.UserRepository
+	deviceRepository        repositories.DeviceAuthorizationRepository
+	trustedIssuerRepository repositories.TrustedIssuerRepository
+	replayCache             ReplayCache
+	client                  OauthClientService
+	authorizationDetails    AuthorizationDetailsService
+	resources               ResourceService
+	encryption              TokenEncryptionService
+	revocation              RevocationService
+	securityEvents          SecurityEventPublisher
+	logger                  *zap.Logger
 }
 
 func NewTokenService(
 	accessTokenRepository repositories.AccessTokenRepository,
 	refreshTokenRepository repositories.RefreshTokenRepository,
 	authRepository repositories.AuthorizationRepository,
+	userRepository repositories.UserRepository,
+	deviceRepository repositories.DeviceAuthorizationRepository,
+	trustedIssuerRepository repositories.TrustedIssuerRepository,
+	replayCache ReplayCache,
 	client OauthClientService,
+	authorizationDetails AuthorizationDetailsService,
+	resources ResourceService,
+	encryption TokenEncryptionService,
+	revocation RevocationService,
+	securityEvents SecurityEventPublisher,
 	logger *zap.Logger) TokenService {
 	return &tokenService{
-		accessTokenRepository:  accessTokenRepository,
-		refreshTokenRepository: refreshTokenRepository,
-		authRepository:         authRepository,
-		client:                 client,
-		logger:                 logger,
+		accessTokenRepository:   accessTokenRepository,
+		refreshTokenRepository:  refreshTokenRepository,
+		authRepository:          authRepository,
+		userRepository:          userRepository,
+		deviceRepository:        deviceRepository,
+		trustedIssuerRepository: trustedIssuerRepository,
+		replayCache:             replayCache,
+		client:                  client,
+		authorizationDetails:    authorizationDetails,
+		resources:               resources,
+		encryption:              encryption,
+		revocation:              revocation,
+		securityEvents:          securityEvents,
+		logger:                  logger,
 	}
 }
 
@@ -65,116 +146,121 @@ func (t *tokenService) GrantAccessToken(command *GrantAccessTokenCommand) (*oaut
 	t.logger.Info("Granting access token", zap.String("grantType", string(command.GrantType)), zap.String("clientId", command.ClientId))
 	switch command.GrantType {
 	case granttype.ClientCredentials:
-		return t.handleClientCredentialsFlow(command.ClientId, command.ClientSecret)
+		return t.handleClientCredentialsFlow(command.Client, command.AuthorizationDetails, command.Resources, command.binding())
 	case granttype.RefreshToken:
-		return t.handleRefreshTokenFlow(command.ClientId, command.ClientSecret, command.RefreshToken)
+		return t.handleRefreshTokenFlow(command.Client, command.RefreshToken, command.AuthorizationDetails, command.Resources, command.binding())
 	case granttype.AuthorizationCode:
-		return t.handleAuthorizationCodeFlow(command.ClientId, command.ClientSecret, command.Code, command.RedirectUri, command.CodeVerifier)
+		return t.handleAuthorizationCodeFlow(command.Client, command.Code, command.RedirectUri, command.CodeVerifier, command.AuthorizationDetails, command.Resources, command.binding())
+	case granttype.Password:
+		return t.handlePasswordFlow(command.Client, command.Username, command.Password, command.Scope, command.AuthorizationDetails, command.Resources, command.binding())
+	case granttype.DeviceCode:
+		return t.handleDeviceCodeFlow(command.Client, command.DeviceCode, command.AuthorizationDetails, command.Resources, command.binding())
+	case granttype.TokenExchange:
+		return t.handleTokenExchangeFlow(command)
+	case granttype.JWTBearer:
+		return t.handleJWTBearerFlow(command.Client, command.Assertion, command.Scope, command.AuthorizationDetails, command.Resources, command.binding())
 	default:
 		t.logger.Warn("Unsupported grant type", zap.String("grantType", string(command.GrantType)))
 		return nil, fmt.Errorf("unsupported grant type: %s", command.GrantType)
 	}
 }
 
-// handleClientCredentialsFlow processes the client credentials grant type by validating the client credentials,
-// generating an access token, and issuing a refresh token.
-func (t *tokenService) handleClientCredentialsFlow(clientId, clientSecret string) (*oauth.Token, error) {
+// GrantImplicitAccessToken issues the access token of an implicit or hybrid authorization response.
+// Tokens delivered through the front channel never come with a refresh token.
+func (t *tokenService) GrantImplicitAccessToken(command *ImplicitGrantCommand) (*oauth.Token, error) {
+	t.logger.Info("Granting implicit access token", zap.String("clientId", command.Client.ClientId), zap.String("userId", command.UserId))
+	userId := command.UserId
+	return t.issueToken(&tokenGrant{
+		flow:                 "implicit",
+		client:               command.Client,
+		userId:               &userId,
+		scopes:               command.Scopes,
+		authorizationDetails: command.AuthorizationDetails,
+		audience:             command.Resources,
+		authTime:             command.AuthTime,
+	})
+}
 
+// handleClientCredentialsFlow processes the client credentials grant type by issuing an access token for the
+// client's own scopes, restricted to the requested resources, and any authorization details it requests. The access
+// token is bound to the DPoP key or TLS client certificate the request was sent with.
+func (t *tokenService) handleClientCredentialsFlow(client *store.OauthClient, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	clientId := client.ClientId
 	t.logger.Info("Handling Client Credentials Flow", zap.String("clientId", clientId))
 
-	// Step 1: Retrieve and validate the client
-	client, err := t.client.FindOauthClient(clientId)
-	if err != nil {
-		t.logger.Error("Error retrieving client for Client Credentials Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, api.ErrInvalidClient
-	}
-
-	if err := client.ValidateSecret(clientSecret); err != nil {
-		t.logger.Error("Client authentication failed for Client Credentials Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, api.ErrInvalidClient
+	// Step 1: Only clients that authenticated can act on their own behalf
+	if client.IsPublic() {
+		t.logger.Warn("Public client attempted the Client Credentials Flow", zap.String("clientId", clientId))
+		return nil, api.ErrUnauthorizedClient
 	}
 
 	// Preload scopes for the client
-	err = t.client.PreloadOauthClientScopes(client)
+	err := t.client.PreloadOauthClientScopes(client)
 	if err != nil {
 		t.logger.Error("Error preloading client scopes for Client Credentials Flow", zap.String("clientId", clientId), zap.Error(err))
 		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
 	}
 
-	t.logger.Debug("Client authenticated successfully for Client Credentials Flow", zap.String("clientId", clientId))
-
-	// Step 2: Generate a new access token
-	accessTokenJwt, err := utils.GenerateJWT(&clientId, nil, []byte("secret"), "access")
+	// Step 2: Restrict the scopes to the requested resources and validate the requested authorization details
+	scopes, err := t.resources.RestrictScopes(resources, client.Scopes)
 	if err != nil {
-		t.logger.Error("Error generating JWT for access token in Client Credentials Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, fmt.Errorf("failed to generate access token JWT: %w", err)
+		return nil, err
 	}
-	t.logger.Debug("Access token JWT generated for Client Credentials Flow")
 
-	// Create and save the new access token
-	accessToken := store.NewAccessTokenBuilder().
-		WithClient(client).
-		WithClientId(&clientId).
-		WithToken(accessTokenJwt).
-		WithTokenType("Bearer").
-		WithExpiresAt(time.Now().Add(AccessTokenDuration)).
-		WithScopes(client.Scopes).
-		Build()
-
-	savedAccessToken, err := t.accessTokenRepository.Save(accessToken)
+	details, err := t.authorizationDetails.Validate(authorizationDetails)
 	if err != nil {
-		t.logger.Error("Error saving new access token for Client Credentials Flow", zap.String("clientId", utils.StringDeref(accessToken.ClientId)), zap.Error(err))
-		return nil, fmt.Errorf("failed to save access token: %w", err)
+		return nil, err
 	}
-	t.logger.Info("New access token saved successfully for Client Credentials Flow", zap.String("accessTokenId", savedAccessToken.Id))
 
-	// Step 3: Build and return the token response
-	token := oauth.NewTokenBuilder().
-		WithClientId(savedAccessToken.ClientId).
-		WithAccessToken(savedAccessToken.Token).
-		WithTokenType(savedAccessToken.TokenType).
-		WithAccessTokenCreatedAt(savedAccessToken.CreatedAt).
-		WithAccessTokenExpiresIn(int(AccessTokenDuration.Seconds())).
-		WithAccessTokenExpiresAt(savedAccessToken.ExpiresAt).
-		WithExtension(nil).
-		WithScope(utils.ScopesToStringSlice(savedAccessToken.Scopes)).
-		Build()
-	t.logger.Info("Token response built for Client Credentials Flow", zap.String("clientId", utils.StringDeref(savedAccessToken.ClientId)))
-
-	return token, nil
+	// Step 3: Issue the access token
+	return t.issueToken(&tokenGrant{
+		flow:                 "Client Credentials Flow",
+		client:               client,
+		scopes:               scopes,
+		audience:             resources,
+		authorizationDetails: details,
+		binding:              binding,
+	})
 }
 
 // handleRefreshTokenFlow processes the refresh token grant type by validating the refresh token,
 // authenticating the client (if confidential), generating a new access token, and issuing a new refresh token.
-func (t *tokenService) handleRefreshTokenFlow(clientId, clientSecret, token string) (*oauth.Token, error) {
-	t.logger.Info("Processing refresh token request", zap.String("clientId", clientId), zap.String("refreshToken", token))
+// A refresh token bound to a DPoP key can only be used with a proof signed by that key. Refresh tokens are rotated:
+// each can be used once, and presenting one again revokes its whole family.
+func (t *tokenService) handleRefreshTokenFlow(client *store.OauthClient, token, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	t.logger.Info("Processing refresh token request")
 
-	// Step 1: Retrieve and validate the refresh token
+	// Step 1: Retrieve and validate the refresh token; refresh tokens are opaque, so only stored, unexpired ones are
+	// valid
 	refreshToken, err := t.refreshTokenRepository.FindByRefreshToken(token)
 	if err != nil {
-		t.logger.Error("Error finding refresh token", zap.String("refreshToken", token), zap.Error(err))
-		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+		t.logger.Error("Error finding refresh token", zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidGrant, err)
 	}
 	t.logger.Debug("Refresh token retrieved", zap.String("refreshTokenId", refreshToken.Id))
 
-	if clientId == "" {
-		if refreshToken.ClientId != nil {
-			t.logger.Debug("Client ID not provided; using Client ID from refresh token", zap.String("refreshTokenClientId", *refreshToken.ClientId))
-			clientId = *refreshToken.ClientId
+	// Step 2: Identify the client; public clients may leave it to the refresh token, others have authenticated
+	if client == nil {
+		if refreshToken.ClientId == nil {
+			t.logger.Warn("Refresh token has no client and none was authenticated", zap.String("refreshTokenId", refreshToken.Id))
+			return nil, api.ErrInvalidClient
 		}
-	} else {
-		// If clientId is provided in the request, it must match the one in the refresh token (if present)
-		if refreshToken.ClientId != nil && clientId != *refreshToken.ClientId {
-			return nil, fmt.Errorf("client ID mismatch: provided client ID does not match refresh token's client ID")
+		t.logger.Debug("Client ID not provided; using Client ID from refresh token", zap.String("refreshTokenClientId", *refreshToken.ClientId))
+		client, err = t.client.FindOauthClient(*refreshToken.ClientId)
+		if err != nil {
+			t.logger.Error("Error retrieving client for Refresh Token Flow", zap.String("clientId", *refreshToken.ClientId), zap.Error(err))
+			return nil, api.ErrInvalidClient
 		}
+		if !client.IsPublic() {
+			t.logger.Warn("Confidential client did not authenticate for Refresh Token Flow", zap.String("clientId", client.ClientId))
+			return nil, api.ErrInvalidClient
+		}
+	} else if refreshToken.ClientId != nil && client.ClientId != *refreshToken.ClientId {
+		// The authenticated client must be the one the refresh token was issued to
+		t.logger.Warn("Refresh token was issued to another client", zap.String("clientId", client.ClientId))
+		return nil, fmt.Errorf("%w: refresh token was issued to another client", api.ErrInvalidGrant)
 	}
-
-	// Step 2: Retrieve and validate the client
-	client, err := t.client.FindOauthClient(clientId)
-	if err != nil {
-		t.logger.Error("Error retrieving client for Refresh Token Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, api.ErrInvalidClient
-	}
+	clientId := client.ClientId
 
 	// Preload scopes for the client
 	err = t.client.PreloadOauthClientScopes(client)
@@ -185,118 +271,96 @@ func (t *tokenService) handleRefreshTokenFlow(clientId, clientSecret, token stri
 
 	t.logger.Debug("Client retrieved for Refresh Token Flow", zap.String("clientId", client.ClientId))
 
-	if client.Confidential {
-		if err := t.authenticateClient(clientId, clientSecret, client); err != nil {
-			t.logger.Error("Client authentication failed for Refresh Token Flow", zap.String("clientId", clientId), zap.Error(err))
-			return nil, api.ErrInvalidClient
-		}
-		t.logger.Debug("Confidential client authenticated for Refresh Token Flow", zap.String("clientId", clientId))
+	// A refresh token bound to a DPoP key needs a proof signed by the same key
+	if refreshToken.Jkt != "" && refreshToken.Jkt != binding.jkt {
+		t.logger.Warn("Refresh token is bound to another DPoP key", zap.String("clientId", clientId), zap.String("jkt", binding.jkt))
+		return nil, fmt.Errorf("%w: refresh token is bound to a DPoP key", api.ErrInvalidGrant)
 	}
 
-	// Step 3: Validate the refresh token
-	claims, err := utils.ValidateRefreshToken(token, []byte("secret"))
-	if err != nil {
-		t.logger.Error("Error validating refresh token", zap.String("refreshToken", token), zap.Error(err))
-		return nil, fmt.Errorf("failed to validate refresh token: %w", err)
+	// Step 3: A token that was already rotated is being replayed, by an attacker or by the client after an attacker
+	if refreshToken.IsUsed() {
+		return nil, t.handleRefreshTokenReuse(refreshToken)
 	}
-	t.logger.Debug("Successfully validated refresh token", zap.Any("claims", claims))
 
-	// Step 4: Generate a new access token
-	accessTokenJwt, err := utils.GenerateJWT(refreshToken.ClientId, refreshToken.UserId, []byte("secret"), "access")
+	// Step 4: Narrow the grant to the request before the refresh token is used up, so that a rejected request leaves
+	// it usable
+	// The new access token carries the authorization details of the grant, or the subset the request asks for
+	grantDetails, tokenDetails, err := narrowAuthorizationDetails(refreshToken.AuthorizationDetails, authorizationDetails)
 	if err != nil {
-		t.logger.Error("Error generating JWT for new access token in Refresh Token Flow", zap.String("clientId", utils.StringDeref(refreshToken.ClientId)), zap.Error(err))
-		return nil, fmt.Errorf("failed to generate new access token JWT: %w", err)
+		t.logger.Warn("Requested authorization details exceed the refresh token", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
 	}
-	t.logger.Debug("New access token JWT generated for Refresh Token Flow")
-
-	newAccessToken := store.NewAccessTokenBuilder().
-		WithClientId(refreshToken.ClientId).
-		WithToken(accessTokenJwt).
-		WithTokenType("Bearer").
-		WithExpiresAt(time.Now().Add(AccessTokenDuration)).
-		WithUserId(refreshToken.UserId).
-		WithScopes(client.Scopes).
-		Build()
 
-	savedAccessToken, err := t.accessTokenRepository.Save(newAccessToken)
+	// The new access token is for the resources of the grant, or the subset the request asks for
+	audience, err := narrowResources(refreshToken.Resources, resources)
 	if err != nil {
-		t.logger.Error("Error saving new access token for Refresh Token Flow", zap.String("clientId", utils.StringDeref(refreshToken.ClientId)), zap.Error(err))
-		return nil, fmt.Errorf("failed to save new access token: %w", err)
+		t.logger.Warn("Requested resources exceed the refresh token", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
 	}
-	t.logger.Info("New access token created and saved successfully for Refresh Token Flow", zap.String("accessTokenId", savedAccessToken.Id))
-
-	// Step 5: Invalidate used refresh token
-	err = t.refreshTokenRepository.InvalidateRefreshTokensByAccessTokenId(refreshToken.AccessTokenId)
+	// Its scopes are those of the grant, restricted to the audience
+	grantScopes := refreshToken.Scopes
+	scopes, err := t.resources.RestrictScopes(audience, grantScopes)
 	if err != nil {
-		t.logger.Error("Error invalidating used refresh token", zap.String("accessTokenId", refreshToken.AccessTokenId), zap.Error(err))
 		return nil, err
 	}
-	t.logger.Debug("Old refresh token invalidated successfully")
 
-	// Step 6: Generate a new refresh token
-	refreshTokenJwt, err := utils.GenerateJWT(savedAccessToken.ClientId, savedAccessToken.UserId, []byte("secret"), "refresh")
-	if err != nil {
-		t.logger.Error("Error generating JWT for new refresh token in Refresh Token Flow", zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
-		return nil, fmt.Errorf("failed to generate new refresh token JWT: %w", err)
+	// The new tokens join the family of the refresh token; tokens issued before families were tracked start one
+	familyId := refreshToken.FamilyId
+	if familyId == "" {
+		familyId = uuid.New().String()
 	}
-	t.logger.Debug("New refresh token JWT generated")
-
-	newRefreshToken := store.NewRefreshTokenBuilder().
-		WithAccessToken(savedAccessToken).
-		WithAccessTokenId(savedAccessToken.Id).
-		WithClient(savedAccessToken.Client).
-		WithClientId(savedAccessToken.ClientId).
-		WithToken(refreshTokenJwt).
-		WithTokenType("Bearer").
-		WithExpiresAt(time.Now().Add(RefreshTokenDuration)).
-		WithUserId(savedAccessToken.UserId).
-		Build()
-
-	savedRefreshToken, err := t.refreshTokenRepository.Save(newRefreshToken)
-	if err != nil {
-		t.logger.Error("Error saving new refresh token for Refresh Token Flow", zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
-		return nil, fmt.Errorf("failed to save new refresh token: %w", err)
-	}
-	t.logger.Info("New refresh token saved successfully for Refresh Token Flow", zap.String("refreshTokenId", savedRefreshToken.Id))
-
-	// Step 6: Build and return the token response
-	newToken := oauth.NewTokenBuilder().
-		WithClientId(savedAccessToken.ClientId).
-		WithUserId(savedAccessToken.UserId).
-		WithAccessToken(savedAccessToken.Token).
-		WithTokenType(savedAccessToken.TokenType).
-		WithAccessTokenCreatedAt(savedAccessToken.CreatedAt).
-		WithAccessTokenExpiresIn(int(AccessTokenDuration.Seconds())).
-		WithAccessTokenExpiresAt(savedAccessToken.ExpiresAt).
-		WithRefreshToken(savedRefreshToken.Token).
-		WithRefreshTokenCreatedAt(savedRefreshToken.CreatedAt).
-		WithRefreshTokenExpiresAt(savedRefreshToken.ExpiresAt).
-		WithExtension(nil).
-		WithScope(utils.ScopesToStringSlice(savedAccessToken.Scopes)).
-		Build()
 
-	t.logger.Info("Token response successfully built for Refresh Token Flow", zap.String("clientId", utils.StringDeref(savedAccessToken.ClientId)))
-
-	return newToken, nil
+	// Step 5: Issue the new tokens; the refresh token is used up as its replacement is saved
+	return t.issueToken(&tokenGrant{
+		flow:                      "Refresh Token Flow",
+		client:                    client,
+		userId:                    refreshToken.UserId,
+		scopes:                    scopes,
+		grantScopes:               grantScopes,
+		issueRefreshToken:         true,
+		audience:                  audience,
+		resources:                 refreshToken.Resources,
+		binding:                   binding,
+		authorizationDetails:      tokenDetails,
+		grantAuthorizationDetails: grantDetails,
+		familyId:                  familyId,
+		rotatedRefreshToken:       refreshToken,
+	})
 }
 
-// authenticateClient checks if the client is confidential and validates the provided client secret.
-func (t *tokenService) authenticateClient(clientId, clientSecret string, client *store.OauthClient) error {
-	if clientSecret == "" {
-		t.logger.Warn("Client is confidential but no client secret provided", zap.String("clientId", utils.StringDeref(&clientId)))
-		return fmt.Errorf("client secret is required for confidential clients")
+// handleRefreshTokenReuse revokes the family of a refresh token presented after it was rotated, together with the
+// access tokens issued in it, and reports the reuse as a security event. The request always fails with
+// invalid_grant.
+func (t *tokenService) handleRefreshTokenReuse(refreshToken *store.RefreshToken) error {
+	t.logger.Warn("Rotated refresh token presented again, revoking its family",
+		zap.String("clientId", utils.StringDeref(refreshToken.ClientId)),
+		zap.String("refreshTokenId", refreshToken.Id),
+		zap.String("familyId", refreshToken.FamilyId),
+	)
+
+	if err := t.revocation.RevokeFamily(refreshToken); err != nil {
+		t.logger.Error("Error revoking family of reused refresh token", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
 	}
-	if err := client.ValidateSecret(clientSecret); err != nil {
-		t.logger.Error("Authentication failed for confidential client", zap.String("clientId", utils.StringDeref(&clientId)), zap.Error(err))
-		return fmt.Errorf("authentication failed: %w", err)
+
+	err := t.securityEvents.Publish(SecurityEvent{
+		Type:           RefreshTokenReuseEvent,
+		ClientId:       utils.StringDeref(refreshToken.ClientId),
+		UserId:         utils.StringDeref(refreshToken.UserId),
+		FamilyId:       refreshToken.FamilyId,
+		RefreshTokenId: refreshToken.Id,
+		OccurredAt:     time.Now(),
+	})
+	if err != nil {
+		t.logger.Error("Error publishing refresh token reuse event", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
 	}
-	t.logger.Debug("Client authenticated successfully", zap.String("clientId", utils.StringDeref(&clientId)))
-	return nil
+
+	return fmt.Errorf("%w: refresh token has already been used", api.ErrInvalidGrant)
 }
 
 // handleAuthorizationCodeFlow processes the authorization code grant type by validating the authorization code,
 // generating an access token, and issuing a refresh token.
-func (t *tokenService) handleAuthorizationCodeFlow(clientId, clientSecret, code, redirectUri, codeVerifier string) (*oauth.Token, error) {
+func (t *tokenService) handleAuthorizationCodeFlow(client *store.OauthClient, code, redirectUri, codeVerifier, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	clientId := client.ClientId
 	t.logger.Info("Handling Authorization Code Flow", zap.String("clientId", clientId), zap.String("code", code))
 	// Step 1: Retrieve and validate the authorization code
 	authCode, err := t.authRepository.FindByCode(code)
@@ -339,27 +403,30 @@ func (t *tokenService) handleAuthorizationCodeFlow(clientId, clientSecret, code,
 		return nil, fmt.Errorf("code challenge method required for PKCE")
 	}
 
-	// Step 2: Retrieve and validate the client
-	client, err := t.client.FindOauthClient(clientId)
-	if err != nil {
-		t.logger.Error("Error retrieving client for Authorization Code Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, api.ErrInvalidClient
-	}
-
-	// Preload scopes for the client
+	// Step 2: Preload scopes for the authenticated client
 	err = t.client.PreloadOauthClientScopes(client)
 	if err != nil {
 		t.logger.Error("Error preloading client scopes for Authorization Code Flow", zap.String("clientId", clientId), zap.Error(err))
 		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
 	}
 
-	t.logger.Debug("Client retrieved for Authorization Code Flow", zap.String("clientId", clientId))
+	// The access token carries the authorization details granted to the code, or the subset the request asks for
+	grantDetails, tokenDetails, err := narrowAuthorizationDetails(authCode.AuthorizationDetails, authorizationDetails)
+	if err != nil {
+		t.logger.Warn("Requested authorization details exceed the authorization code", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
+	}
 
-	if err := t.authenticateClient(clientId, clientSecret, client); err != nil {
-		t.logger.Error("Client authentication failed for Authorization Code Flow", zap.String("clientId", clientId), zap.Error(err))
-		return nil, api.ErrInvalidClient
+	// The access token is for the resources granted to the code, or the subset the request asks for
+	audience, err := narrowResources(authCode.Resources, resources)
+	if err != nil {
+		t.logger.Warn("Requested resources exceed the authorization code", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
+	}
+	scopes, err := t.resources.RestrictScopes(audience, authCode.Scopes)
+	if err != nil {
+		return nil, err
 	}
-	t.logger.Debug("Confidential client authenticated for Authorization Code Flow", zap.String("clientId", clientId))
 
 	// Step 2.5: Invalidate the authorization code to prevent replay attacks
 	t.logger.Debug("Invalidating authorization code to prevent replay attacks", zap.String("code", authCode.Code))
@@ -370,58 +437,672 @@ func (t *tokenService) handleAuthorizationCodeFlow(clientId, clientSecret, code,
 	}
 	t.logger.Info("Authorization code invalidated successfully", zap.String("code", authCode.Code))
 
-	// Step 3: Generate a new access token
-	accessTokenJwt, err := utils.GenerateJWT(authCode.ClientId, authCode.UserId, []byte("secret"), "access")
+	// Step 3: Issue the access and refresh tokens for the scopes granted to the code, with an ID token when the
+	// authorization request was an OpenID Connect one
+	grant := &tokenGrant{
+		flow:                      "authorization_code",
+		client:                    client,
+		userId:                    authCode.UserId,
+		scopes:                    scopes,
+		grantScopes:               authCode.Scopes,
+		code:                      code,
+		issueRefreshToken:         true,
+		audience:                  audience,
+		resources:                 authCode.Resources,
+		authTime:                  authCode.AuthTime,
+		binding:                   binding,
+		authorizationDetails:      tokenDetails,
+		grantAuthorizationDetails: grantDetails,
+	}
+	if hasScope(authCode.Scopes, OpenIDScope) && authCode.UserId != nil {
+		grant.idToken = &idTokenGrant{
+			alg:      client.TokenSigning.IdTokenSignedResponseAlg,
+			clientId: client.ClientId,
+			userId:   *authCode.UserId,
+			nonce:    authCode.Nonce,
+			authTime: authCode.AuthTime,
+			code:     code,
+		}
+		t.logger.Debug("OpenID scope granted, an ID token will be issued", zap.String("clientId", clientId))
+	}
+
+	token, err := t.issueToken(grant)
 	if err != nil {
-		t.logger.Error("Error generating JWT for access token in Authorization Code Flow", zap.String("clientId", utils.StringDeref(authCode.ClientId)), zap.Error(err))
-		return nil, fmt.Errorf("failed to generate access token JWT: %w", err)
+		return nil, err
 	}
-	t.logger.Debug("Access token JWT generated for Authorization Code Flow")
+	t.logger.Info("Token response successfully built for Authorization Code Flow", zap.String("clientId", clientId))
 
-	newAccessToken := store.NewAccessTokenBuilder().
-		WithClientId(authCode.ClientId).
-		WithToken(accessTokenJwt).
-		WithCode(code).
-		WithTokenType("Bearer").
-		WithExpiresAt(time.Now().Add(AccessTokenDuration)).
-		WithUserId(authCode.UserId).
-		Build()
+	return token, nil
+}
+
+// handlePasswordFlow processes the resource owner password credentials grant type by authenticating the client,
+// validating the resource owner's local credentials, and issuing an access token for the requested scopes.
+func (t *tokenService) handlePasswordFlow(client *store.OauthClient, username, password, scope, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	clientId := client.ClientId
+	t.logger.Info("Handling Password Flow", zap.String("clientId", clientId), zap.String("username", username))
+
+	// Step 1: Check the authenticated client may use the grant
+	if !slices.Contains(client.GrantTypes, string(granttype.Password)) {
+		t.logger.Warn("Client is not allowed to use the password grant", zap.String("clientId", clientId))
+		return nil, api.ErrUnauthorizedClient
+	}
 
-	savedAccessToken, err := t.accessTokenRepository.Save(newAccessToken)
+	err := t.client.PreloadOauthClientScopes(client)
 	if err != nil {
-		t.logger.Error("Error saving new access token for Authorization Code Flow", zap.String("clientId", utils.StringDeref(authCode.ClientId)), zap.Error(err))
-		return nil, fmt.Errorf("failed to save new access token: %w", err)
+		t.logger.Error("Error preloading client scopes for Password Flow", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
 	}
-	t.logger.Info("New access token created and saved successfully for Authorization Code Flow", zap.String("accessTokenId", savedAccessToken.Id))
 
-	// Step 4: Generate a new refresh token
-	refreshTokenJwt, err := utils.GenerateJWT(savedAccessToken.ClientId, savedAccessToken.UserId, []byte("secret"), "refresh")
+	scopes, err := resolveRequestedScopes(client.Scopes, scope)
 	if err != nil {
-		t.logger.Error("Error generating JWT for refresh token in Authorization Code Flow", zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
-		return nil, fmt.Errorf("failed to generate refresh token JWT: %w", err)
+		t.logger.Warn("Requested scope is not allowed for client", zap.String("clientId", clientId), zap.String("scope", scope), zap.Error(err))
+		return nil, err
 	}
-	t.logger.Debug("New refresh token JWT generated for Authorization Code Flow")
 
-	newRefreshToken := store.NewRefreshTokenBuilder().
-		WithAccessToken(savedAccessToken).
-		WithAccessTokenId(savedAccessToken.Id).
-		WithClient(savedAccessToken.Client).
-		WithClientId(savedAccessToken.ClientId).
-		WithToken(refreshTokenJwt).
-		WithTokenType("Bearer").
-		WithExpiresAt(time.Now().Add(RefreshTokenDuration)).
-		WithUserId(savedAccessToken.UserId).
+	scopes, err = t.resources.RestrictScopes(resources, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Validate the resource owner credentials
+	user, err := t.userRepository.FindByEmail(username)
+	if err != nil {
+		// Spend the time a password check takes, so that the response time does not reveal which usernames exist
+		store.ValidateUnknownUserPassword(password)
+		t.logger.Warn("Resource owner not found for Password Flow", zap.String("username", username), zap.Error(err))
+		return nil, fmt.Errorf("%w: invalid resource owner credentials", api.ErrInvalidGrant)
+	}
+
+	if err := user.ValidatePassword(password); err != nil {
+		t.logger.Warn("Resource owner authentication failed for Password Flow", zap.String("userId", user.Id), zap.Error(err))
+		return nil, fmt.Errorf("%w: invalid resource owner credentials", api.ErrInvalidGrant)
+	}
+	t.logger.Debug("Resource owner authenticated for Password Flow", zap.String("userId", user.Id))
+
+	// Step 3: Issue the tokens
+	details, err := t.authorizationDetails.Validate(authorizationDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.issueToken(&tokenGrant{
+		flow:                 "Password Flow",
+		client:               client,
+		userId:               &user.Id,
+		scopes:               scopes,
+		audience:             resources,
+		resources:            resources,
+		authorizationDetails: details,
+		issueRefreshToken:    slices.Contains(client.GrantTypes, string(granttype.RefreshToken)),
+		binding:              binding,
+	})
+}
+
+// handleDeviceCodeFlow processes the device code grant type (RFC 8628, section 3.4) by checking the state of the
+// device authorization being polled, and issues tokens once the end user has approved it.
+func (t *tokenService) handleDeviceCodeFlow(client *store.OauthClient, deviceCode, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	clientId := client.ClientId
+	t.logger.Info("Handling Device Code Flow", zap.String("clientId", clientId))
+
+	// Step 1: Check the authenticated client may use the grant
+	if !slices.Contains(client.GrantTypes, string(granttype.DeviceCode)) {
+		t.logger.Warn("Client is not allowed to use the device code grant", zap.String("clientId", clientId))
+		return nil, api.ErrUnauthorizedClient
+	}
+
+	// Step 2: Retrieve the device authorization being polled
+	deviceAuthorization, err := t.deviceRepository.FindByDeviceCode(deviceCode)
+	if err != nil {
+		t.logger.Warn("Device code not found", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: unknown device code", api.ErrInvalidGrant)
+	}
+
+	if utils.StringDeref(deviceAuthorization.ClientId) != clientId {
+		t.logger.Warn("Client ID mismatch for device code", zap.String("expectedClientId", utils.StringDeref(deviceAuthorization.ClientId)), zap.String("receivedClientId", clientId))
+		return nil, fmt.Errorf("%w: device code was issued to another client", api.ErrInvalidGrant)
+	}
+
+	if deviceAuthorization.IsExpired() {
+		t.logger.Warn("Device code has expired", zap.String("clientId", clientId), zap.Time("expiresAt", deviceAuthorization.ExpiresAt))
+		if _, err := t.deviceRepository.Delete(deviceCode); err != nil {
+			t.logger.Error("Error deleting expired device code", zap.Error(err))
+		}
+		return nil, api.ErrExpiredToken
+	}
+
+	// Step 3: Enforce the polling interval, backing the device off when it polls too fast
+	now := time.Now()
+	lastPolledAt := deviceAuthorization.LastPolledAt
+	deviceAuthorization.LastPolledAt = &now
+	if lastPolledAt != nil && now.Sub(*lastPolledAt) < time.Duration(deviceAuthorization.Interval)*time.Second {
+		deviceAuthorization.Interval += int(configuration.DeviceCodeSlowDownIncrement.Seconds())
+		t.logger.Warn("Device is polling too fast", zap.String("clientId", clientId), zap.Int("interval", deviceAuthorization.Interval))
+		if err := t.deviceRepository.Update(deviceAuthorization); err != nil {
+			return nil, err
+		}
+		return nil, api.ErrSlowDown
+	}
+
+	switch deviceAuthorization.Status {
+	case store.DeviceAuthorizationPending:
+		t.logger.Debug("Device authorization is still pending", zap.String("clientId", clientId))
+		if err := t.deviceRepository.Update(deviceAuthorization); err != nil {
+			return nil, err
+		}
+		return nil, api.ErrAuthorizationPending
+	case store.DeviceAuthorizationDenied:
+		t.logger.Info("Device authorization was denied by the end user", zap.String("clientId", clientId))
+		if _, err := t.deviceRepository.Delete(deviceCode); err != nil {
+			return nil, err
+		}
+		return nil, api.ErrAccessDenied
+	case store.DeviceAuthorizationApproved:
+		t.logger.Info("Device authorization was approved by the end user", zap.String("clientId", clientId), zap.String("userId", utils.StringDeref(deviceAuthorization.UserId)))
+	default:
+		t.logger.Error("Unknown device authorization status", zap.String("status", deviceAuthorization.Status))
+		return nil, fmt.Errorf("unknown device authorization status: %s", deviceAuthorization.Status)
+	}
+
+	// Step 4: Redeem the device code so it cannot be used twice; of two concurrent polls, only the one that deletes it
+	// is issued tokens
+	redeemed, err := t.deviceRepository.Delete(deviceCode)
+	if err != nil {
+		t.logger.Error("Error deleting redeemed device code", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to invalidate device code: %w", err)
+	}
+	if !redeemed {
+		t.logger.Warn("Device code was redeemed by a concurrent request", zap.String("clientId", clientId))
+		return nil, fmt.Errorf("%w: device code has already been redeemed", api.ErrInvalidGrant)
+	}
+
+	err = t.client.PreloadOauthClientScopes(client)
+	if err != nil {
+		t.logger.Error("Error preloading client scopes for Device Code Flow", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
+	}
+
+	scopes, err := resolveRequestedScopes(client.Scopes, deviceAuthorization.Scope)
+	if err != nil {
+		t.logger.Warn("Requested scope is no longer allowed for client", zap.String("clientId", clientId), zap.Error(err))
+		return nil, err
+	}
+
+	scopes, err = t.resources.RestrictScopes(resources, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Issue the tokens
+	// Device authorizations carry no authorization details the request could narrow
+	if _, _, err := narrowAuthorizationDetails("", authorizationDetails); err != nil {
+		return nil, err
+	}
+
+	return t.issueToken(&tokenGrant{
+		flow:              "Device Code Flow",
+		client:            client,
+		userId:            deviceAuthorization.UserId,
+		scopes:            scopes,
+		issueRefreshToken: slices.Contains(client.GrantTypes, string(granttype.RefreshToken)),
+		audience:          resources,
+		resources:         resources,
+		binding:           binding,
+	})
+}
+
+// handleTokenExchangeFlow processes the token exchange grant type (RFC 8693) by validating the subject token and,
+// for delegation, the actor token, then issuing a new access token narrowed to the requested audience and scope.
+func (t *tokenService) handleTokenExchangeFlow(command *GrantAccessTokenCommand) (*oauth.Token, error) {
+	client := command.Client
+	clientId := client.ClientId
+	t.logger.Info("Handling Token Exchange Flow", zap.String("clientId", clientId), zap.Strings("audience", command.Audience))
+
+	// Step 1: Check the authenticated client may use the grant
+	if !slices.Contains(client.GrantTypes, string(granttype.TokenExchange)) {
+		t.logger.Warn("Client is not allowed to use the token exchange grant", zap.String("clientId", clientId))
+		return nil, api.ErrUnauthorizedClient
+	}
+
+	// Step 2: Validate the subject token and the client's policy over it
+	subject, err := t.validateExchangeToken(command.SubjectToken, command.SubjectTokenType)
+	if err != nil {
+		t.logger.Warn("Invalid subject token for Token Exchange Flow", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("%w: invalid subject_token: %v", api.ErrInvalidRequest, err)
+	}
+
+	if !client.CanExchangeSubjectOf(subject.clientId) {
+		t.logger.Warn("Client may not exchange tokens of the subject's client", zap.String("clientId", clientId), zap.String("subjectClientId", subject.clientId))
+		return nil, fmt.Errorf("%w: client may not exchange tokens issued to %s", api.ErrUnauthorizedClient, subject.clientId)
+	}
+
+	claims := map[string]interface{}{}
+
+	// Step 3: For delegation, validate the actor and record it, on top of any earlier delegation chain
+	if command.ActorToken != "" {
+		actor, err := t.validateExchangeToken(command.ActorToken, command.ActorTokenType)
+		if err != nil {
+			t.logger.Warn("Invalid actor token for Token Exchange Flow", zap.String("clientId", clientId), zap.Error(err))
+			return nil, fmt.Errorf("%w: invalid actor_token: %v", api.ErrInvalidRequest, err)
+		}
+
+		act := map[string]interface{}{
+			"sub":       actor.subject(),
+			"client_id": actor.clientId,
+		}
+		if subject.act != nil {
+			act["act"] = subject.act
+		}
+		claims["act"] = act
+		t.logger.Debug("Delegation requested for Token Exchange Flow", zap.Any("act", act))
+	}
+
+	// Step 4: Check the requested audiences against the client's policy
+	for _, audience := range command.Audience {
+		if !client.CanExchangeForAudience(audience) {
+			t.logger.Warn("Audience is not allowed for client", zap.String("clientId", clientId), zap.String("audience", audience))
+			return nil, fmt.Errorf("%w: audience %s is not allowed for the client", api.ErrInvalidTarget, audience)
+		}
+	}
+
+	// Step 5: Narrow the scope to what both the client and the subject token allow
+	err = t.client.PreloadOauthClientScopes(client)
+	if err != nil {
+		t.logger.Error("Error preloading client scopes for Token Exchange Flow", zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
+	}
+
+	scopes, err := resolveExchangeScopes(client.Scopes, subject.scopes, command.Scope)
+	if err != nil {
+		t.logger.Warn("Requested scope exceeds the subject token or client scopes", zap.String("clientId", clientId), zap.String("scope", command.Scope), zap.Error(err))
+		return nil, err
+	}
+
+	// Resource indicators narrow the scope further and join the requested audiences
+	scopes, err = t.resources.RestrictScopes(command.Resources, scopes)
+	if err != nil {
+		return nil, err
+	}
+	audience := append(slices.Clone(command.Audience), command.Resources...)
+
+	issuedTokenType := command.RequestedTokenType
+	if issuedTokenType == "" {
+		issuedTokenType = tokentype.AccessToken
+	}
+
+	// Step 6: Issue the token; exchanged tokens are short-lived and never come with a refresh token
+	// Exchanged tokens are narrowed by audience and scope; authorization details cannot be added to them
+	if _, _, err := narrowAuthorizationDetails("", command.AuthorizationDetails); err != nil {
+		return nil, err
+	}
+
+	return t.issueToken(&tokenGrant{
+		flow:            "Token Exchange Flow",
+		client:          client,
+		userId:          subject.userId,
+		scopes:          scopes,
+		audience:        audience,
+		claims:          claims,
+		issuedTokenType: string(issuedTokenType),
+		binding:         command.binding(),
+	})
+}
+
+// handleJWTBearerFlow processes the JWT bearer grant type (RFC 7523, section 2.1). The assertion is signed either by
+// a client with registered keys (iss and sub are its client_id) or by a trusted issuer asserting one of our users.
+func (t *tokenService) handleJWTBearerFlow(authenticated *store.OauthClient, assertion, scope, authorizationDetails string, resources []string, binding tokenBinding) (*oauth.Token, error) {
+	t.logger.Info("Handling JWT Bearer Flow")
+
+	// Step 1: Find who signed the assertion and the keys to verify it with
+	issuer, err := utils.UnverifiedJWTIssuer(assertion)
+	if err != nil {
+		t.logger.Warn("Malformed JWT bearer assertion", zap.Error(err))
+		return nil, fmt.Errorf("%w: malformed assertion", api.ErrInvalidGrant)
+	}
+
+	var client *store.OauthClient
+	var trustedIssuer *store.TrustedIssuer
+	var jwks, jwksUri string
+
+	if issuerClient, err := t.client.FindOauthClient(issuer); err == nil && issuerClient.HasKeys() {
+		client = issuerClient
+		jwks, jwksUri = client.Jwks, client.JwksUri
+	} else {
+		trustedIssuer, err = t.trustedIssuerRepository.FindByIssuer(issuer)
+		if err != nil {
+			t.logger.Warn("JWT bearer assertion from unknown issuer", zap.String("issuer", issuer), zap.Error(err))
+			return nil, fmt.Errorf("%w: unknown assertion issuer", api.ErrInvalidGrant)
+		}
+		client, err = t.client.FindOauthClient(utils.StringDeref(trustedIssuer.ClientId))
+		if err != nil {
+			t.logger.Error("Error retrieving client of trusted issuer", zap.String("issuer", issuer), zap.Error(err))
+			return nil, api.ErrInvalidClient
+		}
+		jwks, jwksUri = trustedIssuer.Jwks, trustedIssuer.JwksUri
+	}
+
+	if authenticated != nil && authenticated.ClientId != client.ClientId {
+		t.logger.Warn("Client ID does not match the assertion", zap.String("clientId", authenticated.ClientId), zap.String("assertionClientId", client.ClientId))
+		return nil, api.ErrInvalidClient
+	}
+
+	if !slices.Contains(client.GrantTypes, string(granttype.JWTBearer)) {
+		t.logger.Warn("Client is not allowed to use the JWT bearer grant", zap.String("clientId", client.ClientId))
+		return nil, api.ErrUnauthorizedClient
+	}
+
+	// Step 2: Verify the signature and the iss, sub, aud and exp claims
+	keySet, err := utils.LoadJWKSet(jwks, jwksUri)
+	if err != nil {
+		t.logger.Error("Error loading keys to verify JWT bearer assertion", zap.String("issuer", issuer), zap.Error(err))
+		return nil, fmt.Errorf("%w: assertion keys are unavailable", api.ErrInvalidGrant)
+	}
+
+	verified, err := utils.VerifyJWTWithKeySet(assertion, keySet)
+	if err != nil {
+		t.logger.Warn("JWT bearer assertion failed verification", zap.String("issuer", issuer), zap.Error(err))
+		return nil, fmt.Errorf("%w: invalid assertion", api.ErrInvalidGrant)
+	}
+
+	if !slices.ContainsFunc(verified.Audience(), isTokenEndpointAudience) {
+		t.logger.Warn("JWT bearer assertion is not intended for this server", zap.Strings("aud", verified.Audience()))
+		return nil, fmt.Errorf("%w: assertion audience does not identify this server", api.ErrInvalidGrant)
+	}
+
+	if verified.Expiration().After(time.Now().Add(configuration.AssertionMaxLifetime)) {
+		t.logger.Warn("JWT bearer assertion lifetime is too long", zap.Time("exp", verified.Expiration()))
+		return nil, fmt.Errorf("%w: assertion expires too far in the future", api.ErrInvalidGrant)
+	}
+
+	var userId *string
+	if trustedIssuer == nil {
+		if verified.Subject() != client.ClientId {
+			t.logger.Warn("JWT bearer assertion subject is not the client", zap.String("sub", verified.Subject()))
+			return nil, fmt.Errorf("%w: assertion subject must be the client_id", api.ErrInvalidGrant)
+		}
+	} else {
+		user, err := t.userRepository.FindById(verified.Subject())
+		if err != nil {
+			user, err = t.userRepository.FindByEmail(verified.Subject())
+		}
+		if err != nil {
+			t.logger.Warn("JWT bearer assertion subject is unknown", zap.String("issuer", issuer), zap.Error(err))
+			return nil, fmt.Errorf("%w: unknown assertion subject", api.ErrInvalidGrant)
+		}
+		userId = &user.Id
+	}
+
+	// Step 3: Reject replayed assertions
+	fresh, err := t.replayCache.Remember("jwt-bearer:"+issuer, verified.JwtID(), verified.Expiration())
+	if err != nil {
+		t.logger.Error("Error checking JWT bearer assertion for replay", zap.Error(err))
+		return nil, api.ErrServerError
+	}
+	if !fresh {
+		t.logger.Warn("JWT bearer assertion replayed", zap.String("issuer", issuer), zap.String("jti", verified.JwtID()))
+		return nil, fmt.Errorf("%w: assertion has already been used", api.ErrInvalidGrant)
+	}
+
+	// Step 4: Resolve the scope and issue the token; assertions are re-presented instead of refresh tokens
+	err = t.client.PreloadOauthClientScopes(client)
+	if err != nil {
+		t.logger.Error("Error preloading client scopes for JWT Bearer Flow", zap.String("clientId", client.ClientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to preload client scopes: %w", err)
+	}
+
+	scopes, err := resolveRequestedScopes(client.Scopes, scope)
+	if err != nil {
+		t.logger.Warn("Requested scope is not allowed for client", zap.String("clientId", client.ClientId), zap.String("scope", scope), zap.Error(err))
+		return nil, err
+	}
+
+	scopes, err = t.resources.RestrictScopes(resources, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := t.authorizationDetails.Validate(authorizationDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.issueToken(&tokenGrant{
+		flow:                 "JWT Bearer Flow",
+		client:               client,
+		userId:               userId,
+		scopes:               scopes,
+		audience:             resources,
+		authorizationDetails: details,
+		binding:              binding,
+	})
+}
+
+// isTokenEndpointAudience reports whether an assertion audience identifies this authorization server.
+func isTokenEndpointAudience(audience string) bool {
+	return audience == configuration.IssuerURL || audience == configuration.IssuerURL+"/oauth/token"
+}
+
+// exchangeToken describes who a subject or actor token presented for token exchange represents.
+type exchangeToken struct {
+	clientId string
+	userId   *string
+	scopes   []string               // scopes the token grants
+	act      map[string]interface{} // delegation chain already recorded in the token, if any
+}
+
+// subject returns the identifier of the party the token represents: the user if any, the client otherwise.
+func (e *exchangeToken) subject() string {
+	if e.userId != nil {
+		return *e.userId
+	}
+	return e.clientId
+}
+
+// validateExchangeToken checks that a subject or actor token was issued by this server and is still active.
+func (t *tokenService) validateExchangeToken(token string, tokenType tokentype.TokenType) (*exchangeToken, error) {
+	claims, typ, err := utils.ParseJWTWithType(token)
+	if err != nil {
+		return nil, err
+	}
+	// The signature shows the token was signed with a key of this server, the issuer that it was issued as one of its
+	// tokens
+	if iss, _ := claims["iss"].(string); iss != configuration.IssuerURL {
+		return nil, fmt.Errorf("token was not issued by this server")
+	}
+	// The typ header tells which of its tokens it is: an access token must be declared as one, and only access
+	// tokens and ID tokens can be exchanged, never e.g. a JWT introspection response
+	switch {
+	case typ == utils.AccessTokenJWTType:
+	case typ == utils.JWTType && tokenType == tokentype.JWT:
+	default:
+		return nil, fmt.Errorf("a token of type %q cannot be exchanged as %s", typ, tokenType)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("token has no exp claim")
+	}
+
+	exchange := &exchangeToken{}
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		exchange.act = act
+	}
+
+	// Access tokens must still be on record, so revoked tokens cannot be exchanged
+	if typ == utils.AccessTokenJWTType {
+		accessToken, err := t.accessTokenRepository.FindByAccessToken(token)
+		if err != nil {
+			return nil, err
+		}
+		exchange.clientId = utils.StringDeref(accessToken.ClientId)
+		exchange.userId = accessToken.UserId
+		exchange.scopes = utils.ScopesToStringSlice(accessToken.Scopes)
+		return exchange, nil
+	}
+
+	// ID tokens carry neither client_id nor, for a single audience, azp: their audience is the client they were
+	// issued to
+	exchange.clientId, _ = claims["clientId"].(string)
+	if exchange.clientId == "" {
+		exchange.clientId, _ = claims["azp"].(string)
+	}
+	if exchange.clientId == "" {
+		exchange.clientId = singleAudience(claims["aud"])
+	}
+	if userId, ok := claims["userId"].(string); ok {
+		exchange.userId = &userId
+	} else if sub, ok := claims["sub"].(string); ok {
+		exchange.userId = &sub
+	}
+	// A token without a scope claim grants no scopes
+	scope, _ := claims["scope"].(string)
+	exchange.scopes = splitAndTrim(scope)
+	if exchange.clientId == "" {
+		return nil, fmt.Errorf("token does not identify its client")
+	}
+	return exchange, nil
+}
+
+// singleAudience returns the audience of a JWT aud claim naming exactly one, and an empty string otherwise.
+func singleAudience(aud interface{}) string {
+	switch aud := aud.(type) {
+	case string:
+		return aud
+	case []interface{}:
+		if len(aud) == 1 {
+			audience, _ := aud[0].(string)
+			return audience
+		}
+	}
+	return ""
+}
+
+// resolveExchangeScopes narrows the requested scope to the client's registered scopes and to the scopes of the
+// subject token. An empty request keeps every scope both of them allow.
+func resolveExchangeScopes(clientScopes []store.Scope, subjectScopes []string, requested string) ([]store.Scope, error) {
+	if strings.TrimSpace(requested) == "" {
+		scopes := make([]store.Scope, 0, len(clientScopes))
+		for _, scope := range clientScopes {
+			if slices.Contains(subjectScopes, scope.Name) {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes, nil
+	}
+
+	scopes, err := resolveRequestedScopes(clientScopes, requested)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(subjectScopes, scope.Name) {
+			return nil, fmt.Errorf("%w: scope %s exceeds the subject token", api.ErrInvalidScope, scope.Name)
+		}
+	}
+	return scopes, nil
+}
+
+// tokenGrant carries what a flow has established about the grant once the client and resource owner are verified.
+type tokenGrant struct {
+	flow              string
+	client            *store.OauthClient
+	userId            *string
+	scopes            []store.Scope
+	code              string
+	issueRefreshToken bool
+	audience          []string               // narrows the token to these audiences
+	resources         []string               // resource indicators of the grant, kept on the refresh token
+	authTime          *time.Time             // when the resource owner authenticated, reported as auth_time
+	claims            map[string]interface{} // additional JWT claims, such as act
+	issuedTokenType   string                 // reported back to token exchange clients
+	idToken           *idTokenGrant          // issues an OpenID Connect ID token along with the access token
+	binding           tokenBinding           // binds the tokens to a DPoP key or TLS client certificate
+	// authorizationDetails are the fine-grained permissions (RFC 9396) of the access token. The refresh token keeps
+	// grantAuthorizationDetails instead when the access token was narrowed to a subset of the grant.
+	authorizationDetails      oauth.AuthorizationDetails
+	grantAuthorizationDetails oauth.AuthorizationDetails
+	// grantScopes are the scopes of the grant, kept on the refresh token when the access token was narrowed to the
+	// scopes of its audience
+	grantScopes []store.Scope
+	// familyId is the refresh token family the tokens join, and rotatedRefreshToken the refresh token that is used up
+	// as the new one is saved; a grant without them starts a new family.
+	familyId            string
+	rotatedRefreshToken *store.RefreshToken
+}
+
+// issueToken mints, persists and returns an access token for the grant, together with a refresh token when requested.
+func (t *tokenService) issueToken(grant *tokenGrant) (*oauth.Token, error) {
+	clientId := grant.client.ClientId
+
+	claims := maps.Clone(grant.claims)
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+	if len(grant.scopes) > 0 {
+		claims["scope"] = utils.JoinStringSlice(utils.ScopesToStringSlice(grant.scopes), " ")
+	}
+	if grant.authTime != nil {
+		claims["auth_time"] = grant.authTime.Unix()
+	}
+	if len(grant.audience) > 0 {
+		claims["aud"] = grant.audience
+	}
+	if len(grant.authorizationDetails) > 0 {
+		claims["authorization_details"] = grant.authorizationDetails
+	}
+	maps.Copy(claims, confirmationClaims(grant.binding))
+	jti := uuid.New().String()
+	claims["jti"] = jti
+
+	// Claims beyond the standard ones are kept for introspection
+	extensionClaims := maps.Clone(grant.claims)
+	if extensionClaims == nil {
+		extensionClaims = map[string]interface{}{}
+	}
+	if grant.authTime != nil {
+		extensionClaims["auth_time"] = grant.authTime.Unix()
+	}
+
+	// A grant issuing a refresh token starts a new refresh token family, unless it continues one
+	familyId := grant.familyId
+	if familyId == "" && grant.issueRefreshToken {
+		familyId = uuid.New().String()
+	}
+
+	// exp, the stored expiry and expires_in all derive from the same issue time and lifetime
+	issuedAt := time.Now()
+	accessTokenJwt, err := utils.GenerateAccessTokenJWT(grant.client.TokenSigning.AccessTokenSignedResponseAlg, &clientId, grant.userId, issuedAt, AccessTokenDuration, claims)
+	if err != nil {
+		t.logger.Error("Error generating JWT for access token", zap.String("flow", grant.flow), zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to generate access token JWT: %w", err)
+	}
+	accessTokenJwt, err = t.encryption.EncryptAccessToken(accessTokenJwt, grant.client, grant.audience)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken := store.NewAccessTokenBuilder().
+		WithClientId(&clientId).
+		WithToken(accessTokenJwt).
+		WithCode(grant.code).
+		WithTokenType(accessTokenType(grant.binding.jkt)).
+		WithExpiresAt(issuedAt.Add(AccessTokenDuration)).
+		WithUserId(grant.userId).
+		WithScopes(grant.scopes).
+		WithAudience(grant.audience).
+		WithJkt(grant.binding.jkt).
+		WithCertificateThumbprint(grant.binding.certificateThumbprint()).
+		WithAuthorizationDetails(grant.authorizationDetails.String()).
+		WithJti(jti).
+		WithExtensionClaims(encodeExtensionClaims(extensionClaims)).
+		WithFamilyId(familyId).
 		Build()
 
-	savedRefreshToken, err := t.refreshTokenRepository.Save(newRefreshToken)
+	savedAccessToken, err := t.accessTokenRepository.Save(accessToken)
 	if err != nil {
-		t.logger.Error("Error saving new refresh token for Authorization Code Flow", zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
-		return nil, fmt.Errorf("failed to save new refresh token: %w", err)
+		t.logger.Error("Error saving new access token", zap.String("flow", grant.flow), zap.String("clientId", clientId), zap.Error(err))
+		return nil, fmt.Errorf("failed to save access token: %w", err)
 	}
-	t.logger.Info("New refresh token saved successfully for Authorization Code Flow", zap.String("refreshTokenId", savedRefreshToken.Id))
+	t.logger.Info("New access token saved successfully", zap.String("flow", grant.flow), zap.String("accessTokenId", savedAccessToken.Id))
 
-	// Step 5: Build and return the token response
-	token := oauth.NewTokenBuilder().
+	tokenBuilder := oauth.NewTokenBuilder().
 		WithClientId(savedAccessToken.ClientId).
 		WithUserId(savedAccessToken.UserId).
 		WithAccessToken(savedAccessToken.Token).
@@ -429,13 +1110,235 @@ func (t *tokenService) handleAuthorizationCodeFlow(clientId, clientSecret, code,
 		WithAccessTokenCreatedAt(savedAccessToken.CreatedAt).
 		WithAccessTokenExpiresIn(int(AccessTokenDuration.Seconds())).
 		WithAccessTokenExpiresAt(savedAccessToken.ExpiresAt).
-		WithRefreshToken(savedRefreshToken.Token).
-		WithRefreshTokenCreatedAt(savedRefreshToken.CreatedAt).
-		WithRefreshTokenExpiresAt(savedRefreshToken.ExpiresAt).
 		WithExtension(nil).
-		Build()
+		WithScope(utils.ScopesToStringSlice(savedAccessToken.Scopes)).
+		WithIssuedTokenType(grant.issuedTokenType).
+		WithAuthorizationDetails(grant.authorizationDetails)
 
-	t.logger.Info("Token response successfully built for Authorization Code Flow", zap.String("clientId", utils.StringDeref(savedAccessToken.ClientId)))
+	if grant.idToken != nil {
+		idToken, err := generateIDToken(grant.idToken.withAccessToken(savedAccessToken.Token))
+		if err != nil {
+			t.logger.Error("Error generating ID token", zap.String("flow", grant.flow), zap.String("clientId", clientId), zap.Error(err))
+			return nil, fmt.Errorf("failed to generate ID token: %w", err)
+		}
+		idToken, err = t.encryption.EncryptIDToken(idToken, grant.client)
+		if err != nil {
+			return nil, err
+		}
+		tokenBuilder.WithIDToken(idToken)
+	}
 
-	return token, nil
+	if grant.issueRefreshToken {
+		refreshTokenValue, err := utils.GenerateRefreshToken()
+		if err != nil {
+			t.logger.Error("Error generating refresh token", zap.String("flow", grant.flow), zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
+			return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		}
+
+		grantDetails := grant.grantAuthorizationDetails
+		if grantDetails == nil {
+			grantDetails = grant.authorizationDetails
+		}
+		grantScopes := grant.grantScopes
+		if grantScopes == nil {
+			grantScopes = grant.scopes
+		}
+
+		refreshToken := store.NewRefreshTokenBuilder().
+			WithAccessTokenId(savedAccessToken.Id).
+			WithClientId(savedAccessToken.ClientId).
+			WithToken(refreshTokenValue).
+			WithTokenType("Bearer").
+			WithExpiresAt(time.Now().Add(RefreshTokenDuration)).
+			WithUserId(savedAccessToken.UserId).
+			WithScopes(grantScopes).
+			WithJkt(refreshTokenJkt(grant.client, grant.binding.jkt)).
+			WithAuthorizationDetails(grantDetails.String()).
+			WithResources(grant.resources).
+			WithFamilyId(familyId).
+			Build()
+
+		savedRefreshToken, err := t.saveRefreshToken(grant, refreshToken)
+		if err != nil {
+			t.logger.Error("Error saving new refresh token", zap.String("flow", grant.flow), zap.String("accessTokenId", savedAccessToken.Id), zap.Error(err))
+			return nil, err
+		}
+		t.logger.Info("New refresh token saved successfully", zap.String("flow", grant.flow), zap.String("refreshTokenId", savedRefreshToken.Id))
+
+		tokenBuilder.
+			WithRefreshToken(refreshTokenValue).
+			WithRefreshTokenCreatedAt(savedRefreshToken.CreatedAt).
+			WithRefreshTokenExpiresAt(savedRefreshToken.ExpiresAt)
+	}
+
+	t.logger.Info("Token response built", zap.String("flow", grant.flow), zap.String("clientId", clientId))
+	return tokenBuilder.Build(), nil
+}
+
+// saveRefreshToken saves the refresh token of a grant. A rotated refresh token is used up in the same transaction;
+// if it was used in the meantime, by a concurrent request presenting it, the reuse revokes its family.
+func (t *tokenService) saveRefreshToken(grant *tokenGrant, refreshToken *store.RefreshToken) (*store.RefreshToken, error) {
+	if grant.rotatedRefreshToken == nil {
+		savedRefreshToken, err := t.refreshTokenRepository.Save(refreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save refresh token: %w", err)
+		}
+		return savedRefreshToken, nil
+	}
+
+	savedRefreshToken, rotated, err := t.refreshTokenRepository.Rotate(grant.rotatedRefreshToken.Id, refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, t.handleRefreshTokenReuse(grant.rotatedRefreshToken)
+	}
+	return savedRefreshToken, nil
+}
+
+// narrowAuthorizationDetails returns the authorization details of a grant, stored as a JSON array, together with the
+// ones an access token issued from it carries: all of them, or the subset the token request asks for. A request may
+// narrow the details of a grant but never extend them (RFC 9396, section 6.1).
+func narrowAuthorizationDetails(granted, requested string) (oauth.AuthorizationDetails, oauth.AuthorizationDetails, error) {
+	grantDetails, err := oauth.ParseAuthorizationDetails(granted)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read granted authorization details: %w", err)
+	}
+	if requested == "" {
+		return grantDetails, grantDetails, nil
+	}
+
+	tokenDetails, err := oauth.ParseAuthorizationDetails(requested)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", api.ErrInvalidAuthorizationDetails, err)
+	}
+	if !grantDetails.Covers(tokenDetails) {
+		return nil, nil, fmt.Errorf("%w: authorization_details exceed the grant", api.ErrInvalidAuthorizationDetails)
+	}
+	return grantDetails, tokenDetails, nil
+}
+
+// tokenBinding holds what the issued tokens are sender-constrained to.
+type tokenBinding struct {
+	jkt          string              // thumbprint of the DPoP key the request proved possession of
+	certificates []*x509.Certificate // TLS client certificate chain, leaf first
+}
+
+// bound reports whether the request proved possession of a DPoP key or a TLS client certificate.
+func (b tokenBinding) bound() bool {
+	return b.jkt != "" || len(b.certificates) > 0
+}
+
+// certificateThumbprint returns the x5t#S256 thumbprint of the TLS client certificate, if one was presented.
+func (b tokenBinding) certificateThumbprint() string {
+	if len(b.certificates) == 0 {
+		return ""
+	}
+	return utils.CertificateThumbprint(b.certificates[0])
+}
+
+// confirmationClaims returns the cnf claim binding an access token to the DPoP key (RFC 9449, section 6.1) and the
+// TLS client certificate (RFC 8705, section 3.1) the request was sent with, or nil for bearer tokens.
+func confirmationClaims(binding tokenBinding) map[string]interface{} {
+	if !binding.bound() {
+		return nil
+	}
+	confirmation := map[string]interface{}{}
+	if binding.jkt != "" {
+		confirmation["jkt"] = binding.jkt
+	}
+	if thumbprint := binding.certificateThumbprint(); thumbprint != "" {
+		confirmation["x5t#S256"] = thumbprint
+	}
+	return map[string]interface{}{"cnf": confirmation}
+}
+
+// encodeExtensionClaims encodes the claims of an access token beyond the standard ones as a JSON object, or returns
+// an empty string when there are none.
+func encodeExtensionClaims(claims map[string]interface{}) string {
+	if len(claims) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// accessTokenType returns the token_type of an access token bound to jkt, if any.
+func accessTokenType(jkt string) string {
+	if jkt != "" {
+		return DPoPTokenType
+	}
+	return "Bearer"
+}
+
+// refreshTokenJkt returns the DPoP key a refresh token is bound to. Only public clients get bound refresh tokens;
+// confidential clients already authenticate when they use them (RFC 9449, section 5).
+func refreshTokenJkt(client *store.OauthClient, jkt string) string {
+	if !client.IsPublic() {
+		return ""
+	}
+	return jkt
+}
+
+// idTokenGrant describes the OpenID Connect ID token to mint for an authenticated end-user.
+type idTokenGrant struct {
+	alg         string // id_token_signed_response_alg of the client, if any
+	clientId    string
+	userId      string
+	nonce       string
+	authTime    *time.Time
+	accessToken string // hashed into at_hash when issued alongside the ID token
+	code        string // hashed into c_hash when issued alongside the ID token
+}
+
+// withAccessToken returns a copy of the grant bound to the access token it is issued with.
+func (g *idTokenGrant) withAccessToken(accessToken string) *idTokenGrant {
+	bound := *g
+	bound.accessToken = accessToken
+	return &bound
+}
+
+// generateIDToken signs an ID token for the grant, binding it to the access token and code it accompanies.
+func generateIDToken(grant *idTokenGrant) (string, error) {
+	claims := map[string]interface{}{}
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	if grant.authTime != nil && !grant.authTime.IsZero() {
+		claims["auth_time"] = grant.authTime.Unix()
+	}
+	if grant.accessToken != "" {
+		claims["at_hash"] = utils.IDTokenHash(grant.alg, grant.accessToken)
+	}
+	if grant.code != "" {
+		claims["c_hash"] = utils.IDTokenHash(grant.alg, grant.code)
+	}
+	return utils.GenerateIDToken(grant.alg, grant.userId, grant.clientId, IDTokenDuration, claims)
+}
+
+// hasScope reports whether the named scope is among the granted scopes.
+func hasScope(scopes []store.Scope, name string) bool {
+	return slices.ContainsFunc(scopes, func(s store.Scope) bool { return s.Name == name })
+}
+
+// resolveRequestedScopes narrows the client's registered scopes to the space-separated requested scope.
+// An empty request grants every scope registered for the client.
+func resolveRequestedScopes(clientScopes []store.Scope, requested string) ([]store.Scope, error) {
+	names := splitAndTrim(requested)
+	if len(names) == 0 {
+		return clientScopes, nil
+	}
+
+	scopes := make([]store.Scope, 0, len(names))
+	for _, name := range names {
+		idx := slices.IndexFunc(clientScopes, func(s store.Scope) bool { return s.Name == name })
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: scope %s is not registered for the client", api.ErrInvalidScope, name)
+		}
+		scopes = append(scopes, clientScopes[idx])
+	}
+	return scopes, nil
 }
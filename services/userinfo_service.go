@@ -1,6 +1,7 @@
 package services
 
 import (
+	"crypto/x509"
 	"fmt"
 
 	"github.com/manuelrojas19/go-oauth2-server/store/repositories"
@@ -9,6 +10,10 @@ import (
 
 type GetUserinfoCommand struct {
 	AccessToken string
+	Scheme      string            // authorization scheme the token was presented with, Bearer or DPoP
+	DPoPProof   *DPoPProofCommand // proof sent with the request, checked when the token is DPoP-bound
+	// ClientCertificate is the TLS client certificate of the connection, checked when the token is certificate-bound
+	ClientCertificate *x509.Certificate
 }
 
 type UserinfoResponse struct {
@@ -20,13 +25,15 @@ type UserinfoResponse struct {
 type userinfoService struct {
 	accessTokenRepository repositories.AccessTokenRepository
 	userRepository        repositories.UserRepository
+	dpopService           DPoPService
 	logger                *zap.Logger
 }
 
-func NewUserinfoService(accessTokenRepository repositories.AccessTokenRepository, userRepository repositories.UserRepository, logger *zap.Logger) UserinfoService {
+func NewUserinfoService(accessTokenRepository repositories.AccessTokenRepository, userRepository repositories.UserRepository, dpopService DPoPService, logger *zap.Logger) UserinfoService {
 	return &userinfoService{
 		accessTokenRepository: accessTokenRepository,
 		userRepository:        userRepository,
+		dpopService:           dpopService,
 		logger:                logger,
 	}
 }
@@ -43,6 +50,21 @@ func (s *userinfoService) GetUserinfo(command *GetUserinfoCommand) (*UserinfoRes
 	}
 	s.logger.Debug("Access token entity found", zap.Any("accessTokenEntity", accessTokenEntity))
 
+	// Sender-constrained tokens are only accepted with a proof of possession of their key
+	err = s.dpopService.ValidateTokenBinding(&TokenBindingCommand{
+		Jkt:    accessTokenEntity.Jkt,
+		Scheme: command.Scheme,
+		Proof:  command.DPoPProof,
+	})
+	if err != nil {
+		s.logger.Warn("Access token binding check failed", zap.Error(err))
+		return nil, err
+	}
+	if err := validateCertificateBinding(accessTokenEntity.CertificateThumbprint, command.ClientCertificate); err != nil {
+		s.logger.Warn("Access token certificate binding check failed", zap.Error(err))
+		return nil, err
+	}
+
 	s.logger.Debug("Calling userRepository.FindById", zap.String("userId", *accessTokenEntity.UserId))
 	userEntity, err := s.userRepository.FindById(*accessTokenEntity.UserId)
 	if err != nil {
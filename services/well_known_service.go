@@ -1,80 +1,142 @@
 package services
 
 import (
-	"crypto/rsa"
-	"crypto/sha1"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
 )
 
+// ServerMetadata is the authorization server metadata document (RFC 8414), which doubles as the OpenID Provider
+// configuration when the OpenID Connect fields are filled in.
+type ServerMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                              string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint,omitempty"`
+	JwksUri                                    string   `json:"jwks_uri,omitempty"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint,omitempty"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint,omitempty"`
+	ScopesSupported                            []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported                        []string `json:"grant_types_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported,omitempty"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported,omitempty"`
+	IdTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	IdTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported,omitempty"`
+	IdTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported,omitempty"`
+	ClaimsSupported                            []string `json:"claims_supported,omitempty"`
+	RequestParameterSupported                  bool     `json:"request_parameter_supported,omitempty"`
+	RequestUriParameterSupported               bool     `json:"request_uri_parameter_supported,omitempty"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported,omitempty"`
+	DPoPSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported,omitempty"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	AuthorizationDetailsTypesSupported         []string `json:"authorization_details_types_supported,omitempty"`
+	IntrospectionSigningAlgValuesSupported     []string `json:"introspection_signing_alg_values_supported,omitempty"`
+	IntrospectionEncryptionAlgValuesSupported  []string `json:"introspection_encryption_alg_values_supported,omitempty"`
+	IntrospectionEncryptionEncValuesSupported  []string `json:"introspection_encryption_enc_values_supported,omitempty"`
+}
+
+// metadataEndpoints maps route paths to the metadata field that advertises them. An endpoint is only published
+// once its route is registered.
+var metadataEndpoints = map[string]func(metadata *ServerMetadata, url string){
+	"/oauth/authorize":            func(m *ServerMetadata, url string) { m.AuthorizationEndpoint = url },
+	"/oauth/token":                func(m *ServerMetadata, url string) { m.TokenEndpoint = url },
+	"/oauth/userinfo":             func(m *ServerMetadata, url string) { m.UserinfoEndpoint = url },
+	"/.well-known/jwks.json":      func(m *ServerMetadata, url string) { m.JwksUri = url },
+	"/oauth/register":             func(m *ServerMetadata, url string) { m.RegistrationEndpoint = url },
+	"/oauth/introspect":           func(m *ServerMetadata, url string) { m.IntrospectionEndpoint = url },
+	"/oauth/revoke":               func(m *ServerMetadata, url string) { m.RevocationEndpoint = url },
+	"/oauth/device_authorization": func(m *ServerMetadata, url string) { m.DeviceAuthorizationEndpoint = url },
+	"/oauth/par":                  func(m *ServerMetadata, url string) { m.PushedAuthorizationRequestEndpoint = url },
+}
+
 type wellKnownService struct {
-	jwkSetCache *jwkCache
-	once        sync.Once
+	authorizationDetailsService AuthorizationDetailsService
+	jwkSetCache                 *jwkCache
+	once                        sync.Once
+	endpoints                   []string
+	mu                          sync.RWMutex
+	logger                      *zap.Logger
 }
 
 type jwkCache struct {
 	set        *jwk.Set
 	expiration time.Time
+	version    int // key ring version the set was built from
 }
 
-func NewWellKnownService() WellKnownService {
-	return &wellKnownService{}
+func NewWellKnownService(authorizationDetailsService AuthorizationDetailsService, logger *zap.Logger) WellKnownService {
+	return &wellKnownService{
+		authorizationDetailsService: authorizationDetailsService,
+		logger:                      logger,
+	}
 }
 
-// GetJwk retrieves the JWK set containing the public key for JWT.
+// GetJwk retrieves the JWK set with every signing key still needed to verify tokens, for every algorithm: the next
+// key, published before it starts signing, the active key and the retiring keys. Revoked keys are left out.
 func (w *wellKnownService) GetJwk() (*jwk.Set, error) {
 	w.once.Do(func() {
 		w.jwkSetCache = &jwkCache{}
 	})
 
-	if w.jwkSetCache.set != nil && time.Now().Before(w.jwkSetCache.expiration) {
+	// A rotation invalidates the cached set right away, so revoked keys disappear without waiting for it to expire
+	version := configuration.SigningKeys.Version()
+	if w.jwkSetCache.set != nil && w.jwkSetCache.version == version && time.Now().Before(w.jwkSetCache.expiration) {
 		return w.jwkSetCache.set, nil
 	}
 
 	set := jwk.NewSet()
 
-	publicKey, err := configuration.GetJWTPublicKey()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get JWT public key: %w", err)
-	}
+	for _, signingKey := range configuration.SigningKeys.Published() {
+		jwtKey, err := jwk.New(signingKey.PublicKey())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JWK from public key: %w", err)
+		}
 
-	jwtKey, err := jwk.New(publicKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create JWK from public key: %w", err)
-	}
+		params := map[string]interface{}{
+			jwk.KeyIDKey:     signingKey.Id,
+			jwk.KeyUsageKey:  "sig",
+			jwk.AlgorithmKey: signingKey.Algorithm,
+		}
 
-	params := map[string]interface{}{
-		jwk.KeyIDKey:     calculateKid(publicKey),
-		jwk.KeyUsageKey:  "sig",
-		jwk.AlgorithmKey: "RS256",
-	}
+		if err := setJWKParameters(jwtKey, params); err != nil {
+			return nil, fmt.Errorf("failed to set JWK parameters: %w", err)
+		}
 
-	if err := setJWKParameters(jwtKey, params); err != nil {
-		return nil, fmt.Errorf("failed to set JWK parameters: %w", err)
+		set.Add(jwtKey)
 	}
 
-	set.Add(jwtKey)
+	if set.Len() == 0 {
+		return nil, errors.New("no signing keys to publish")
+	}
 
 	// Cache for 5 minutes (or a configurable duration)
 	w.jwkSetCache.set = &set
 	w.jwkSetCache.expiration = time.Now().Add(5 * time.Minute)
+	w.jwkSetCache.version = version
 
 	return &set, nil
 }
 
-// calculateKid generates a key ID based on the public key.
-func calculateKid(publicKey *rsa.PublicKey) string {
-	keyData := publicKey.N.Bytes()
-	hasher := sha1.New()
-	hasher.Write(keyData)
-	return hex.EncodeToString(hasher.Sum(nil))
-}
-
 // setJWKParameters sets multiple parameters on a JWK key and returns any errors encountered.
 func setJWKParameters(key jwk.Key, params map[string]interface{}) error {
 	for k, v := range params {
@@ -84,3 +146,76 @@ func setJWKParameters(key jwk.Key, params map[string]interface{}) error {
 	}
 	return nil
 }
+
+// RegisterEndpoints records the route paths the server exposes, so the metadata only advertises live endpoints.
+func (w *wellKnownService) RegisterEndpoints(paths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.endpoints = slices.Clone(paths)
+	slices.Sort(w.endpoints)
+}
+
+// GetAuthorizationServerMetadata builds the RFC 8414 metadata from the registered routes and supported enums.
+func (w *wellKnownService) GetAuthorizationServerMetadata() *ServerMetadata {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	metadata := &ServerMetadata{
+		Issuer:                            configuration.IssuerURL,
+		ResponseTypesSupported:            enumStrings(responsetype.Supported),
+		ResponseModesSupported:            []string{"query", "fragment"},
+		GrantTypesSupported:               enumStrings(granttype.Supported),
+		TokenEndpointAuthMethodsSupported: enumStrings(authmethodtype.Supported),
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		// private_key_jwt assertions are signed with an asymmetric algorithm, client_secret_jwt ones with HMAC
+		TokenEndpointAuthSigningAlgValuesSupported: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512"},
+		// The introspection and revocation endpoints authenticate clients the same way the token endpoint does
+		IntrospectionEndpointAuthMethodsSupported: enumStrings(authmethodtype.Supported),
+		RevocationEndpointAuthMethodsSupported:    enumStrings(authmethodtype.Supported),
+		// Request objects (RFC 9101) are accepted by value and by reference, signed with any asymmetric algorithm
+		RequestParameterSupported:              true,
+		RequestUriParameterSupported:           true,
+		RequestObjectSigningAlgValuesSupported: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+		DPoPSigningAlgValuesSupported:          utils.DPoPSigningAlgorithms,
+		// Certificate-bound access tokens (RFC 8705) need the TLS listener to receive client certificates
+		TLSClientCertificateBoundAccessTokens: configuration.TLSEnabled(),
+		// JWT introspection responses (RFC 9701) are signed with any configured algorithm and encrypted on request
+		IntrospectionSigningAlgValuesSupported:    slices.Clone(configuration.SigningAlgorithms),
+		IntrospectionEncryptionAlgValuesSupported: encryptiontype.SupportedAlgorithms,
+		IntrospectionEncryptionEncValuesSupported: encryptiontype.SupportedEncryptionMethods,
+	}
+	for _, path := range w.endpoints {
+		if set, ok := metadataEndpoints[path]; ok {
+			set(metadata, configuration.IssuerURL+path)
+		}
+	}
+
+	// Rich authorization requests (RFC 9396) accept the types that have a schema registered
+	types, err := w.authorizationDetailsService.SupportedTypes()
+	if err != nil {
+		w.logger.Warn("Failed to list authorization details types, omitting them from the metadata", zap.Error(err))
+	}
+	metadata.AuthorizationDetailsTypesSupported = types
+	return metadata
+}
+
+// GetOpenIDConfiguration builds the OpenID Provider metadata, the RFC 8414 document plus the OpenID Connect fields.
+func (w *wellKnownService) GetOpenIDConfiguration() *ServerMetadata {
+	metadata := w.GetAuthorizationServerMetadata()
+	metadata.ScopesSupported = []string{OpenIDScope}
+	metadata.SubjectTypesSupported = []string{"public"}
+	metadata.IdTokenSigningAlgValuesSupported = slices.Clone(configuration.SigningAlgorithms)
+	metadata.IdTokenEncryptionAlgValuesSupported = encryptiontype.SupportedAlgorithms
+	metadata.IdTokenEncryptionEncValuesSupported = encryptiontype.SupportedEncryptionMethods
+	metadata.ClaimsSupported = []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "c_hash"}
+	return metadata
+}
+
+// enumStrings converts a list of enum values to the strings published in the metadata.
+func enumStrings[T ~string](values []T) []string {
+	strings := make([]string, 0, len(values))
+	for _, value := range values {
+		strings = append(strings, string(value))
+	}
+	return strings
+}
@@ -29,8 +29,9 @@ func (u *sessionService) CreateSession(userId, email string) (string, error) {
 	start := time.Now()
 	sessionID := uuid.New().String()
 	sessionData := map[string]interface{}{
-		"user_id": userId,
-		"email":   email,
+		"user_id":   userId,
+		"email":     email,
+		"auth_time": time.Now().Unix(),
 	}
 	u.logger.Info("Attempting to create session", zap.String("userId", userId), zap.String("email", email))
 
@@ -160,6 +161,36 @@ func (u *sessionService) GetUserIdFromSession(sessionID string) (string, error)
 	return userID, nil
 }
 
+// GetAuthTimeFromSession returns when the user of the session authenticated, for the OpenID Connect auth_time claim.
+func (u *sessionService) GetAuthTimeFromSession(sessionID string) (time.Time, error) {
+	start := time.Now()
+	u.logger.Info("Attempting to retrieve auth time from session", zap.String("sessionId", sessionID))
+	authTime, err := u.redisClient.HGet(context.Background(), sessionID, "auth_time").Int64()
+	if err != nil {
+		if err == redis.Nil {
+			u.logger.Info("Session not found or auth_time not found in session",
+				zap.String("sessionId", sessionID),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return time.Time{}, fmt.Errorf("session not found or auth_time not found in session")
+		}
+		u.logger.Error("Error retrieving auth_time from session",
+			zap.String("sessionId", sessionID),
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+			zap.Stack("stacktrace"),
+		)
+		return time.Time{}, fmt.Errorf("failed to retrieve auth time from session: %w", err)
+	}
+
+	u.logger.Debug("Auth time retrieved from session",
+		zap.String("sessionId", sessionID),
+		zap.Int64("authTime", authTime),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return time.Unix(authTime, 0), nil
+}
+
 func (u *sessionService) DeleteSession(sessionID string) error {
 	start := time.Now()
 	u.logger.Info("Attempting to delete session", zap.String("sessionId", sessionID))
@@ -4,33 +4,58 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type AccessToken struct {
-	Id            string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	Token         string    `gorm:"type:text;unique;not null"`
-	TokenType     string    `gorm:"type:varchar(255);not null"`
-	ExpiresAt     time.Time `gorm:"not null"`
-	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	Code          string    `gorm:"type:text"` // Reference to authorization code
-	UserId        *string   `gorm:"index"`
-	ClientId      *string   `gorm:"index"`
-	User          *User
-	Client        *OauthClient
-	RefreshTokens []RefreshToken `gorm:"foreignKey:AccessTokenId;constraint:OnDelete:CASCADE"`
-	Scopes        []Scope        `gorm:"many2many:access_token_scopes;constraint:OnDelete:CASCADE"`
+	Id                    string         `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Token                 string         `gorm:"type:text;unique;not null"`
+	TokenType             string         `gorm:"type:varchar(255);not null"`
+	ExpiresAt             time.Time      `gorm:"not null"`
+	CreatedAt             time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+	Code                  string         `gorm:"type:text"` // Reference to authorization code
+	UserId                *string        `gorm:"index"`
+	ClientId              *string        `gorm:"index"`
+	Audience              pq.StringArray `gorm:"type:text[]"`       // Intended audiences: the requested resources, or the audience of an exchanged token
+	Jkt                   string         `gorm:"type:varchar(255)"` // Thumbprint of the DPoP key the token is bound to, if any
+	CertificateThumbprint string         `gorm:"type:varchar(255)"` // x5t#S256 of the TLS client certificate the token is bound to, if any
+	AuthorizationDetails  string         `gorm:"type:text"`         // JSON array of the authorization details (RFC 9396) granted to the token
+	User                  *User
+	Client                *OauthClient
+	RefreshTokens         []RefreshToken `gorm:"foreignKey:AccessTokenId;constraint:OnDelete:CASCADE"`
+	Scopes                []Scope        `gorm:"many2many:access_token_scopes;constraint:OnDelete:CASCADE"`
+
+	// Jti is the jti claim of the token
+	Jti string `gorm:"type:varchar(255);index"`
+	// ExtensionClaims is the JSON object of the token's claims beyond the standard ones, such as act, reported by
+	// introspection
+	ExtensionClaims string `gorm:"type:text"`
+	// FamilyId is the refresh token family the token was issued in, if any, revoked with it
+	FamilyId string `gorm:"type:varchar(255);index"`
+}
+
+// IsExpired checks if the access token has expired
+func (a *AccessToken) IsExpired() bool {
+	return time.Now().After(a.ExpiresAt)
 }
 
 type AccessTokenBuilder struct {
-	token     string
-	tokenType string
-	expiresAt time.Time
-	clientId  *string
-	client    *OauthClient
-	userId    *string
-	user      *User
-	code      string
-	scopes    []Scope
+	token                 string
+	tokenType             string
+	expiresAt             time.Time
+	clientId              *string
+	client                *OauthClient
+	userId                *string
+	user                  *User
+	code                  string
+	scopes                []Scope
+	audience              []string
+	jkt                   string
+	certificateThumbprint string
+	authorizationDetails  string
+	jti                   string
+	extensionClaims       string
+	familyId              string
 }
 
 // NewAccessTokenBuilder initializes a new builder instance.
@@ -89,6 +114,48 @@ func (b *AccessTokenBuilder) WithUser(user *User) *AccessTokenBuilder {
 	return b
 }
 
+// WithAudience sets the audiences the token is intended for.
+func (b *AccessTokenBuilder) WithAudience(audience []string) *AccessTokenBuilder {
+	b.audience = audience
+	return b
+}
+
+// WithCertificateThumbprint binds the token to the TLS client certificate with the given x5t#S256 thumbprint.
+func (b *AccessTokenBuilder) WithCertificateThumbprint(thumbprint string) *AccessTokenBuilder {
+	b.certificateThumbprint = thumbprint
+	return b
+}
+
+// WithJkt binds the token to the DPoP key with the given thumbprint.
+func (b *AccessTokenBuilder) WithJkt(jkt string) *AccessTokenBuilder {
+	b.jkt = jkt
+	return b
+}
+
+// WithAuthorizationDetails sets the JSON encoded authorization details granted to the token.
+func (b *AccessTokenBuilder) WithAuthorizationDetails(authorizationDetails string) *AccessTokenBuilder {
+	b.authorizationDetails = authorizationDetails
+	return b
+}
+
+// WithJti sets the jti claim of the token.
+func (b *AccessTokenBuilder) WithJti(jti string) *AccessTokenBuilder {
+	b.jti = jti
+	return b
+}
+
+// WithExtensionClaims sets the JSON encoded claims of the token beyond the standard ones.
+func (b *AccessTokenBuilder) WithExtensionClaims(extensionClaims string) *AccessTokenBuilder {
+	b.extensionClaims = extensionClaims
+	return b
+}
+
+// WithFamilyId sets the refresh token family the token is issued in.
+func (b *AccessTokenBuilder) WithFamilyId(familyId string) *AccessTokenBuilder {
+	b.familyId = familyId
+	return b
+}
+
 func (b *AccessTokenBuilder) WithCode(code string) *AccessTokenBuilder {
 	b.code = code
 	return b
@@ -97,16 +164,23 @@ func (b *AccessTokenBuilder) WithCode(code string) *AccessTokenBuilder {
 // Build constructs an AccessToken instance.
 func (b *AccessTokenBuilder) Build() *AccessToken {
 	return &AccessToken{
-		Id:        uuid.New().String(),
-		Token:     b.token,
-		TokenType: b.tokenType,
-		ExpiresAt: b.expiresAt,
-		ClientId:  b.clientId,
-		Client:    b.client,
-		UserId:    b.userId,
-		User:      b.user,
-		Code:      b.code,
-		CreatedAt: time.Now(),
-		Scopes:    b.scopes,
+		Id:                    uuid.New().String(),
+		Token:                 b.token,
+		TokenType:             b.tokenType,
+		ExpiresAt:             b.expiresAt,
+		ClientId:              b.clientId,
+		Client:                b.client,
+		UserId:                b.userId,
+		User:                  b.user,
+		Code:                  b.code,
+		CreatedAt:             time.Now(),
+		Scopes:                b.scopes,
+		Audience:              b.audience,
+		Jkt:                   b.jkt,
+		CertificateThumbprint: b.certificateThumbprint,
+		AuthorizationDetails:  b.authorizationDetails,
+		Jti:                   b.jti,
+		ExtensionClaims:       b.extensionClaims,
+		FamilyId:              b.familyId,
 	}
 }
@@ -4,22 +4,29 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type AuthCode struct {
-	Id                  string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	Code                string    `gorm:"type:text;unique;not null"`
-	RedirectURI         string    `gorm:"type:varchar(255);not null"`
-	Used                bool      `gorm:"not null;default:false"`
-	UserId              *string   `gorm:"index"`
-	ClientId            *string   `gorm:"index"`
-	ExpiresAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	CreatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	CodeChallenge       string    `gorm:"type:varchar(255)"`
-	CodeChallengeMethod string    `gorm:"type:varchar(255)"`
-	User                *User
-	Client              *OauthClient
-	Scopes              []Scope `gorm:"many2many:auth_code_scopes;"`
+	Id                  string     `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Code                string     `gorm:"type:text;unique;not null"`
+	RedirectURI         string     `gorm:"type:varchar(255);not null"`
+	Used                bool       `gorm:"not null;default:false"`
+	UserId              *string    `gorm:"index"`
+	ClientId            *string    `gorm:"index"`
+	ExpiresAt           time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedAt           time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	CodeChallenge       string     `gorm:"type:varchar(255)"`
+	CodeChallengeMethod string     `gorm:"type:varchar(255)"`
+	Nonce               string     `gorm:"type:varchar(255)"` // OpenID Connect nonce, echoed in the ID token
+	AuthTime            *time.Time // When the end-user authenticated, reported as auth_time
+	// AuthorizationDetails is the JSON array of authorization details (RFC 9396) the code was granted for
+	AuthorizationDetails string `gorm:"type:text"`
+	// Resources are the resource indicators (RFC 8707) the code was granted for
+	Resources pq.StringArray `gorm:"type:text[]"`
+	User      *User
+	Client    *OauthClient
+	Scopes    []Scope `gorm:"many2many:auth_code_scopes;"`
 }
 
 // AuthCodeBuilder helps in constructing AuthCode instances
@@ -57,7 +64,7 @@ func (b *AuthCodeBuilder) WithRedirectURI(redirectURI string) *AuthCodeBuilder {
 }
 
 func (b *AuthCodeBuilder) WithScopes(scopes []Scope) *AuthCodeBuilder {
-	b.authorizationCode.Scopes = scopes
+	b.scopes = scopes
 	return b
 }
 
@@ -92,6 +99,28 @@ func (b *AuthCodeBuilder) WithCodeChallengeMethod(codeChallengeMethod string) *A
 	return b
 }
 
+func (b *AuthCodeBuilder) WithNonce(nonce string) *AuthCodeBuilder {
+	b.authorizationCode.Nonce = nonce
+	return b
+}
+
+func (b *AuthCodeBuilder) WithAuthTime(authTime *time.Time) *AuthCodeBuilder {
+	b.authorizationCode.AuthTime = authTime
+	return b
+}
+
+// WithAuthorizationDetails sets the JSON encoded authorization details the code is granted for.
+func (b *AuthCodeBuilder) WithAuthorizationDetails(authorizationDetails string) *AuthCodeBuilder {
+	b.authorizationCode.AuthorizationDetails = authorizationDetails
+	return b
+}
+
+// WithResources sets the resource indicators the code is granted for.
+func (b *AuthCodeBuilder) WithResources(resources []string) *AuthCodeBuilder {
+	b.authorizationCode.Resources = resources
+	return b
+}
+
 func (b *AuthCodeBuilder) Build() *AuthCode {
 	b.authorizationCode.Id = uuid.New().String()
 	b.authorizationCode.Scopes = b.scopes
@@ -0,0 +1,14 @@
+package store
+
+import (
+	"time"
+)
+
+// AuthorizationDetailType is a type of authorization detail clients may request in authorization_details (RFC 9396).
+// Each entry of that type is validated against Schema, a JSON Schema document describing its fields.
+type AuthorizationDetailType struct {
+	Type        string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Description string    `gorm:"type:text"`
+	Schema      string    `gorm:"type:text;not null"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
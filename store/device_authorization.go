@@ -0,0 +1,84 @@
+package store
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// DeviceAuthorization tracks a device authorization request (RFC 8628) from issuance until the device redeems it.
+type DeviceAuthorization struct {
+	Id           string  `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	DeviceCode   string  `gorm:"type:varchar(255);unique;not null"`
+	UserCode     string  `gorm:"type:varchar(255);unique;not null"`
+	ClientId     *string `gorm:"index"`
+	Scope        string  `gorm:"type:text"`
+	Status       string  `gorm:"type:varchar(50);not null;default:pending"`
+	UserId       *string `gorm:"index"`
+	Interval     int     `gorm:"not null"`
+	LastPolledAt *time.Time
+	ExpiresAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Client       *OauthClient
+	User         *User
+}
+
+// IsExpired reports whether the device code can no longer be redeemed.
+func (d *DeviceAuthorization) IsExpired() bool {
+	return time.Now().After(d.ExpiresAt)
+}
+
+// DeviceAuthorizationBuilder helps in constructing DeviceAuthorization instances
+type DeviceAuthorizationBuilder struct {
+	deviceAuthorization DeviceAuthorization
+}
+
+func NewDeviceAuthorizationBuilder() *DeviceAuthorizationBuilder {
+	return &DeviceAuthorizationBuilder{
+		deviceAuthorization: DeviceAuthorization{
+			Status:    DeviceAuthorizationPending,
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+func (b *DeviceAuthorizationBuilder) WithDeviceCode(deviceCode string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.DeviceCode = deviceCode
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithUserCode(userCode string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.UserCode = userCode
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithClientId(clientId *string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.ClientId = clientId
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithScope(scope string) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.Scope = scope
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithInterval(interval int) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.Interval = interval
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) WithExpiresAt(expiresAt time.Time) *DeviceAuthorizationBuilder {
+	b.deviceAuthorization.ExpiresAt = expiresAt
+	return b
+}
+
+func (b *DeviceAuthorizationBuilder) Build() *DeviceAuthorization {
+	b.deviceAuthorization.Id = uuid.New().String()
+	return &b.deviceAuthorization
+}
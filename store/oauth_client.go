@@ -3,20 +3,26 @@ package store
 import (
 	"fmt"
 	"log"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
 	"github.com/manuelrojas19/go-oauth2-server/oauth/responsetype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type OauthClient struct {
-	ClientId                string         `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	ClientName              string         `gorm:"type:varchar(255);unique;not null"`
-	ClientSecret            string         `gorm:"type:text;not null"`
+	ClientId     string `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	ClientName   string `gorm:"type:varchar(255);unique;not null"`
+	ClientSecret string `gorm:"type:text;not null"`
+	// SealedClientSecret is the client secret encrypted with CLIENT_SECRET_KEY, kept for client_secret_jwt clients
+	// only, whose assertions are signed with the plain secret
+	SealedClientSecret      string         `gorm:"type:text"`
 	ResponseTypes           pq.StringArray `gorm:"type:text[];not null"`
 	GrantTypes              pq.StringArray `gorm:"type:text[];not null"`
 	TokenEndpointAuthMethod string         `gorm:"type:varchar(255);not null"`
@@ -25,6 +31,27 @@ type OauthClient struct {
 	CreatedAt               time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt               time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	ClientSecretExpiresAt   int64     `gorm:"type:bigint"`
+	Jwks                    string    `gorm:"type:text"`         // Inline JWK Set with the client's public keys
+	JwksUri                 string    `gorm:"type:varchar(255)"` // Location of the client's JWK Set document
+	// TokenExchangeSubjectClients lists the clients whose tokens this client may present as subject_token.
+	TokenExchangeSubjectClients pq.StringArray `gorm:"type:text[]"`
+	// TokenExchangeAudiences lists the audiences this client may request tokens for through token exchange.
+	TokenExchangeAudiences pq.StringArray `gorm:"type:text[]"`
+	// RequestUris lists the URLs the client may pass request objects by reference from; no others are fetched.
+	RequestUris pq.StringArray `gorm:"type:text[]"`
+	// IntrospectionAllowed lets the client, a resource server, introspect tokens issued for its audience (RFC 7662).
+	// It is granted by the operator and cannot be requested at registration.
+	IntrospectionAllowed bool `gorm:"not null;default:false"`
+	// RequirePushedAuthorizationRequests only lets the client start authorization through a pushed request (RFC 9126).
+	RequirePushedAuthorizationRequests bool `gorm:"not null;default:false"`
+	// RequireSignedRequestObject only accepts authorization parameters from a signed request object (RFC 9101).
+	RequireSignedRequestObject bool `gorm:"not null;default:false"`
+	// TLSClientAuth is the certificate subject a tls_client_auth client authenticates with (RFC 8705).
+	TLSClientAuth authmethodtype.CertificateSubject `gorm:"embedded;embeddedPrefix:tls_client_auth_"`
+	// TokenEncryption holds the algorithms the client's ID tokens and access tokens are encrypted to its keys with.
+	TokenEncryption encryptiontype.TokenEncryption `gorm:"embedded"`
+	// TokenSigning holds the algorithms the client's ID tokens and access tokens are signed with, if not the default.
+	TokenSigning signingtype.TokenSigning `gorm:"embedded"`
 
 	Scopes []Scope `gorm:"many2many:oauth_client_scopes;foreignKey:ClientId;joinForeignKey:ClientId;References:Id;JoinReferences:ScopeId"`
 }
@@ -41,10 +68,36 @@ func (c *OauthClient) ValidateSecret(providedPassword string) error {
 	return nil
 }
 
+// CanExchangeSubjectOf reports whether this client may exchange tokens that were issued to subjectClientId.
+// A client may always exchange its own tokens.
+func (c *OauthClient) CanExchangeSubjectOf(subjectClientId string) bool {
+	return subjectClientId == c.ClientId || slices.Contains(c.TokenExchangeSubjectClients, subjectClientId)
+}
+
+// SupportsResponseType reports whether the client registered the given response type.
+// Combined response types match regardless of the order of their values.
+func (c *OauthClient) SupportsResponseType(responseType responsetype.ResponseType) bool {
+	requested := responsetype.Normalize(responseType)
+	return slices.ContainsFunc(c.ResponseTypes, func(registered string) bool {
+		return responsetype.Normalize(responsetype.ResponseType(registered)) == requested
+	})
+}
+
+// CanExchangeForAudience reports whether this client may request an exchanged token for the given audience.
+func (c *OauthClient) CanExchangeForAudience(audience string) bool {
+	return slices.Contains(c.TokenExchangeAudiences, audience)
+}
+
+// AllowsRequestUri reports whether the client registered the URL to pass request objects by reference from.
+func (c *OauthClient) AllowsRequestUri(requestUri string) bool {
+	return slices.Contains(c.RequestUris, requestUri)
+}
+
 // OauthClientBuilder helps build an OauthClient with optional configurations.
 type OauthClientBuilder struct {
 	clientID                string
 	clientSecret            string
+	sealedClientSecret      string
 	clientName              string
 	responseTypes           []responsetype.ResponseType
 	grantTypes              []granttype.GrantType
@@ -53,6 +106,16 @@ type OauthClientBuilder struct {
 	confidential            bool
 	clientSecretExpiresAt   int64
 	scopes                  []Scope
+	tokenExchangeSubjects   []string
+	jwks                    string
+	jwksUri                 string
+	tokenExchangeAudiences  []string
+	requestUris             []string
+	requirePAR              bool
+	requireSignedRequest    bool
+	tlsClientAuth           authmethodtype.CertificateSubject
+	tokenEncryption         encryptiontype.TokenEncryption
+	tokenSigning            signingtype.TokenSigning
 }
 
 // NewOauthClientBuilder initializes a new OauthClientBuilder.
@@ -108,6 +171,12 @@ func (b *OauthClientBuilder) WithConfidential(confidential bool) *OauthClientBui
 	return b
 }
 
+// WithSealedClientSecret sets the encrypted client secret client_secret_jwt assertions are verified with.
+func (b *OauthClientBuilder) WithSealedClientSecret(sealedClientSecret string) *OauthClientBuilder {
+	b.sealedClientSecret = sealedClientSecret
+	return b
+}
+
 // WithClientSecretExpiresAt sets the client secret expiration time.
 func (b *OauthClientBuilder) WithClientSecretExpiresAt(expiresAt int64) *OauthClientBuilder {
 	b.clientSecretExpiresAt = expiresAt
@@ -120,6 +189,76 @@ func (b *OauthClientBuilder) WithScopes(scopes []Scope) *OauthClientBuilder {
 	return b
 }
 
+// WithTokenExchangePolicy sets the subject clients and audiences allowed for token exchange.
+func (b *OauthClientBuilder) WithTokenExchangePolicy(subjectClients, audiences []string) *OauthClientBuilder {
+	b.tokenExchangeSubjects = subjectClients
+	b.tokenExchangeAudiences = audiences
+	return b
+}
+
+// WithRequestUris sets the URLs the client may pass request objects by reference from.
+func (b *OauthClientBuilder) WithRequestUris(requestUris []string) *OauthClientBuilder {
+	b.requestUris = requestUris
+	return b
+}
+
+// WithJwks sets the client's public keys, either inline or by reference.
+func (b *OauthClientBuilder) WithJwks(jwks, jwksUri string) *OauthClientBuilder {
+	b.jwks = jwks
+	b.jwksUri = jwksUri
+	return b
+}
+
+// WithRequirePushedAuthorizationRequests sets whether the client must use pushed authorization requests.
+func (b *OauthClientBuilder) WithRequirePushedAuthorizationRequests(required bool) *OauthClientBuilder {
+	b.requirePAR = required
+	return b
+}
+
+// WithRequireSignedRequestObject sets whether the client must send its parameters in a signed request object.
+func (b *OauthClientBuilder) WithRequireSignedRequestObject(required bool) *OauthClientBuilder {
+	b.requireSignedRequest = required
+	return b
+}
+
+// WithTLSClientAuth sets the certificate subject the client authenticates with when using tls_client_auth.
+func (b *OauthClientBuilder) WithTLSClientAuth(subject authmethodtype.CertificateSubject) *OauthClientBuilder {
+	b.tlsClientAuth = subject
+	return b
+}
+
+// WithTokenEncryption sets the algorithms the client's ID tokens and access tokens are encrypted with.
+func (b *OauthClientBuilder) WithTokenEncryption(encryption encryptiontype.TokenEncryption) *OauthClientBuilder {
+	b.tokenEncryption = encryption
+	return b
+}
+
+// WithTokenSigning sets the algorithms the client's ID tokens and access tokens are signed with.
+func (b *OauthClientBuilder) WithTokenSigning(signing signingtype.TokenSigning) *OauthClientBuilder {
+	b.tokenSigning = signing
+	return b
+}
+
+// UsesMutualTLS reports whether the client authenticates with a TLS client certificate instead of a secret.
+func (c *OauthClient) UsesMutualTLS() bool {
+	return authmethodtype.IsMutualTLS(authmethodtype.TokenEndpointAuthMethod(c.TokenEndpointAuthMethod))
+}
+
+// IsPublic reports whether the client cannot keep credentials confidential and does not authenticate at all.
+func (c *OauthClient) IsPublic() bool {
+	return authmethodtype.TokenEndpointAuthMethod(c.TokenEndpointAuthMethod) == authmethodtype.None
+}
+
+// UsesClientAssertion reports whether the client authenticates with a signed JWT client assertion.
+func (c *OauthClient) UsesClientAssertion() bool {
+	return authmethodtype.IsJWTAssertion(authmethodtype.TokenEndpointAuthMethod(c.TokenEndpointAuthMethod))
+}
+
+// HasKeys reports whether the client registered public keys to verify its signed assertions.
+func (c *OauthClient) HasKeys() bool {
+	return c.Jwks != "" || c.JwksUri != ""
+}
+
 // Build constructs the OauthClient object.
 func (b *OauthClientBuilder) Build() *OauthClient {
 	if b.clientID == "" {
@@ -137,6 +276,7 @@ func (b *OauthClientBuilder) Build() *OauthClient {
 		ClientId:                b.clientID,
 		ClientName:              b.clientName,
 		ClientSecret:            b.clientSecret,
+		SealedClientSecret:      b.sealedClientSecret,
 		ResponseTypes:           responsetype.EnumListToStringList(b.responseTypes),
 		GrantTypes:              granttype.EnumListToStringList(b.grantTypes),
 		TokenEndpointAuthMethod: string(b.tokenEndpointAuthMethod),
@@ -146,5 +286,17 @@ func (b *OauthClientBuilder) Build() *OauthClient {
 		Confidential:            b.confidential,
 		ClientSecretExpiresAt:   b.clientSecretExpiresAt,
 		Scopes:                  b.scopes,
+		Jwks:                    b.jwks,
+		JwksUri:                 b.jwksUri,
+
+		TokenExchangeSubjectClients: b.tokenExchangeSubjects,
+		TokenExchangeAudiences:      b.tokenExchangeAudiences,
+		RequestUris:                 b.requestUris,
+
+		RequirePushedAuthorizationRequests: b.requirePAR,
+		RequireSignedRequestObject:         b.requireSignedRequest,
+		TLSClientAuth:                      b.tlsClientAuth,
+		TokenEncryption:                    b.tokenEncryption,
+		TokenSigning:                       b.tokenSigning,
 	}
 }
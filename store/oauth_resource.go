@@ -2,12 +2,29 @@ package store
 
 import "time"
 
+// OauthResource is a protected resource access tokens can be restricted to with resource indicators (RFC 8707).
+// Tokens issued for a resource carry its Uri as audience and only the scopes it owns.
 type OauthResource struct {
-	ResourceId  string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	Name        string    `gorm:"type:varchar(255);unique;not null"`
-	Description string    `gorm:"type:text;not null"`
-	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ResourceId  string `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Uri         string `gorm:"type:varchar(255);unique;not null"` // Absolute URI clients name the resource by (RFC 8707)
+	Name        string `gorm:"type:varchar(255);unique;not null"`
+	Description string `gorm:"type:text;not null"`
+	// AccessTokenEncryptedResponseAlg and AccessTokenEncryptedResponseEnc make access tokens issued for the resource
+	// encrypted, to the encryption key in Jwks or, without one, to the server's own key so that only introspection
+	// can read them
+	AccessTokenEncryptedResponseAlg string    `gorm:"type:varchar(255)"`
+	AccessTokenEncryptedResponseEnc string    `gorm:"type:varchar(255)"`
+	Jwks                            string    `gorm:"type:text"` // Inline JWK Set with the resource server's public keys
+	CreatedAt                       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt                       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+
+	// ClientId is the client the resource server authenticates as, which may introspect tokens issued for the resource
+	ClientId *string `gorm:"index"`
 
 	Scopes []Scope `gorm:"many2many:oauth_resource_scopes;foreignKey:ResourceId;joinForeignKey:ResourceId;References:Id;JoinReferences:ScopeId"`
 }
+
+// EncryptsAccessTokens reports whether access tokens issued for the resource must be encrypted.
+func (r *OauthResource) EncryptsAccessTokens() bool {
+	return r.AccessTokenEncryptedResponseAlg != ""
+}
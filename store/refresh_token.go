@@ -1,24 +1,48 @@
 package store
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type RefreshToken struct {
 	Id            string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	Token         string    `gorm:"type:text;unique;not null"`
+	Token         string    `gorm:"type:text;unique;not null"` // SHA-256 of the refresh token, see HashRefreshToken
 	TokenType     string    `gorm:"type:varchar(255);not null"`
 	ExpiresAt     time.Time `gorm:"not null"`
 	CreatedAt     time.Time `gorm:"default:now()"`
 	AccessTokenId string    `gorm:"index;not null;constraint:OnDelete:CASCADE"`
 	ClientId      *string   `gorm:"index"`
 	UserId        *string   `gorm:"index"`
-	AccessToken   *AccessToken
-	Client        *OauthClient
-	User          *User
-	Scopes        []Scope `gorm:"many2many:refresh_token_scopes;"`
+	Jkt           string    `gorm:"type:varchar(255)"` // Thumbprint of the DPoP key the token is bound to, if any
+	// AuthorizationDetails is the JSON array of the authorization details (RFC 9396) of the grant, which access
+	// tokens issued with the refresh token may narrow but not extend
+	AuthorizationDetails string `gorm:"type:text"`
+	// Resources are the resource indicators (RFC 8707) of the grant, which access tokens issued with the refresh
+	// token may narrow but not extend
+	Resources   pq.StringArray `gorm:"type:text[]"`
+	AccessToken *AccessToken
+	Client      *OauthClient
+	User        *User
+	Scopes      []Scope `gorm:"many2many:refresh_token_scopes;"`
+
+	// FamilyId identifies the tokens descending from one grant through refresh token rotation. The access tokens
+	// issued along the way carry it as well, so that the whole family can be revoked at once.
+	FamilyId string `gorm:"type:varchar(255);index"`
+	// UsedAt is when the token was rotated. Used tokens are kept until they expire so that presenting one again,
+	// which means it was stolen, can be detected.
+	UsedAt *time.Time
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 of a refresh token, which is what is stored and looked up, so that
+// the database never holds refresh tokens that could be used.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // IsExpired checks if the refresh token has expired
@@ -26,27 +50,37 @@ func (r *RefreshToken) IsExpired() bool {
 	return time.Now().After(r.ExpiresAt)
 }
 
+// IsUsed checks if the refresh token has already been rotated
+func (r *RefreshToken) IsUsed() bool {
+	return r.UsedAt != nil
+}
+
 type RefreshTokenBuilder struct {
-	id            string
-	token         string
-	tokenType     string
-	expiresAt     time.Time
-	createdAt     time.Time
-	client        *OauthClient
-	clientId      *string
-	accessToken   *AccessToken
-	accessTokenId string
-	user          *User
-	userId        *string
-	scopes        []Scope
+	id                   string
+	token                string
+	tokenType            string
+	expiresAt            time.Time
+	createdAt            time.Time
+	client               *OauthClient
+	clientId             *string
+	accessToken          *AccessToken
+	accessTokenId        string
+	user                 *User
+	userId               *string
+	scopes               []Scope
+	jkt                  string
+	authorizationDetails string
+	resources            []string
+	familyId             string
 }
 
 func NewRefreshTokenBuilder() *RefreshTokenBuilder {
 	return &RefreshTokenBuilder{}
 }
 
+// WithToken sets the refresh token handed out to the client; only its hash is kept.
 func (b *RefreshTokenBuilder) WithToken(token string) *RefreshTokenBuilder {
-	b.token = token
+	b.token = HashRefreshToken(token)
 	return b
 }
 
@@ -111,19 +145,47 @@ func (b *RefreshTokenBuilder) WithScopes(scopes []Scope) *RefreshTokenBuilder {
 	return b
 }
 
+// WithJkt binds the token to the DPoP key with the given thumbprint.
+func (b *RefreshTokenBuilder) WithJkt(jkt string) *RefreshTokenBuilder {
+	b.jkt = jkt
+	return b
+}
+
+// WithAuthorizationDetails sets the JSON encoded authorization details of the grant.
+func (b *RefreshTokenBuilder) WithAuthorizationDetails(authorizationDetails string) *RefreshTokenBuilder {
+	b.authorizationDetails = authorizationDetails
+	return b
+}
+
+// WithResources sets the resource indicators of the grant.
+func (b *RefreshTokenBuilder) WithResources(resources []string) *RefreshTokenBuilder {
+	b.resources = resources
+	return b
+}
+
+// WithFamilyId sets the refresh token family the token belongs to.
+func (b *RefreshTokenBuilder) WithFamilyId(familyId string) *RefreshTokenBuilder {
+	b.familyId = familyId
+	return b
+}
+
 func (b *RefreshTokenBuilder) Build() *RefreshToken {
 	return &RefreshToken{
-		Id:            uuid.New().String(),
-		Token:         b.token,
-		TokenType:     b.tokenType,
-		ExpiresAt:     b.expiresAt,
-		Client:        b.client,
-		ClientId:      b.clientId,
-		AccessToken:   b.accessToken,
-		AccessTokenId: b.accessTokenId,
-		User:          b.user,
-		UserId:        b.userId,
-		CreatedAt:     time.Now(),
-		Scopes:        b.scopes,
+		Id:                   uuid.New().String(),
+		Token:                b.token,
+		TokenType:            b.tokenType,
+		ExpiresAt:            b.expiresAt,
+		Client:               b.client,
+		ClientId:             b.clientId,
+		AccessToken:          b.accessToken,
+		AccessTokenId:        b.accessTokenId,
+		User:                 b.user,
+		UserId:               b.userId,
+		CreatedAt:            time.Now(),
+		Scopes:               b.scopes,
+		Jkt:                  b.jkt,
+		AuthorizationDetails: b.authorizationDetails,
+		Resources:            b.resources,
+		FamilyId:             b.familyId,
 	}
 }
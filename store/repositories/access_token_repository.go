@@ -62,15 +62,15 @@ func (ot *accessTokenRepository) Save(token *store.AccessToken) (*store.AccessTo
 }
 
 func (ot *accessTokenRepository) FindByAccessToken(accessToken string) (*store.AccessToken, error) {
-	ot.logger.Info("Attempting to find access token", zap.String("accessToken", accessToken))
+	ot.logger.Info("Attempting to find access token")
 	var token store.AccessToken
 
-	if err := ot.Db.Where("access_token = ?", accessToken).First(&token).Error; err != nil {
+	if err := ot.Db.Preload("Scopes").Preload("User").Where("token = ?", accessToken).First(&token).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ot.logger.Debug("Access token not found", zap.String("accessToken", accessToken))
+			ot.logger.Debug("Access token not found")
 			return nil, fmt.Errorf("access token not found")
 		}
-		ot.logger.Error("Failed to find access token in database", zap.String("accessToken", accessToken), zap.Error(err))
+		ot.logger.Error("Failed to find access token in database", zap.Error(err))
 		return nil, fmt.Errorf("failed to find access token: %w", err)
 	}
 	ot.logger.Debug("Access token found", zap.String("accessTokenId", token.Id))
@@ -85,17 +85,17 @@ func (ot *accessTokenRepository) FindByAccessToken(accessToken string) (*store.A
 }
 
 func (ot *accessTokenRepository) DeleteByAccessToken(accessToken string) error {
-	ot.logger.Info("Attempting to delete access token", zap.String("accessToken", accessToken))
-	result := ot.Db.Where("access_token = ?", accessToken).Delete(&store.AccessToken{})
+	ot.logger.Info("Attempting to delete access token")
+	result := ot.Db.Where("token = ?", accessToken).Delete(&store.AccessToken{})
 	if result.Error != nil {
-		ot.logger.Error("Failed to delete access token from database", zap.String("accessToken", accessToken), zap.Error(result.Error))
+		ot.logger.Error("Failed to delete access token from database", zap.Error(result.Error))
 		return fmt.Errorf("failed to delete access token: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		ot.logger.Info("No access token found to delete", zap.String("accessToken", accessToken))
+		ot.logger.Info("No access token found to delete")
 	} else {
-		ot.logger.Info("Access token deleted successfully", zap.String("accessToken", accessToken))
+		ot.logger.Info("Access token deleted successfully", zap.Int64("rowsAffected", result.RowsAffected))
 	}
 	// If no rows were affected, it means the token was not found, but we don't return an error as per RFC 7009
 	return nil
@@ -51,8 +51,8 @@ func (r *authCodeRepository) FindByCode(code string) (*store.AuthCode, error) {
 	// Initialize a new AuthCode entity
 	authCode := new(store.AuthCode)
 
-	// Query the database for the code
-	result := r.Db.Where("code = ?", code).First(authCode)
+	// Query the database for the code, along with the scopes granted to it
+	result := r.Db.Preload("Scopes").Where("code = ?", code).First(authCode)
 
 	// Handle errors during the query
 	if result.Error != nil {
@@ -87,14 +87,22 @@ func (r *authCodeRepository) Delete(code string) error {
 	)
 	r.logger.Debug("Executing database delete operation for AuthCode")
 
-	result := r.Db.Where("code = ?", code).Delete(&store.AuthCode{})
-	if result.Error != nil {
+	var result *gorm.DB
+	err := r.Db.Transaction(func(tx *gorm.DB) error {
+		// Remove the granted scopes first so the join rows do not block the delete
+		if err := tx.Exec("DELETE FROM auth_code_scopes WHERE auth_code_id IN (SELECT id FROM auth_codes WHERE code = ?)", code).Error; err != nil {
+			return err
+		}
+		result = tx.Where("code = ?", code).Delete(&store.AuthCode{})
+		return result.Error
+	})
+	if err != nil {
 		r.logger.Error("Error deleting AuthCode from database",
 			zap.String("code", code),
-			zap.Error(result.Error),
+			zap.Error(err),
 			zap.Stack("stacktrace"),
 		)
-		return fmt.Errorf("failed to delete AuthCode: %w", result.Error)
+		return fmt.Errorf("failed to delete AuthCode: %w", err)
 	}
 
 	if result.RowsAffected == 0 {
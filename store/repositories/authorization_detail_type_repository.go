@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type authorizationDetailTypeRepository struct {
+	Db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewAuthorizationDetailTypeRepository initializes a new AuthorizationDetailTypeRepository
+func NewAuthorizationDetailTypeRepository(db *gorm.DB, logger *zap.Logger) AuthorizationDetailTypeRepository {
+	return &authorizationDetailTypeRepository{
+		Db:     db,
+		logger: logger,
+	}
+}
+
+// FindByType retrieves the registered AuthorizationDetailType with the given type identifier
+func (r *authorizationDetailTypeRepository) FindByType(detailType string) (*store.AuthorizationDetailType, error) {
+	r.logger.Info("Searching for authorization detail type", zap.String("type", detailType))
+
+	authorizationDetailType := new(store.AuthorizationDetailType)
+	result := r.Db.Where("type = ?", detailType).First(authorizationDetailType)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			r.logger.Debug("Authorization detail type not found", zap.String("type", detailType))
+			return nil, fmt.Errorf("authorization detail type not found: %w", result.Error)
+		}
+		r.logger.Error("Error finding authorization detail type in database",
+			zap.String("type", detailType),
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return nil, fmt.Errorf("error finding authorization detail type: %w", result.Error)
+	}
+
+	r.logger.Info("Successfully found authorization detail type", zap.String("type", detailType))
+	return authorizationDetailType, nil
+}
+
+// FindAllTypes returns the identifiers of every registered authorization detail type
+func (r *authorizationDetailTypeRepository) FindAllTypes() ([]string, error) {
+	var types []string
+	if err := r.Db.Model(&store.AuthorizationDetailType{}).Order("type").Pluck("type", &types).Error; err != nil {
+		r.logger.Error("Error listing authorization detail types", zap.Error(err))
+		return nil, fmt.Errorf("error listing authorization detail types: %w", err)
+	}
+	return types, nil
+}
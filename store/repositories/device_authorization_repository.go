@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type deviceAuthorizationRepository struct {
+	Db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewDeviceAuthorizationRepository initializes a new DeviceAuthorizationRepository
+func NewDeviceAuthorizationRepository(db *gorm.DB, logger *zap.Logger) DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{
+		Db:     db,
+		logger: logger,
+	}
+}
+
+// Save saves a DeviceAuthorization to the database
+func (r *deviceAuthorizationRepository) Save(deviceAuthorization *store.DeviceAuthorization) (*store.DeviceAuthorization, error) {
+	r.logger.Info("Attempting to save device authorization",
+		zap.String("userCode", deviceAuthorization.UserCode),
+		zap.String("clientId", utils.StringDeref(deviceAuthorization.ClientId)),
+	)
+
+	if err := r.Db.Create(deviceAuthorization).Error; err != nil {
+		r.logger.Error("Error saving DeviceAuthorization to database",
+			zap.String("userCode", deviceAuthorization.UserCode),
+			zap.String("clientId", utils.StringDeref(deviceAuthorization.ClientId)),
+			zap.Error(err),
+			zap.Stack("stacktrace"),
+		)
+		return nil, fmt.Errorf("failed to save DeviceAuthorization: %w", err)
+	}
+	r.logger.Info("DeviceAuthorization saved successfully", zap.String("id", deviceAuthorization.Id))
+	return deviceAuthorization, nil
+}
+
+// FindByDeviceCode retrieves a DeviceAuthorization using the device code polled by the client
+func (r *deviceAuthorizationRepository) FindByDeviceCode(deviceCode string) (*store.DeviceAuthorization, error) {
+	r.logger.Info("Searching for DeviceAuthorization by device code")
+	return r.findBy("device_code = ?", deviceCode)
+}
+
+// FindByUserCode retrieves a DeviceAuthorization using the user code entered on the verification page
+func (r *deviceAuthorizationRepository) FindByUserCode(userCode string) (*store.DeviceAuthorization, error) {
+	r.logger.Info("Searching for DeviceAuthorization by user code", zap.String("userCode", userCode))
+	return r.findBy("user_code = ?", userCode)
+}
+
+func (r *deviceAuthorizationRepository) findBy(query string, value string) (*store.DeviceAuthorization, error) {
+	deviceAuthorization := new(store.DeviceAuthorization)
+
+	result := r.Db.Where(query, value).First(deviceAuthorization)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			r.logger.Debug("DeviceAuthorization not found in database", zap.Error(result.Error))
+			return nil, fmt.Errorf("DeviceAuthorization not found: %w", result.Error)
+		}
+		r.logger.Error("Error finding DeviceAuthorization in database",
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return nil, fmt.Errorf("error finding DeviceAuthorization: %w", result.Error)
+	}
+
+	r.logger.Info("Successfully found DeviceAuthorization",
+		zap.String("id", deviceAuthorization.Id),
+		zap.String("status", deviceAuthorization.Status),
+	)
+	return deviceAuthorization, nil
+}
+
+// Update persists the status, user and polling state of a DeviceAuthorization
+func (r *deviceAuthorizationRepository) Update(deviceAuthorization *store.DeviceAuthorization) error {
+	r.logger.Info("Updating DeviceAuthorization",
+		zap.String("id", deviceAuthorization.Id),
+		zap.String("status", deviceAuthorization.Status),
+	)
+
+	result := r.Db.Model(&store.DeviceAuthorization{}).
+		Where("id = ?", deviceAuthorization.Id).
+		Updates(map[string]interface{}{
+			"status":         deviceAuthorization.Status,
+			"user_id":        deviceAuthorization.UserId,
+			"interval":       deviceAuthorization.Interval,
+			"last_polled_at": deviceAuthorization.LastPolledAt,
+		})
+	if result.Error != nil {
+		r.logger.Error("Error updating DeviceAuthorization",
+			zap.String("id", deviceAuthorization.Id),
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return fmt.Errorf("failed to update DeviceAuthorization: %w", result.Error)
+	}
+	return nil
+}
+
+// Delete removes a DeviceAuthorization once its device code has been redeemed. It reports whether the
+// DeviceAuthorization was still there, so that of two concurrent redemptions only one succeeds.
+func (r *deviceAuthorizationRepository) Delete(deviceCode string) (bool, error) {
+	r.logger.Info("Attempting to delete DeviceAuthorization")
+
+	result := r.Db.Where("device_code = ?", deviceCode).Delete(&store.DeviceAuthorization{})
+	if result.Error != nil {
+		r.logger.Error("Error deleting DeviceAuthorization from database",
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return false, fmt.Errorf("failed to delete DeviceAuthorization: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		r.logger.Warn("DeviceAuthorization not found for deletion")
+		return false, nil
+	}
+	r.logger.Info("DeviceAuthorization deleted successfully", zap.Int64("rowsAffected", result.RowsAffected))
+	return true, nil
+}
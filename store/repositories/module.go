@@ -13,5 +13,9 @@ var Module = fx.Options(
 		NewScopeRepository,
 		NewAuthCodeRepository,
 		NewUserRepository,
+		NewDeviceAuthorizationRepository,
+		NewTrustedIssuerRepository,
+		NewAuthorizationDetailTypeRepository,
+		NewOauthResourceRepository,
 	),
 )
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type oauthResourceRepository struct {
+	Db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewOauthResourceRepository initializes a new OauthResourceRepository
+func NewOauthResourceRepository(db *gorm.DB, logger *zap.Logger) OauthResourceRepository {
+	return &oauthResourceRepository{
+		Db:     db,
+		logger: logger,
+	}
+}
+
+// FindByUri retrieves the registered OauthResource identified by the given URI, together with the scopes it owns
+func (r *oauthResourceRepository) FindByUri(uri string) (*store.OauthResource, error) {
+	r.logger.Info("Searching for resource", zap.String("uri", uri))
+
+	resource := new(store.OauthResource)
+	result := r.Db.Preload("Scopes").Where("uri = ?", uri).First(resource)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			r.logger.Debug("Resource not found", zap.String("uri", uri))
+			return nil, fmt.Errorf("resource not found: %w", result.Error)
+		}
+		r.logger.Error("Error finding resource in database",
+			zap.String("uri", uri),
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return nil, fmt.Errorf("error finding resource: %w", result.Error)
+	}
+
+	r.logger.Info("Successfully found resource", zap.String("uri", uri), zap.String("resourceId", resource.ResourceId))
+	return resource, nil
+}
+
+// FindByClientId retrieves the resources served by the resource server that authenticates as the given client
+func (r *oauthResourceRepository) FindByClientId(clientId string) ([]store.OauthResource, error) {
+	r.logger.Info("Searching for resources of client", zap.String("clientId", clientId))
+
+	var resources []store.OauthResource
+	result := r.Db.Where("client_id = ?", clientId).Find(&resources)
+	if result.Error != nil {
+		r.logger.Error("Error finding resources of client in database",
+			zap.String("clientId", clientId),
+			zap.Error(result.Error),
+		)
+		return nil, fmt.Errorf("error finding resources of client: %w", result.Error)
+	}
+
+	r.logger.Info("Successfully found resources of client", zap.String("clientId", clientId), zap.Int("count", len(resources)))
+	return resources, nil
+}
@@ -20,39 +20,53 @@ func NewRefreshTokenRepository(db *gorm.DB, logger *zap.Logger) RefreshTokenRepo
 	return &refreshTokenRepository{Db: db, logger: logger}
 }
 
-func (ot *refreshTokenRepository) InvalidateRefreshTokensByAccessTokenId(accessTokenId string) error {
-	ot.logger.Info("Starting transaction to invalidate refresh tokens by access token ID", zap.String("accessTokenId", accessTokenId))
+// Rotate marks a refresh token as used and saves the one replacing it in a single transaction, so that a token is
+// never used up without a replacement. It reports false, saving nothing, when the token had already been used: of two
+// concurrent requests presenting the same token only one can rotate it.
+func (ot *refreshTokenRepository) Rotate(usedRefreshTokenId string, replacement *store.RefreshToken) (*store.RefreshToken, bool, error) {
+	ot.logger.Info("Starting transaction to rotate refresh token", zap.String("refreshTokenId", usedRefreshTokenId))
 
 	tx := ot.Db.Begin()
 	if tx.Error != nil {
-		ot.logger.Error("Failed to begin transaction for invalidating refresh tokens", zap.String("accessTokenId", accessTokenId), zap.Error(tx.Error))
-		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+		ot.logger.Error("Failed to begin transaction for rotating refresh token", zap.String("refreshTokenId", usedRefreshTokenId), zap.Error(tx.Error))
+		return nil, false, fmt.Errorf("failed to start transaction: %w", tx.Error)
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			ot.logger.Error("PANIC: Rolled back transaction for access token ID", zap.String("accessTokenId", accessTokenId), zap.Any("panicReason", r), zap.Stack("stacktrace"))
+			ot.logger.Error("PANIC: Rolled back transaction for refresh token ID", zap.String("refreshTokenId", usedRefreshTokenId), zap.Any("panicReason", r), zap.Stack("stacktrace"))
 		}
 	}()
 
-	ot.logger.Debug("Deleting refresh tokens associated with access token ID", zap.String("accessTokenId", accessTokenId))
-	result := tx.Unscoped().Where("access_token_id = ?", accessTokenId).Delete(new(store.RefreshToken))
+	result := tx.Model(&store.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", usedRefreshTokenId).
+		Update("used_at", time.Now())
 	if result.Error != nil {
-		ot.logger.Error("ERROR: Failed to delete refresh tokens by access token ID", zap.String("accessTokenId", accessTokenId), zap.Error(result.Error))
+		ot.logger.Error("Failed to mark refresh token as used", zap.String("refreshTokenId", usedRefreshTokenId), zap.Error(result.Error))
 		tx.Rollback()
-		return fmt.Errorf("failed to delete refresh token: %w", result.Error)
+		return nil, false, fmt.Errorf("failed to mark refresh token as used: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		ot.logger.Warn("Refresh token was already used", zap.String("refreshTokenId", usedRefreshTokenId))
+		tx.Rollback()
+		return nil, false, nil
+	}
+
+	if err := tx.Create(replacement).Error; err != nil {
+		ot.logger.Error("Error creating replacement refresh token", zap.String("refreshTokenId", usedRefreshTokenId), zap.Error(err))
+		tx.Rollback()
+		return nil, false, fmt.Errorf("failed to create refresh token: %w", err)
 	}
-	ot.logger.Info("Successfully deleted refresh tokens", zap.String("accessTokenId", accessTokenId), zap.Int64("rowsAffected", result.RowsAffected))
 
 	if err := tx.Commit().Error; err != nil {
-		ot.logger.Error("Error committing transaction for invalidating refresh tokens", zap.String("accessTokenId", accessTokenId), zap.Error(err))
+		ot.logger.Error("Error committing transaction for rotated refresh token", zap.String("refreshTokenId", usedRefreshTokenId), zap.Error(err))
 		tx.Rollback()
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	ot.logger.Info("Transaction committed successfully for invalidating refresh tokens", zap.String("accessTokenId", accessTokenId))
-	return nil
+	ot.logger.Info("Refresh token rotated", zap.String("refreshTokenId", usedRefreshTokenId), zap.String("replacementId", replacement.Id))
+	return replacement, true, nil
 }
 
 func (ot *refreshTokenRepository) Save(token *store.RefreshToken) (*store.RefreshToken, error) {
@@ -92,26 +106,26 @@ func (ot *refreshTokenRepository) Save(token *store.RefreshToken) (*store.Refres
 
 // FindByToken retrieves a refresh token from the database using the token string.
 func (ot *refreshTokenRepository) FindByToken(token string) (*store.RefreshToken, error) {
-	ot.logger.Info("Searching for refresh token", zap.String("refreshToken", token))
-	ot.logger.Debug("Executing database query to find refresh token by token string")
+	ot.logger.Info("Searching for refresh token")
+	ot.logger.Debug("Executing database query to find refresh token by token hash")
 
 	// Initialize a new RefreshToken entity
 	refreshToken := new(store.RefreshToken)
 
-	// Query the database for the token
-	result := ot.Db.Where("token = ?", token).First(refreshToken)
+	// Query the database for the token, which is stored hashed
+	result := ot.Db.Where("token = ?", store.HashRefreshToken(token)).First(refreshToken)
 
 	// Handle errors during the query
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			ot.logger.Debug("Refresh token not found in database", zap.String("refreshToken", token))
+			ot.logger.Debug("Refresh token not found in database")
 			return nil, fmt.Errorf("RefreshToken not found or invalidated: %w", result.Error)
 		}
-		ot.logger.Error("Error finding Refresh Token in database", zap.String("refreshToken", token), zap.Error(result.Error))
+		ot.logger.Error("Error finding Refresh Token in database", zap.Error(result.Error))
 		return nil, fmt.Errorf("error finding Refresh Token: %w", result.Error)
 	}
 
-	ot.logger.Info("Successfully found refresh token", zap.String("refreshToken", token), zap.String("refreshTokenId", refreshToken.Id))
+	ot.logger.Info("Successfully found refresh token", zap.String("refreshTokenId", refreshToken.Id))
 	ot.logger.Debug("Found Refresh Token details", zap.Any("refreshTokenEntity", refreshToken))
 
 	return refreshToken, nil
@@ -119,15 +133,15 @@ func (ot *refreshTokenRepository) FindByToken(token string) (*store.RefreshToken
 
 // FindByRefreshToken retrieves a refresh token from the database using the token string.
 func (ot *refreshTokenRepository) FindByRefreshToken(refreshToken string) (*store.RefreshToken, error) {
-	ot.logger.Info("Searching for refresh token by refresh token string", zap.String("refreshToken", refreshToken))
+	ot.logger.Info("Searching for refresh token by refresh token string")
 	var token store.RefreshToken
 
-	if err := ot.Db.Where("token = ?", refreshToken).First(&token).Error; err != nil {
+	if err := ot.Db.Preload("Scopes").Preload("User").Where("token = ?", store.HashRefreshToken(refreshToken)).First(&token).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ot.logger.Debug("Refresh token not found", zap.String("refreshToken", refreshToken))
+			ot.logger.Debug("Refresh token not found")
 			return nil, fmt.Errorf("refresh token not found")
 		}
-		ot.logger.Error("Failed to find refresh token in database", zap.String("refreshToken", refreshToken), zap.Error(err))
+		ot.logger.Error("Failed to find refresh token in database", zap.Error(err))
 		return nil, fmt.Errorf("failed to find refresh token: %w", err)
 	}
 	ot.logger.Debug("Refresh token found", zap.String("refreshTokenId", token.Id))
@@ -142,18 +156,71 @@ func (ot *refreshTokenRepository) FindByRefreshToken(refreshToken string) (*stor
 }
 
 func (ot *refreshTokenRepository) DeleteByRefreshToken(refreshToken string) error {
-	ot.logger.Info("Attempting to delete refresh token by refresh token string", zap.String("refreshToken", refreshToken))
-	result := ot.Db.Where("refresh_token = ?", refreshToken).Delete(&store.RefreshToken{})
+	ot.logger.Info("Attempting to delete refresh token by refresh token string")
+	result := ot.Db.Where("token = ?", store.HashRefreshToken(refreshToken)).Delete(&store.RefreshToken{})
 	if result.Error != nil {
-		ot.logger.Error("Failed to delete refresh token from database", zap.String("refreshToken", refreshToken), zap.Error(result.Error))
+		ot.logger.Error("Failed to delete refresh token from database", zap.Error(result.Error))
 		return fmt.Errorf("failed to delete refresh token: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		ot.logger.Info("No refresh token found to delete", zap.String("refreshToken", refreshToken))
+		ot.logger.Info("No refresh token found to delete")
 	} else {
-		ot.logger.Info("Refresh token deleted successfully", zap.String("refreshToken", refreshToken), zap.Int64("rowsAffected", result.RowsAffected))
+		ot.logger.Info("Refresh token deleted successfully", zap.Int64("rowsAffected", result.RowsAffected))
 	}
 	// If no rows were affected, it means the token was not found, but we don't return an error as per RFC 7009
 	return nil
 }
+
+// DeleteFamily deletes a refresh token together with the rest of its family and the access tokens issued in it.
+// Tokens issued before families were tracked only take the access token they were issued with.
+func (ot *refreshTokenRepository) DeleteFamily(refreshToken *store.RefreshToken) error {
+	ot.logger.Info("Starting transaction to delete refresh token family",
+		zap.String("refreshTokenId", refreshToken.Id), zap.String("familyId", refreshToken.FamilyId))
+
+	err := ot.Db.Transaction(func(tx *gorm.DB) error {
+		result := familyMembers(tx, refreshToken.Id, refreshToken.FamilyId).Delete(&store.RefreshToken{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete refresh tokens: %w", result.Error)
+		}
+		ot.logger.Debug("Deleted refresh tokens of family", zap.String("familyId", refreshToken.FamilyId), zap.Int64("rowsAffected", result.RowsAffected))
+
+		result = familyMembers(tx, refreshToken.AccessTokenId, refreshToken.FamilyId).Delete(&store.AccessToken{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete access tokens: %w", result.Error)
+		}
+		ot.logger.Debug("Deleted access tokens of family", zap.String("familyId", refreshToken.FamilyId), zap.Int64("rowsAffected", result.RowsAffected))
+		return nil
+	})
+	if err != nil {
+		ot.logger.Error("Failed to delete refresh token family", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
+		return err
+	}
+
+	ot.logger.Info("Refresh token family deleted successfully", zap.String("refreshTokenId", refreshToken.Id), zap.String("familyId", refreshToken.FamilyId))
+	return nil
+}
+
+// FindFamilyAccessTokens retrieves the access tokens DeleteFamily deletes along with a refresh token.
+func (ot *refreshTokenRepository) FindFamilyAccessTokens(refreshToken *store.RefreshToken) ([]store.AccessToken, error) {
+	ot.logger.Info("Searching for access tokens of refresh token family",
+		zap.String("refreshTokenId", refreshToken.Id), zap.String("familyId", refreshToken.FamilyId))
+
+	var accessTokens []store.AccessToken
+	if err := familyMembers(ot.Db, refreshToken.AccessTokenId, refreshToken.FamilyId).Find(&accessTokens).Error; err != nil {
+		ot.logger.Error("Failed to find access tokens of refresh token family", zap.String("refreshTokenId", refreshToken.Id), zap.Error(err))
+		return nil, fmt.Errorf("failed to find access tokens of family: %w", err)
+	}
+
+	ot.logger.Debug("Found access tokens of refresh token family", zap.String("familyId", refreshToken.FamilyId), zap.Int("count", len(accessTokens)))
+	return accessTokens, nil
+}
+
+// familyMembers scopes a query to the token with the given ID and, when familyId is set, the rest of its family.
+func familyMembers(db *gorm.DB, id, familyId string) *gorm.DB {
+	query := db.Where("id = ?", id)
+	if familyId != "" {
+		query = query.Or("family_id = ?", familyId)
+	}
+	return query
+}
@@ -26,8 +26,10 @@ type ScopeRepository interface {
 type RefreshTokenRepository interface {
 	Save(token *store.RefreshToken) (*store.RefreshToken, error)
 	FindByRefreshToken(token string) (*store.RefreshToken, error)
-	InvalidateRefreshTokensByAccessTokenId(tokenId string) error
+	Rotate(usedRefreshTokenId string, replacement *store.RefreshToken) (*store.RefreshToken, bool, error)
 	DeleteByRefreshToken(refreshToken string) error
+	DeleteFamily(refreshToken *store.RefreshToken) error
+	FindFamilyAccessTokens(refreshToken *store.RefreshToken) ([]store.AccessToken, error)
 }
 
 type AccessConsentRepository interface {
@@ -45,4 +47,27 @@ type UserRepository interface {
 	Save(authCode *store.User) (*store.User, error)
 	FindByUserId(id string) (*store.User, error)
 	FindById(id string) (*store.User, error)
+	FindByEmail(email string) (*store.User, error)
+}
+
+type TrustedIssuerRepository interface {
+	FindByIssuer(issuer string) (*store.TrustedIssuer, error)
+}
+
+type OauthResourceRepository interface {
+	FindByUri(uri string) (*store.OauthResource, error)
+	FindByClientId(clientId string) ([]store.OauthResource, error)
+}
+
+type AuthorizationDetailTypeRepository interface {
+	FindByType(detailType string) (*store.AuthorizationDetailType, error)
+	FindAllTypes() ([]string, error)
+}
+
+type DeviceAuthorizationRepository interface {
+	Save(deviceAuthorization *store.DeviceAuthorization) (*store.DeviceAuthorization, error)
+	FindByDeviceCode(deviceCode string) (*store.DeviceAuthorization, error)
+	FindByUserCode(userCode string) (*store.DeviceAuthorization, error)
+	Update(deviceAuthorization *store.DeviceAuthorization) error
+	Delete(deviceCode string) (bool, error)
 }
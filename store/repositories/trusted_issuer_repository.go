@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type trustedIssuerRepository struct {
+	Db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewTrustedIssuerRepository initializes a new TrustedIssuerRepository
+func NewTrustedIssuerRepository(db *gorm.DB, logger *zap.Logger) TrustedIssuerRepository {
+	return &trustedIssuerRepository{
+		Db:     db,
+		logger: logger,
+	}
+}
+
+// FindByIssuer retrieves a TrustedIssuer by its iss identifier
+func (r *trustedIssuerRepository) FindByIssuer(issuer string) (*store.TrustedIssuer, error) {
+	r.logger.Info("Searching for trusted issuer", zap.String("issuer", issuer))
+
+	trustedIssuer := new(store.TrustedIssuer)
+	result := r.Db.Where("issuer = ?", issuer).First(trustedIssuer)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			r.logger.Debug("Trusted issuer not found", zap.String("issuer", issuer))
+			return nil, fmt.Errorf("trusted issuer not found: %w", result.Error)
+		}
+		r.logger.Error("Error finding trusted issuer in database",
+			zap.String("issuer", issuer),
+			zap.Error(result.Error),
+			zap.Stack("stacktrace"),
+		)
+		return nil, fmt.Errorf("error finding trusted issuer: %w", result.Error)
+	}
+
+	r.logger.Info("Successfully found trusted issuer", zap.String("issuer", issuer), zap.String("id", trustedIssuer.Id))
+	return trustedIssuer, nil
+}
@@ -93,3 +93,22 @@ func (r *userRepository) FindById(id string) (*store.User, error) {
 	r.logger.Debug("Found user details by ID", zap.Any("user", user))
 	return &user, nil
 }
+
+// FindByEmail retrieves a user by their email address, which doubles as the username for local credentials.
+func (r *userRepository) FindByEmail(email string) (*store.User, error) {
+	r.logger.Info("Finding user by email", zap.String("email", email))
+	r.logger.Debug("Executing database query to find user by email")
+
+	var user store.User
+	if err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.Debug("User not found for email", zap.String("email", email))
+			return nil, fmt.Errorf("user not found")
+		}
+		r.logger.Error("Error finding user by email in database", zap.String("email", email), zap.Error(err))
+		return nil, fmt.Errorf("failed to find user by email: %w", err)
+	}
+
+	r.logger.Info("User found successfully by email", zap.String("userID", user.Id), zap.String("email", user.Email))
+	return &user, nil
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"time"
+)
+
+// TrustedIssuer is an external party whose signed JWT assertions may be exchanged for access tokens (RFC 7523).
+// Assertions from the issuer are verified against its registered keys and tokens are minted for ClientId.
+type TrustedIssuer struct {
+	Id        string    `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Issuer    string    `gorm:"type:varchar(255);unique;not null"`
+	ClientId  *string   `gorm:"index;not null"`
+	Jwks      string    `gorm:"type:text"`         // Inline JWK Set document
+	JwksUri   string    `gorm:"type:varchar(255)"` // Location of the issuer's JWK Set document
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Client    *OauthClient
+}
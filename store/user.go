@@ -1,26 +1,57 @@
 package store
 
 import (
-	"github.com/google/uuid"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// LocalIdpName identifies users whose credentials are stored by this server rather than an external IdP.
+const LocalIdpName = "local"
+
 type User struct {
-	Id        string          `gorm:"primaryKey;type:varchar(255);unique;not null"`
-	Name      string          `gorm:"type:varchar(255);not null"`
-	Email     string          `gorm:"type:varchar(255);unique"`
-	IdpName   string          `gorm:"type:varchar(255);not null"`
-	CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
-	Consents  []AccessConsent `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Id           string          `gorm:"primaryKey;type:varchar(255);unique;not null"`
+	Name         string          `gorm:"type:varchar(255);not null"`
+	Email        string          `gorm:"type:varchar(255);unique"`
+	IdpName      string          `gorm:"type:varchar(255);not null"`
+	PasswordHash string          `gorm:"type:text"` // bcrypt hash, only set for local users
+	CreatedAt    time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+	Consents     []AccessConsent `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+}
+
+// unknownUserPasswordHash is a bcrypt hash, at the default cost, that passwords are compared with when there is no
+// local password to validate them against, so that the time taken does not reveal whether a user exists.
+const unknownUserPasswordHash = "$2a$10$hNm58xq8hXBDazJ/JLMpYex.a5yTpY69pvh.lXXazM//1VhMVPgYa"
+
+// ValidatePassword compares a plaintext password with the stored bcrypt hash.
+// Users federated from an external IdP have no local password and always fail validation.
+func (u *User) ValidatePassword(password string) error {
+	if u.PasswordHash == "" {
+		ValidateUnknownUserPassword(password)
+		return errors.New("user has no local credentials")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return fmt.Errorf("password validation failed: %w", err)
+	}
+	return nil
+}
+
+// ValidateUnknownUserPassword takes as long as validating a password does when there is no user to validate it for.
+func ValidateUnknownUserPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword([]byte(unknownUserPasswordHash), []byte(password))
 }
 
 // UserBuilder helps in constructing User instances with optional configurations.
 type UserBuilder struct {
-	id      string
-	name    string
-	email   string
-	idpName string
+	id           string
+	name         string
+	email        string
+	idpName      string
+	passwordHash string
 }
 
 // NewUserBuilder initializes a new UserBuilder.
@@ -52,6 +83,12 @@ func (b *UserBuilder) WithIdpName(idpName string) *UserBuilder {
 	return b
 }
 
+// WithPasswordHash sets the bcrypt password hash used for local credential validation.
+func (b *UserBuilder) WithPasswordHash(passwordHash string) *UserBuilder {
+	b.passwordHash = passwordHash
+	return b
+}
+
 // Build creates a new User instance using the builder's settings.
 func (b *UserBuilder) Build() *User {
 	if b.id == "" {
@@ -59,11 +96,12 @@ func (b *UserBuilder) Build() *User {
 	}
 
 	return &User{
-		Id:        b.id,
-		Name:      b.name,
-		Email:     b.email,
-		IdpName:   b.idpName,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+		Id:           b.id,
+		Name:         b.name,
+		Email:        b.email,
+		IdpName:      b.idpName,
+		PasswordHash: b.passwordHash,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
 	}
 }
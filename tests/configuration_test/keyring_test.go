@@ -0,0 +1,202 @@
+package configuration_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRotationInterval = 24 * time.Hour
+	testRetirementPeriod = 2 * time.Hour
+)
+
+// useTestKeyRotation configures ES256 keys, which are quick to generate, persisted to a temporary file, and restores
+// the previous configuration when the test ends.
+func useTestKeyRotation(t *testing.T) {
+	algorithms, file := configuration.SigningAlgorithms, configuration.SigningKeysFile
+	interval, retirement := configuration.SigningKeyRotationInterval, configuration.SigningKeyRetirementPeriod
+	t.Cleanup(func() {
+		configuration.SigningAlgorithms, configuration.SigningKeysFile = algorithms, file
+		configuration.SigningKeyRotationInterval, configuration.SigningKeyRetirementPeriod = interval, retirement
+	})
+
+	configuration.SigningAlgorithms = []string{signingtype.ES256}
+	configuration.SigningKeysFile = filepath.Join(t.TempDir(), "signing_keys.json")
+	configuration.SigningKeyRotationInterval = testRotationInterval
+	configuration.SigningKeyRetirementPeriod = testRetirementPeriod
+}
+
+// keyStates counts the keys of the ring by algorithm and state, e.g. "ES256 active".
+func keyStates(r *configuration.KeyRing) map[string]int {
+	states := map[string]int{}
+	for _, key := range r.Keys() {
+		states[key.Algorithm+" "+string(key.State)]++
+	}
+	return states
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate func(r *configuration.KeyRing, start time.Time) error
+		want   map[string]int
+	}{
+		{
+			name:   "initial keys",
+			rotate: func(*configuration.KeyRing, time.Time) error { return nil },
+			want:   map[string]int{"ES256 active": 1, "ES256 next": 1},
+		},
+		{
+			name: "rotation not yet due",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				_, err := r.RotateIfDue(start.Add(testRotationInterval / 2))
+				return err
+			},
+			want: map[string]int{"ES256 active": 1, "ES256 next": 1},
+		},
+		{
+			name: "scheduled rotation",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				_, err := r.RotateIfDue(start.Add(testRotationInterval))
+				return err
+			},
+			want: map[string]int{"ES256 active": 1, "ES256 next": 1, "ES256 retiring": 1},
+		},
+		{
+			name: "retired key dropped after the retirement period",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				rotatedAt := start.Add(testRotationInterval)
+				if _, err := r.RotateIfDue(rotatedAt); err != nil {
+					return err
+				}
+				_, err := r.RotateIfDue(rotatedAt.Add(testRetirementPeriod + time.Minute))
+				return err
+			},
+			want: map[string]int{"ES256 active": 1, "ES256 next": 1},
+		},
+		{
+			name: "manual rotation",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				return r.Rotate(start.Add(time.Minute))
+			},
+			want: map[string]int{"ES256 active": 1, "ES256 next": 1, "ES256 retiring": 1},
+		},
+		{
+			name: "emergency rotation",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				if err := r.Rotate(start.Add(time.Minute)); err != nil {
+					return err
+				}
+				return r.EmergencyRotate(start.Add(2 * time.Minute))
+			},
+			want: map[string]int{"ES256 active": 1, "ES256 next": 1, "ES256 retiring": 1, "ES256 revoked": 2},
+		},
+		{
+			name: "algorithm withdrawn",
+			rotate: func(r *configuration.KeyRing, start time.Time) error {
+				configuration.SigningAlgorithms = []string{signingtype.ES384}
+				_, err := r.RotateIfDue(start.Add(time.Minute))
+				return err
+			},
+			want: map[string]int{"ES256 retiring": 1, "ES384 active": 1, "ES384 next": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestKeyRotation(t)
+			start := time.Now()
+			ring := &configuration.KeyRing{}
+			changed, err := ring.RotateIfDue(start)
+			require.NoError(t, err)
+			require.True(t, changed)
+
+			require.NoError(t, tt.rotate(ring, start))
+
+			assert.Equal(t, tt.want, keyStates(ring))
+			assert.FileExists(t, configuration.SigningKeysFile)
+		})
+	}
+}
+
+func TestKeyRingVerificationKey(t *testing.T) {
+	useTestKeyRotation(t)
+	start := time.Now()
+	ring := &configuration.KeyRing{}
+	_, err := ring.RotateIfDue(start)
+	require.NoError(t, err)
+	retiring, err := ring.Active(signingtype.ES256)
+	require.NoError(t, err)
+	require.NoError(t, ring.Rotate(start.Add(time.Minute)))
+	revoked, err := ring.Active(signingtype.ES256)
+	require.NoError(t, err)
+	require.NoError(t, ring.EmergencyRotate(start.Add(2*time.Minute)))
+	active, err := ring.Active(signingtype.ES256)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		kid       string
+		wantErr   bool
+		published bool
+	}{
+		{name: "active key", kid: active.Id, published: true},
+		{name: "retiring key", kid: retiring.Id, published: true},
+		{name: "revoked key", kid: revoked.Id, wantErr: true},
+		{name: "unknown key", kid: "unknown", wantErr: true},
+	}
+
+	published := map[string]bool{}
+	for _, key := range ring.Published() {
+		published[key.Id] = true
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ring.VerificationKey(tt.kid)
+
+			assert.Equal(t, tt.published, published[tt.kid])
+			if tt.wantErr {
+				assert.Nil(t, key)
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.kid, key.Id)
+		})
+	}
+}
+
+func TestLoadSecretsRetirementPeriod(t *testing.T) {
+	tests := []struct {
+		name             string
+		retirementPeriod string
+		wantErr          bool
+	}{
+		{name: "default", retirementPeriod: ""},
+		{name: "token lifetime", retirementPeriod: "1h"},
+		{name: "shorter than the token lifetime", retirementPeriod: "30m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestKeyRotation(t)
+			defaultAlgorithm := configuration.DefaultSigningAlgorithm
+			t.Cleanup(func() { configuration.DefaultSigningAlgorithm = defaultAlgorithm })
+			t.Setenv("SIGNING_KEY_RETIREMENT_PERIOD", tt.retirementPeriod)
+
+			err := configuration.LoadSecrets()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
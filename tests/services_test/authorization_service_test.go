@@ -38,6 +38,10 @@ func TestAuthorize(t *testing.T) {
 		testUnsupportedResponseType(t, authService, mockOauthClientService)
 	})
 
+	t.Run("unregistered response type", func(t *testing.T) {
+		testUnregisteredResponseType(t, authService, mockOauthClientService)
+	})
+
 	t.Run("invalid redirect uri", func(t *testing.T) {
 		testInvalidRedirectUri(t, authService, mockOauthClientService)
 	})
@@ -69,23 +73,40 @@ func testClientNotFound(t *testing.T, authService services.AuthorizationService,
 }
 
 func testUnsupportedResponseType(t *testing.T, authService services.AuthorizationService, mockOauthClientService *mocks.MockOauthClientService) {
-	mockOauthClientService.EXPECT().FindOauthClient("client_id").Return(&store.OauthClient{
+	command := &services.AuthorizeCommand{
 		ClientId:     "client_id",
-		RedirectURIs: []string{"https://example.com"},
+		Scope:        "scope",
+		RedirectUri:  "https://example.com",
+		ResponseType: responsetype.ResponseType("code device_code"),
+		SessionId:    "session_id",
+		State:        "state",
+	}
+
+	got, err := authService.Authorize(command)
+	assert.Nil(t, got)
+	assert.EqualError(t, err, "the authorization server does not support obtaining an authorization code using this method")
+}
+
+func testUnregisteredResponseType(t *testing.T, authService services.AuthorizationService, mockOauthClientService *mocks.MockOauthClientService) {
+	mockOauthClientService.EXPECT().FindOauthClient("client_id").Return(&store.OauthClient{
+		ClientId:      "client_id",
+		RedirectURIs:  []string{"https://example.com"},
+		ResponseTypes: []string{"code"},
 	}, nil)
 
 	command := &services.AuthorizeCommand{
 		ClientId:     "client_id",
-		Scope:        "scope",
+		Scope:        "openid",
 		RedirectUri:  "https://example.com",
-		ResponseType: responsetype.Token,
+		ResponseType: responsetype.ResponseType("token code"),
 		SessionId:    "session_id",
 		State:        "state",
+		Nonce:        "nonce",
 	}
 
 	got, err := authService.Authorize(command)
 	assert.Nil(t, got)
-	assert.EqualError(t, err, "the authorization server does not support obtaining an authorization code using this method")
+	assert.EqualError(t, err, "response type is not registered for client: code token")
 }
 
 func testInvalidRedirectUri(t *testing.T, authService services.AuthorizationService, mockOauthClientService *mocks.MockOauthClientService) {
@@ -131,14 +152,17 @@ func testSessionDoesNotExist(t *testing.T, authService services.AuthorizationSer
 
 func testSuccess(t *testing.T, authService services.AuthorizationService, mockOauthClientService *mocks.MockOauthClientService, mockSessionService *mocks.MockSessionService, mockUserRepository *mocks.MockUserRepository, mockAuthRepository *mocks.MockAuthorizationRepository) {
 	mockOauthClientService.EXPECT().FindOauthClient("client_id").Return(&store.OauthClient{
-		ClientId:     "client_id",
-		RedirectURIs: []string{"https://example.com"},
+		ClientId:      "client_id",
+		RedirectURIs:  []string{"https://example.com"},
+		ResponseTypes: []string{"code"},
 	}, nil)
 	mockSessionService.EXPECT().SessionExists("session_id").Return(true)
 	mockSessionService.EXPECT().GetUserIdFromSession("session_id").Return("user_id", nil)
 	mockUserRepository.EXPECT().FindByUserId("user_id").Return(&store.User{
 		Id: "user_id",
 	}, nil)
+	mockSessionService.EXPECT().GetAuthTimeFromSession("session_id").Return(time.Now(), nil)
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(gomock.Any()).Return(nil)
 
 	mockAuthRepository.EXPECT().Save(gomock.Any()).Return(&store.AuthCode{
 		Code:        "auth_code",
@@ -169,7 +193,9 @@ func testSuccess(t *testing.T, authService services.AuthorizationService, mockOa
 
 	got, err := authService.Authorize(command)
 	assert.NoError(t, err)
-	if !expected.Equal(got) {
+	assert.Nil(t, got.Token)
+	assert.Empty(t, got.IDToken)
+	if !expected.Equal(got.Code) {
 		t.Errorf("expected and actual AuthCode are not equal")
 	}
 }
@@ -0,0 +1,233 @@
+package service_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+// newAssertionKey returns a key to sign private_key_jwt client assertions with and the client keys registering it.
+func newAssertionKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := jwk.New(privateKey.Public())
+	require.NoError(t, err)
+	keySet := jwk.NewSet()
+	keySet.Add(publicKey)
+	jwks, err := json.Marshal(keySet)
+	require.NoError(t, err)
+	return privateKey, string(jwks)
+}
+
+// signClientAssertion signs a client assertion of clientId for audience with a fresh jti.
+func signClientAssertion(t *testing.T, privateKey *ecdsa.PrivateKey, clientId, audience string) string {
+	token := jwt.New()
+	require.NoError(t, token.Set(jwt.IssuerKey, clientId))
+	require.NoError(t, token.Set(jwt.SubjectKey, clientId))
+	require.NoError(t, token.Set(jwt.AudienceKey, audience))
+	require.NoError(t, token.Set(jwt.ExpirationKey, time.Now().Add(time.Minute)))
+	require.NoError(t, token.Set(jwt.JwtIDKey, uuid.New().String()))
+	signed, err := jwt.Sign(token, jwa.ES256, privateKey)
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestClientAuthenticationMethodEnforcement(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	configuration.IssuerURL = "https://issuer.example"
+
+	assertionKey, jwks := newAssertionKey(t)
+	otherAssertionKey, _ := newAssertionKey(t)
+	certificate, certificateJwks := newSelfSignedCertificate(t)
+	certificates := []*x509.Certificate{certificate}
+	otherCertificate, _ := newSelfSignedCertificate(t)
+
+	secretClient := newTestClient(t, "secret-client", granttype.ClientCredentials)
+	publicClient := newTestClient(t, "public-client", granttype.AuthorizationCode)
+	publicClient.TokenEndpointAuthMethod = string(authmethodtype.None)
+	jwtClient := newTestClient(t, "jwt-client", granttype.ClientCredentials)
+	jwtClient.TokenEndpointAuthMethod = string(authmethodtype.PrivateKeyJWT)
+	jwtClient.Jwks = jwks
+	mtlsClient := newTestClient(t, "mtls-client", granttype.ClientCredentials)
+	mtlsClient.TokenEndpointAuthMethod = string(authmethodtype.SelfSignedTLSClientAuth)
+	mtlsClient.Jwks = certificateJwks
+
+	// Mocks
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	for _, client := range []*store.OauthClient{secretClient, publicClient, jwtClient, mtlsClient} {
+		mockOauthClientService.EXPECT().FindOauthClient(client.ClientId).Return(client, nil).AnyTimes()
+	}
+	mockOauthClientService.EXPECT().FindOauthClient(gomock.Any()).Return(nil, errors.New("client not found")).AnyTimes()
+	seen := map[string]bool{}
+	mockReplayCache := mocks.NewMockReplayCache(ctrl)
+	mockReplayCache.EXPECT().Remember(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(namespace, id string, _ time.Time) (bool, error) {
+		fresh := !seen[namespace+id]
+		seen[namespace+id] = true
+		return fresh, nil
+	}).AnyTimes()
+
+	// Under test
+	clientAuthenticator := services.NewClientAuthenticator(mockOauthClientService, mockReplayCache, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		command *services.ClientAuthenticationCommand
+		wantErr bool
+	}{
+		{
+			name:    "missing client_id",
+			command: &services.ClientAuthenticationCommand{ClientSecret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown client",
+			command: &services.ClientAuthenticationCommand{ClientId: "unknown-client", ClientSecret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "secret client without a secret",
+			command: &services.ClientAuthenticationCommand{ClientId: secretClient.ClientId},
+			wantErr: true,
+		},
+		{
+			name:    "secret client with a wrong secret",
+			command: &services.ClientAuthenticationCommand{ClientId: secretClient.ClientId, ClientSecret: "wrong"},
+			wantErr: true,
+		},
+		{
+			name: "secret client with a client assertion",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:        secretClient.ClientId,
+				ClientAssertion: signClientAssertion(t, assertionKey, secretClient.ClientId, configuration.IssuerURL),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "secret client with its secret",
+			command: &services.ClientAuthenticationCommand{ClientId: secretClient.ClientId, ClientSecret: "secret"},
+		},
+		{
+			name:    "public client with a secret",
+			command: &services.ClientAuthenticationCommand{ClientId: publicClient.ClientId, ClientSecret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "public client without credentials",
+			command: &services.ClientAuthenticationCommand{ClientId: publicClient.ClientId},
+		},
+		{
+			name: "private_key_jwt client with a secret",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:        jwtClient.ClientId,
+				ClientSecret:    "secret",
+				ClientAssertion: signClientAssertion(t, assertionKey, jwtClient.ClientId, configuration.IssuerURL),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "private_key_jwt client without an assertion",
+			command: &services.ClientAuthenticationCommand{ClientId: jwtClient.ClientId},
+			wantErr: true,
+		},
+		{
+			name: "private_key_jwt client with an assertion for another audience",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:        jwtClient.ClientId,
+				ClientAssertion: signClientAssertion(t, assertionKey, jwtClient.ClientId, "https://other.example"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "private_key_jwt client with an assertion signed by another key",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:        jwtClient.ClientId,
+				ClientAssertion: signClientAssertion(t, otherAssertionKey, jwtClient.ClientId, configuration.IssuerURL),
+			},
+			wantErr: true,
+		},
+		{
+			name: "private_key_jwt client with its assertion",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:        jwtClient.ClientId,
+				ClientAssertion: signClientAssertion(t, assertionKey, jwtClient.ClientId, configuration.IssuerURL+"/oauth/token"),
+			},
+		},
+		{
+			name: "mutual TLS client with a secret",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:           mtlsClient.ClientId,
+				ClientSecret:       "secret",
+				ClientCertificates: certificates,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "mutual TLS client without a certificate",
+			command: &services.ClientAuthenticationCommand{ClientId: mtlsClient.ClientId},
+			wantErr: true,
+		},
+		{
+			name: "mutual TLS client with a certificate it did not register",
+			command: &services.ClientAuthenticationCommand{
+				ClientId:           mtlsClient.ClientId,
+				ClientCertificates: []*x509.Certificate{otherCertificate},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "mutual TLS client with its certificate",
+			command: &services.ClientAuthenticationCommand{ClientId: mtlsClient.ClientId, ClientCertificates: certificates},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clientAuthenticator.Authenticate(tt.command)
+
+			if tt.wantErr {
+				assert.Nil(t, got)
+				assert.ErrorIs(t, err, api.ErrInvalidClient)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.command.ClientId, got.ClientId)
+		})
+	}
+
+	t.Run("replayed client assertion", func(t *testing.T) {
+		command := &services.ClientAuthenticationCommand{
+			ClientId:        jwtClient.ClientId,
+			ClientAssertion: signClientAssertion(t, assertionKey, jwtClient.ClientId, configuration.IssuerURL),
+		}
+		_, err := clientAuthenticator.Authenticate(command)
+		require.NoError(t, err)
+
+		got, err := clientAuthenticator.Authenticate(command)
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, api.ErrInvalidClient)
+	})
+}
@@ -0,0 +1,215 @@
+package service_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const tokenEndpoint = "https://issuer.example/oauth/token"
+
+// signDPoPProof signs a DPoP proof for a POST to the token endpoint, with claims overriding the defaults.
+func signDPoPProof(t *testing.T, privateKey *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	publicKey, err := jwk.New(privateKey.Public())
+	require.NoError(t, err)
+	headers := jws.NewHeaders()
+	require.NoError(t, headers.Set(jws.TypeKey, utils.DPoPProofType))
+	require.NoError(t, headers.Set(jws.JWKKey, publicKey))
+
+	payload := map[string]interface{}{
+		"htm": "POST",
+		"htu": tokenEndpoint,
+		"iat": time.Now().Unix(),
+		"jti": "jti",
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	encoded, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	signed, err := jws.Sign(encoded, jwa.ES256, privateKey, jws.WithHeaders(headers))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestValidateDPoPProof(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := jwk.New(privateKey.Public())
+	require.NoError(t, err)
+	thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	// Mocks
+	mockReplayCache := mocks.NewMockReplayCache(ctrl)
+
+	// Under test
+	dpopService := services.NewDPoPService(mockReplayCache, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		method  string
+		token   string
+		checked bool // whether the proof reaches the replay check
+		fresh   bool
+		wantErr error
+	}{
+		{
+			name:    "another HTTP method",
+			method:  "GET",
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "another endpoint",
+			claims:  map[string]interface{}{"htu": "https://issuer.example/oauth/introspect"},
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "issued too long ago",
+			claims:  map[string]interface{}{"iat": time.Now().Add(-time.Hour).Unix()},
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "hash of another access token",
+			claims:  map[string]interface{}{"ath": utils.DPoPAccessTokenHash("other")},
+			token:   "access_token",
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "replayed proof",
+			checked: true,
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "valid proof",
+			checked: true,
+			fresh:   true,
+		},
+		{
+			name:    "valid proof for an access token",
+			claims:  map[string]interface{}{"ath": utils.DPoPAccessTokenHash("access_token")},
+			token:   "access_token",
+			checked: true,
+			fresh:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.checked {
+				mockReplayCache.EXPECT().Remember("dpop:"+jkt, "jti", gomock.Any()).Return(tt.fresh, nil)
+			}
+			method := tt.method
+			if method == "" {
+				method = "POST"
+			}
+
+			got, err := dpopService.ValidateProof(&services.DPoPProofCommand{
+				Proof:       signDPoPProof(t, privateKey, tt.claims),
+				Method:      method,
+				Uri:         tokenEndpoint,
+				AccessToken: tt.token,
+			})
+
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, jkt, got)
+		})
+	}
+}
+
+func TestValidateTokenBinding(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// Mocks
+	mockReplayCache := mocks.NewMockReplayCache(ctrl)
+	mockReplayCache.EXPECT().Remember(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+
+	// Under test
+	dpopService := services.NewDPoPService(mockReplayCache, zap.NewNop())
+
+	proof := func() *services.DPoPProofCommand {
+		return &services.DPoPProofCommand{
+			Proof:       signDPoPProof(t, privateKey, map[string]interface{}{"ath": utils.DPoPAccessTokenHash("access_token")}),
+			Method:      "POST",
+			Uri:         tokenEndpoint,
+			AccessToken: "access_token",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		command *services.TokenBindingCommand
+		wantErr error
+	}{
+		{
+			name:    "bearer token",
+			command: &services.TokenBindingCommand{Scheme: "Bearer"},
+		},
+		{
+			name:    "bearer token with the DPoP scheme",
+			command: &services.TokenBindingCommand{Scheme: services.DPoPTokenType},
+			wantErr: api.ErrInvalidToken,
+		},
+		{
+			name:    "bound token with the Bearer scheme",
+			command: &services.TokenBindingCommand{Jkt: "jkt", Scheme: "Bearer"},
+			wantErr: api.ErrInvalidToken,
+		},
+		{
+			name:    "bound token without a proof",
+			command: &services.TokenBindingCommand{Jkt: "jkt", Scheme: services.DPoPTokenType},
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+		{
+			name:    "bound token with a proof signed by another key",
+			command: &services.TokenBindingCommand{Jkt: "jkt", Scheme: services.DPoPTokenType, Proof: proof()},
+			wantErr: api.ErrInvalidDPoPProof,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dpopService.ValidateTokenBinding(tt.command)
+
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
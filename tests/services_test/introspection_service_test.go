@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestIntrospectAudience(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	configuration.AccessTokenAudience = ""
+
+	frontend := "frontend"
+	resource := store.OauthResource{ResourceId: "reports", Uri: "https://api.example.com/reports"}
+	newAccessToken := func(audience ...string) *store.AccessToken {
+		return &store.AccessToken{
+			Id:        "access-token",
+			ClientId:  &frontend,
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	mockOauthResourceRepository := mocks.NewMockOauthResourceRepository(ctrl)
+	mockOauthResourceRepository.EXPECT().FindByClientId("reports-server").Return([]store.OauthResource{resource}, nil).AnyTimes()
+	mockOauthResourceRepository.EXPECT().FindByClientId(gomock.Any()).Return(nil, nil).AnyTimes()
+	mockRevocationList := mocks.NewMockRevocationList(ctrl)
+
+	// Under test
+	introspectionService := services.NewIntrospectionService(mockAccessTokenRepository, mockRefreshTokenRepository, mockOauthResourceRepository, mockRevocationList, nil, zap.NewNop())
+
+	tests := []struct {
+		name       string
+		token      *store.AccessToken
+		callerId   string
+		wantActive bool
+	}{
+		{
+			name:       "resource server in the audience",
+			token:      newAccessToken(resource.Uri),
+			callerId:   "reports-server",
+			wantActive: true,
+		},
+		{
+			name:     "resource server outside the audience",
+			token:    newAccessToken("https://api.example.com/payroll"),
+			callerId: "reports-server",
+		},
+		{
+			name:       "client named in the audience",
+			token:      newAccessToken("billing-service"),
+			callerId:   "billing-service",
+			wantActive: true,
+		},
+		{
+			name:     "token without an audience introspected by another client",
+			token:    newAccessToken(),
+			callerId: "reports-server",
+		},
+		{
+			name:       "token without an audience introspected by its own client",
+			token:      newAccessToken(),
+			callerId:   frontend,
+			wantActive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessTokenRepository.EXPECT().FindByAccessToken("token").Return(tt.token, nil)
+
+			got, err := introspectionService.Introspect(&services.IntrospectCommand{Token: "token", ClientId: tt.callerId})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantActive, got.Active)
+		})
+	}
+
+	t.Run("refresh token of another client", func(t *testing.T) {
+		refreshToken := &store.RefreshToken{Id: "refresh-token", ClientId: &frontend, ExpiresAt: time.Now().Add(time.Hour)}
+		mockAccessTokenRepository.EXPECT().FindByAccessToken("token").Return(nil, errors.New("access token not found"))
+		mockRefreshTokenRepository.EXPECT().FindByRefreshToken("token").Return(refreshToken, nil)
+
+		got, err := introspectionService.Introspect(&services.IntrospectCommand{Token: "token", ClientId: "reports-server"})
+
+		require.NoError(t, err)
+		assert.False(t, got.Active)
+	})
+}
@@ -0,0 +1,112 @@
+package service_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestResolveRequestObject(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	configuration.IssuerURL = "https://issuer.example"
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := jwk.New(privateKey.Public())
+	require.NoError(t, err)
+	keySet := jwk.NewSet()
+	keySet.Add(publicKey)
+	jwks, err := json.Marshal(keySet)
+	require.NoError(t, err)
+	client := &store.OauthClient{ClientId: "client_id", Jwks: string(jwks)}
+
+	// Mocks
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockOauthClientService.EXPECT().FindOauthClient("client_id").Return(client, nil).AnyTimes()
+	mockOauthClientService.EXPECT().FindOauthClient(gomock.Any()).Return(nil, errors.New("client not found")).AnyTimes()
+
+	// Under test
+	requestObjectService := services.NewRequestObjectService(mockOauthClientService, zap.NewNop())
+
+	sign := func(t *testing.T, claims map[string]interface{}) string {
+		token := jwt.New()
+		require.NoError(t, token.Set(jwt.IssuerKey, "client_id"))
+		require.NoError(t, token.Set(jwt.AudienceKey, configuration.IssuerURL))
+		require.NoError(t, token.Set(jwt.ExpirationKey, time.Now().Add(time.Minute)))
+		for name, value := range claims {
+			require.NoError(t, token.Set(name, value))
+		}
+		signed, err := jwt.Sign(token, jwa.ES256, privateKey)
+		require.NoError(t, err)
+		return string(signed)
+	}
+
+	t.Run("unsigned parameters outside the request object are ignored", func(t *testing.T) {
+		request := sign(t, map[string]interface{}{
+			"client_id":     "client_id",
+			"response_type": "code",
+			"redirect_uri":  "https://example.com",
+		})
+
+		got, err := requestObjectService.Resolve(url.Values{
+			"client_id":    {"client_id"},
+			"request":      {request},
+			"scope":        {"admin"},
+			"redirect_uri": {"https://attacker.example"},
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, got.Get("scope"))
+		assert.Equal(t, "https://example.com", got.Get("redirect_uri"))
+		assert.Equal(t, "client_id", got.Get("client_id"))
+		assert.Equal(t, request, got.Get("request"))
+	})
+
+	t.Run("client_id of another client", func(t *testing.T) {
+		request := sign(t, map[string]interface{}{"client_id": "other_client", "response_type": "code"})
+
+		got, err := requestObjectService.Resolve(url.Values{"client_id": {"client_id"}, "request": {request}})
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, api.ErrInvalidRequestObject)
+	})
+
+	t.Run("client_id missing from the request object", func(t *testing.T) {
+		request := sign(t, map[string]interface{}{"response_type": "code"})
+
+		got, err := requestObjectService.Resolve(url.Values{"client_id": {"client_id"}, "request": {request}})
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, api.ErrInvalidRequestObject)
+	})
+
+	t.Run("request_uri not registered for the client", func(t *testing.T) {
+		got, err := requestObjectService.Resolve(url.Values{
+			"client_id":   {"client_id"},
+			"request_uri": {"https://127.0.0.1/request.jwt"},
+		})
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, api.ErrInvalidRequestUri)
+	})
+}
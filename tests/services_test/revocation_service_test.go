@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestRevoke(t *testing.T) {
+	// Setup
+	clientId := "client-1"
+	expiresAt := time.Now().Add(time.Hour)
+	accessToken := &store.AccessToken{Id: "access-token-id", ClientId: &clientId, Jti: "jti-1", ExpiresAt: expiresAt}
+	refreshToken := &store.RefreshToken{Id: "refresh-token-id", ClientId: &clientId, FamilyId: "family-1", ExpiresAt: expiresAt}
+	// The access tokens issued in the family of refreshToken; the first one predates stored jti values
+	familyAccessTokens := []store.AccessToken{
+		{Id: "legacy-access-token-id", ClientId: &clientId, FamilyId: "family-1", ExpiresAt: expiresAt},
+		{Id: "family-access-token-id", ClientId: &clientId, FamilyId: "family-1", Jti: "jti-2", ExpiresAt: expiresAt},
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		tokenTypeHint string
+		clientId      string
+		wantErr       error
+		// wantRevokedAccessToken and wantRevokedFamily tell which of the client's tokens are revoked, and so have
+		// the jti of their access tokens listed
+		wantRevokedAccessToken bool
+		wantRevokedFamily      bool
+	}{
+		{name: "unknown token", token: "unknown", clientId: clientId},
+		{name: "access token", token: "access-token", clientId: clientId, wantRevokedAccessToken: true},
+		{
+			name:                   "access token with a refresh_token hint",
+			token:                  "access-token",
+			tokenTypeHint:          "refresh_token",
+			clientId:               clientId,
+			wantRevokedAccessToken: true,
+		},
+		{name: "access token of another client", token: "access-token", clientId: "client-2", wantErr: api.ErrUnauthorizedClient},
+		{
+			name:              "refresh token",
+			token:             "refresh-token",
+			tokenTypeHint:     "refresh_token",
+			clientId:          clientId,
+			wantRevokedFamily: true,
+		},
+		{
+			name:              "refresh token with an access_token hint",
+			token:             "refresh-token",
+			tokenTypeHint:     "access_token",
+			clientId:          clientId,
+			wantRevokedFamily: true,
+		},
+		{name: "refresh token of another client", token: "refresh-token", clientId: "client-2", wantErr: api.ErrUnauthorizedClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Mocks
+			mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+			mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+			mockRevocationList := mocks.NewMockRevocationList(ctrl)
+			mockAccessTokenRepository.EXPECT().FindByAccessToken("access-token").Return(accessToken, nil).AnyTimes()
+			mockAccessTokenRepository.EXPECT().FindByAccessToken(gomock.Any()).Return(nil, errors.New("access token not found")).AnyTimes()
+			mockRefreshTokenRepository.EXPECT().FindByRefreshToken("refresh-token").Return(refreshToken, nil).AnyTimes()
+			mockRefreshTokenRepository.EXPECT().FindByRefreshToken(gomock.Any()).Return(nil, errors.New("refresh token not found")).AnyTimes()
+			if tt.wantRevokedAccessToken {
+				mockRevocationList.EXPECT().Add("jti-1", expiresAt).Return(nil)
+				mockAccessTokenRepository.EXPECT().DeleteByAccessToken("access-token").Return(nil)
+			}
+			if tt.wantRevokedFamily {
+				mockRefreshTokenRepository.EXPECT().FindFamilyAccessTokens(refreshToken).Return(familyAccessTokens, nil)
+				mockRevocationList.EXPECT().Add("jti-2", expiresAt).Return(nil)
+				mockRefreshTokenRepository.EXPECT().DeleteFamily(refreshToken).Return(nil)
+			}
+
+			// Under test
+			revocationService := services.NewRevocationService(mockAccessTokenRepository, mockRefreshTokenRepository, mockRevocationList, zap.NewNop())
+
+			err := revocationService.Revoke(&services.RevokeCommand{Token: tt.token, TokenTypeHint: tt.tokenTypeHint, ClientId: tt.clientId})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,733 @@
+package service_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/manuelrojas19/go-oauth2-server/api"
+	"github.com/manuelrojas19/go-oauth2-server/autogenerated/mocks"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/authmethodtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/granttype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/tokentype"
+	"github.com/manuelrojas19/go-oauth2-server/services"
+	"github.com/manuelrojas19/go-oauth2-server/store"
+	"github.com/manuelrojas19/go-oauth2-server/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	readScope  = store.Scope{Id: "scope-read", Name: "read"}
+	writeScope = store.Scope{Id: "scope-write", Name: "write"}
+)
+
+// useTestSigningKey gives the token service a key ring, persisted to a temporary file, to sign access tokens with.
+func useTestSigningKey(t *testing.T) {
+	configuration.SigningAlgorithms = []string{signingtype.RS256}
+	configuration.DefaultSigningAlgorithm = signingtype.RS256
+	configuration.SigningKeysFile = filepath.Join(t.TempDir(), "signing_keys.json")
+	require.NoError(t, configuration.SigningKeys.Rotate(time.Now()))
+}
+
+// newUnrestrictedResourceService returns a ResourceService for requests without resource indicators, which keeps the
+// scopes it is given.
+func newUnrestrictedResourceService(ctrl *gomock.Controller) *mocks.MockResourceService {
+	mockResourceService := mocks.NewMockResourceService(ctrl)
+	mockResourceService.EXPECT().RestrictScopes(gomock.Len(0), gomock.Any()).DoAndReturn(func(_ []string, scopes []store.Scope) ([]store.Scope, error) {
+		return scopes, nil
+	}).AnyTimes()
+	return mockResourceService
+}
+
+// newPlaintextTokenEncryptionService returns a TokenEncryptionService for clients and resources that do not ask for
+// encrypted tokens, which leaves tokens as they are.
+func newPlaintextTokenEncryptionService(ctrl *gomock.Controller) *mocks.MockTokenEncryptionService {
+	mockTokenEncryptionService := mocks.NewMockTokenEncryptionService(ctrl)
+	mockTokenEncryptionService.EXPECT().EncryptAccessToken(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(token string, _ *store.OauthClient, _ []string) (string, error) {
+		return token, nil
+	}).AnyTimes()
+	return mockTokenEncryptionService
+}
+
+func newTestClient(t *testing.T, clientId string, grantTypes ...granttype.GrantType) *store.OauthClient {
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &store.OauthClient{
+		ClientId:     clientId,
+		ClientSecret: string(secretHash),
+		GrantTypes:   granttype.EnumListToStringList(grantTypes),
+		Confidential: true,
+		Scopes:       []store.Scope{readScope, writeScope},
+	}
+}
+
+func TestPasswordGrant(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	localUser := store.NewUserBuilder().WithEmail("alice@example.com").WithIdpName(store.LocalIdpName).
+		WithPasswordHash(string(passwordHash)).Build()
+	federatedUser := store.NewUserBuilder().WithEmail("bob@example.com").WithIdpName("google").Build()
+	passwordClient := newTestClient(t, "password-client", granttype.Password, granttype.RefreshToken)
+	codeClient := newTestClient(t, "code-client", granttype.AuthorizationCode)
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	mockUserRepository := mocks.NewMockUserRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockAuthorizationDetailsService := mocks.NewMockAuthorizationDetailsService(ctrl)
+	mockAuthorizationDetailsService.EXPECT().Validate("").Return(nil, nil).AnyTimes()
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(gomock.Any()).Return(nil).AnyTimes()
+	mockUserRepository.EXPECT().FindByEmail(localUser.Email).Return(localUser, nil).AnyTimes()
+	mockUserRepository.EXPECT().FindByEmail(federatedUser.Email).Return(federatedUser, nil).AnyTimes()
+	mockUserRepository.EXPECT().FindByEmail(gomock.Any()).Return(nil, errors.New("user not found")).AnyTimes()
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, mockRefreshTokenRepository, nil, mockUserRepository, nil, nil, nil, mockOauthClientService, mockAuthorizationDetailsService, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		client   *store.OauthClient
+		username string
+		password string
+		scope    string
+		wantErr  error
+	}{
+		{name: "client not registered for the grant", client: codeClient, username: "alice@example.com", password: "correct horse", wantErr: api.ErrUnauthorizedClient},
+		{name: "scope not registered for the client", client: passwordClient, username: "alice@example.com", password: "correct horse", scope: "admin", wantErr: api.ErrInvalidScope},
+		{name: "unknown user", client: passwordClient, username: "mallory@example.com", password: "correct horse", wantErr: api.ErrInvalidGrant},
+		{name: "wrong password", client: passwordClient, username: "alice@example.com", password: "battery staple", wantErr: api.ErrInvalidGrant},
+		{name: "federated user without a local password", client: passwordClient, username: "bob@example.com", wantErr: api.ErrInvalidGrant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+				ClientId:  tt.client.ClientId,
+				Client:    tt.client,
+				GrantType: granttype.Password,
+				Username:  tt.username,
+				Password:  tt.password,
+				Scope:     tt.scope,
+			})
+
+			assert.Nil(t, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("valid credentials", func(t *testing.T) {
+		mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+			return token, nil
+		})
+		var savedRefreshToken *store.RefreshToken
+		mockRefreshTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.RefreshToken) (*store.RefreshToken, error) {
+			assert.Equal(t, []store.Scope{readScope}, token.Scopes, "the refresh token keeps the scopes of the grant")
+			savedRefreshToken = token
+			return token, nil
+		})
+
+		got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+			ClientId:  passwordClient.ClientId,
+			Client:    passwordClient,
+			GrantType: granttype.Password,
+			Username:  "alice@example.com",
+			Password:  "correct horse",
+			Scope:     "read",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, localUser.Id, *got.UserId)
+		assert.Equal(t, []string{"read"}, got.Scope)
+		assert.NotEmpty(t, got.RefreshToken)
+		assert.Equal(t, store.HashRefreshToken(got.RefreshToken), savedRefreshToken.Token, "only the hash of the refresh token is stored")
+		claims, err := utils.ParseJWT(got.AccessToken)
+		require.NoError(t, err)
+		lifetime := int(services.AccessTokenDuration.Seconds())
+		assert.Equal(t, float64(lifetime), claims["exp"].(float64)-claims["iat"].(float64), "exp follows the access token lifetime")
+		assert.Equal(t, lifetime, got.AccessTokenExpiresIn)
+		assert.Equal(t, int64(claims["exp"].(float64)), got.AccessTokenExpiresAt.Unix(), "the stored expiry matches exp")
+	})
+}
+
+func TestRefreshTokenScopes(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+
+	client := newTestClient(t, "client-1", granttype.Password, granttype.RefreshToken)
+	userId := "user-1"
+	refreshTokenValue, err := utils.GenerateRefreshToken()
+	require.NoError(t, err)
+	refreshToken := store.NewRefreshTokenBuilder().
+		WithToken(refreshTokenValue).
+		WithClientId(&client.ClientId).
+		WithUserId(&userId).
+		WithExpiresAt(time.Now().Add(time.Hour)).
+		WithScopes([]store.Scope{readScope}).
+		Build()
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(client).Return(nil)
+	mockRefreshTokenRepository.EXPECT().FindByRefreshToken(refreshTokenValue).Return(refreshToken, nil)
+	mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+		return token, nil
+	})
+	mockRefreshTokenRepository.EXPECT().Rotate(refreshToken.Id, gomock.Any()).DoAndReturn(func(_ string, token *store.RefreshToken) (*store.RefreshToken, bool, error) {
+		assert.Equal(t, []store.Scope{readScope}, token.Scopes, "the new refresh token keeps the scopes of the grant")
+		return token, true, nil
+	})
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, mockRefreshTokenRepository, nil, nil, nil, nil, nil, mockOauthClientService, nil, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+		ClientId:     client.ClientId,
+		Client:       client,
+		GrantType:    granttype.RefreshToken,
+		RefreshToken: refreshTokenValue,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, got.Scope, "the access token is not widened to the scopes of the client")
+}
+
+func TestRefreshTokenNarrowedToResource(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+
+	client := newTestClient(t, "client-1", granttype.AuthorizationCode, granttype.RefreshToken)
+	userId := "user-1"
+	resource := "https://api.example.com/reports"
+	refreshTokenValue, err := utils.GenerateRefreshToken()
+	require.NoError(t, err)
+	refreshToken := store.NewRefreshTokenBuilder().
+		WithToken(refreshTokenValue).
+		WithClientId(&client.ClientId).
+		WithUserId(&userId).
+		WithExpiresAt(time.Now().Add(time.Hour)).
+		WithScopes([]store.Scope{readScope, writeScope}).
+		WithResources([]string{resource}).
+		Build()
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockResourceService := mocks.NewMockResourceService(ctrl)
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(client).Return(nil)
+	mockResourceService.EXPECT().RestrictScopes([]string{resource}, []store.Scope{readScope, writeScope}).Return([]store.Scope{readScope}, nil)
+	mockRefreshTokenRepository.EXPECT().FindByRefreshToken(refreshTokenValue).Return(refreshToken, nil)
+	mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+		return token, nil
+	})
+	mockRefreshTokenRepository.EXPECT().Rotate(refreshToken.Id, gomock.Any()).DoAndReturn(func(_ string, token *store.RefreshToken) (*store.RefreshToken, bool, error) {
+		assert.Equal(t, []store.Scope{readScope, writeScope}, token.Scopes, "the new refresh token keeps the scopes of the grant")
+		return token, true, nil
+	})
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, mockRefreshTokenRepository, nil, nil, nil, nil, nil, mockOauthClientService, nil, mockResourceService, newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+		ClientId:     client.ClientId,
+		Client:       client,
+		GrantType:    granttype.RefreshToken,
+		RefreshToken: refreshTokenValue,
+		Resources:    []string{resource},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, got.Scope, "the access token is narrowed to the scopes of the resource")
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	// Setup
+	useTestSigningKey(t)
+
+	client := newTestClient(t, "client-1", granttype.Password, granttype.RefreshToken)
+	otherClient := newTestClient(t, "client-2", granttype.Password, granttype.RefreshToken)
+	userId := "user-1"
+	usedAt := time.Now().Add(-time.Minute)
+	newRefreshToken := func() *store.RefreshToken {
+		return store.NewRefreshTokenBuilder().
+			WithToken(store.HashRefreshToken("refresh-token")).
+			WithClientId(&client.ClientId).
+			WithUserId(&userId).
+			WithExpiresAt(time.Now().Add(time.Hour)).
+			WithScopes([]store.Scope{readScope}).
+			WithFamilyId("family-1").
+			Build()
+	}
+
+	tests := []struct {
+		name             string
+		client           *store.OauthClient
+		refreshToken     func() *store.RefreshToken
+		usedConcurrently bool
+		resources        []string
+		wantErr          error
+		// wantReuse reports whether the family is revoked and the reuse published as a security event
+		wantReuse bool
+	}{
+		{
+			name:         "first use",
+			client:       client,
+			refreshToken: newRefreshToken,
+		},
+		{
+			name:   "token issued before families were tracked",
+			client: client,
+			refreshToken: func() *store.RefreshToken {
+				refreshToken := newRefreshToken()
+				refreshToken.FamilyId = ""
+				return refreshToken
+			},
+		},
+		{
+			name:   "rotated token presented again",
+			client: client,
+			refreshToken: func() *store.RefreshToken {
+				refreshToken := newRefreshToken()
+				refreshToken.UsedAt = &usedAt
+				return refreshToken
+			},
+			wantErr:   api.ErrInvalidGrant,
+			wantReuse: true,
+		},
+		{
+			name:             "token rotated by a concurrent request",
+			client:           client,
+			refreshToken:     newRefreshToken,
+			usedConcurrently: true,
+			wantErr:          api.ErrInvalidGrant,
+			wantReuse:        true,
+		},
+		{
+			name:         "token of another client",
+			client:       otherClient,
+			refreshToken: newRefreshToken,
+			wantErr:      api.ErrInvalidGrant,
+		},
+		{
+			name:   "resources beyond the grant",
+			client: client,
+			refreshToken: func() *store.RefreshToken {
+				refreshToken := newRefreshToken()
+				refreshToken.Resources = []string{"https://api.example.com/reports"}
+				return refreshToken
+			},
+			resources: []string{"https://api.example.com/payroll"},
+			wantErr:   api.ErrInvalidTarget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			refreshToken := tt.refreshToken()
+
+			// Mocks
+			mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+			mockRefreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+			mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+			mockRevocationService := mocks.NewMockRevocationService(ctrl)
+			mockSecurityEventPublisher := mocks.NewMockSecurityEventPublisher(ctrl)
+			mockOauthClientService.EXPECT().PreloadOauthClientScopes(gomock.Any()).Return(nil).AnyTimes()
+			mockRefreshTokenRepository.EXPECT().FindByRefreshToken("refresh-token").Return(refreshToken, nil)
+			var replacement *store.RefreshToken
+			if tt.wantErr == nil || tt.usedConcurrently {
+				mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+					return token, nil
+				})
+				mockRefreshTokenRepository.EXPECT().Rotate(refreshToken.Id, gomock.Any()).DoAndReturn(func(_ string, token *store.RefreshToken) (*store.RefreshToken, bool, error) {
+					if tt.usedConcurrently {
+						return nil, false, nil
+					}
+					replacement = token
+					return token, true, nil
+				})
+			}
+			if tt.wantReuse {
+				mockRevocationService.EXPECT().RevokeFamily(refreshToken).Return(nil)
+				mockSecurityEventPublisher.EXPECT().Publish(gomock.Any()).DoAndReturn(func(event services.SecurityEvent) error {
+					assert.Equal(t, services.RefreshTokenReuseEvent, event.Type)
+					assert.Equal(t, refreshToken.FamilyId, event.FamilyId)
+					return nil
+				})
+			}
+
+			// Under test
+			tokenService := services.NewTokenService(mockAccessTokenRepository, mockRefreshTokenRepository, nil, nil, nil, nil, nil, mockOauthClientService, nil, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), mockRevocationService, mockSecurityEventPublisher, zap.NewNop())
+
+			got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+				ClientId:     tt.client.ClientId,
+				Client:       tt.client,
+				GrantType:    granttype.RefreshToken,
+				RefreshToken: "refresh-token",
+				Resources:    tt.resources,
+			})
+
+			if tt.wantErr != nil {
+				assert.Nil(t, got)
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEqual(t, "refresh-token", got.RefreshToken)
+			assert.Equal(t, store.HashRefreshToken(got.RefreshToken), replacement.Token)
+			assert.NotEmpty(t, replacement.FamilyId)
+			if refreshToken.FamilyId != "" {
+				assert.Equal(t, refreshToken.FamilyId, replacement.FamilyId, "the replacement joins the family")
+			}
+		})
+	}
+}
+
+func TestDeviceCodePolling(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+
+	deviceClient := newTestClient(t, "device-client", granttype.DeviceCode)
+	otherClientId := "other-client"
+	userId := "user-1"
+	newDeviceAuthorization := func(status string, polledAgo time.Duration, expiresIn time.Duration) *store.DeviceAuthorization {
+		deviceAuthorization := &store.DeviceAuthorization{
+			DeviceCode: "device-code",
+			ClientId:   &deviceClient.ClientId,
+			Status:     status,
+			UserId:     &userId,
+			Interval:   5,
+			ExpiresAt:  time.Now().Add(expiresIn),
+		}
+		if polledAgo > 0 {
+			lastPolledAt := time.Now().Add(-polledAgo)
+			deviceAuthorization.LastPolledAt = &lastPolledAt
+		}
+		return deviceAuthorization
+	}
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockDeviceAuthorizationRepository := mocks.NewMockDeviceAuthorizationRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(deviceClient).Return(nil).AnyTimes()
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, nil, nil, nil, mockDeviceAuthorizationRepository, nil, nil, mockOauthClientService, nil, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	command := &services.GrantAccessTokenCommand{
+		ClientId:   deviceClient.ClientId,
+		Client:     deviceClient,
+		GrantType:  granttype.DeviceCode,
+		DeviceCode: "device-code",
+	}
+
+	tests := []struct {
+		name                string
+		deviceAuthorization *store.DeviceAuthorization
+		// deleted and updated report whether the device code is deleted or the poll recorded
+		deleted      bool
+		updated      bool
+		wantErr      error
+		wantInterval int
+	}{
+		{
+			name: "device code issued to another client",
+			deviceAuthorization: func() *store.DeviceAuthorization {
+				deviceAuthorization := newDeviceAuthorization(store.DeviceAuthorizationApproved, 0, time.Minute)
+				deviceAuthorization.ClientId = &otherClientId
+				return deviceAuthorization
+			}(),
+			wantErr:      api.ErrInvalidGrant,
+			wantInterval: 5,
+		},
+		{
+			name:                "expired device code",
+			deviceAuthorization: newDeviceAuthorization(store.DeviceAuthorizationPending, 0, -time.Second),
+			deleted:             true,
+			wantErr:             api.ErrExpiredToken,
+			wantInterval:        5,
+		},
+		{
+			name:                "pending",
+			deviceAuthorization: newDeviceAuthorization(store.DeviceAuthorizationPending, 6*time.Second, time.Minute),
+			updated:             true,
+			wantErr:             api.ErrAuthorizationPending,
+			wantInterval:        5,
+		},
+		{
+			name:                "polled within the interval",
+			deviceAuthorization: newDeviceAuthorization(store.DeviceAuthorizationApproved, time.Second, time.Minute),
+			updated:             true,
+			wantErr:             api.ErrSlowDown,
+			wantInterval:        10,
+		},
+		{
+			name:                "denied by the end user",
+			deviceAuthorization: newDeviceAuthorization(store.DeviceAuthorizationDenied, 0, time.Minute),
+			deleted:             true,
+			wantErr:             api.ErrAccessDenied,
+			wantInterval:        5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDeviceAuthorizationRepository.EXPECT().FindByDeviceCode("device-code").Return(tt.deviceAuthorization, nil)
+			if tt.deleted {
+				mockDeviceAuthorizationRepository.EXPECT().Delete("device-code").Return(true, nil)
+			}
+			if tt.updated {
+				mockDeviceAuthorizationRepository.EXPECT().Update(tt.deviceAuthorization).Return(nil)
+			}
+
+			got, err := tokenService.GrantAccessToken(command)
+
+			assert.Nil(t, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantInterval, tt.deviceAuthorization.Interval)
+		})
+	}
+
+	t.Run("approved device code", func(t *testing.T) {
+		mockDeviceAuthorizationRepository.EXPECT().FindByDeviceCode("device-code").
+			Return(newDeviceAuthorization(store.DeviceAuthorizationApproved, 6*time.Second, time.Minute), nil)
+		mockDeviceAuthorizationRepository.EXPECT().Delete("device-code").Return(true, nil)
+		mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+			return token, nil
+		})
+
+		got, err := tokenService.GrantAccessToken(command)
+
+		require.NoError(t, err)
+		assert.Equal(t, userId, *got.UserId)
+	})
+
+	t.Run("approved device code redeemed by a concurrent poll", func(t *testing.T) {
+		mockDeviceAuthorizationRepository.EXPECT().FindByDeviceCode("device-code").
+			Return(newDeviceAuthorization(store.DeviceAuthorizationApproved, 6*time.Second, time.Minute), nil)
+		mockDeviceAuthorizationRepository.EXPECT().Delete("device-code").Return(false, nil)
+
+		got, err := tokenService.GrantAccessToken(command)
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, api.ErrInvalidGrant)
+	})
+}
+
+func TestTokenExchange(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+	configuration.IssuerURL = "https://issuer.example"
+
+	userId := "user-1"
+	exchangeClient := newTestClient(t, "exchange-client", granttype.TokenExchange)
+	exchangeClient.TokenExchangeSubjectClients = []string{"frontend"}
+	exchangeClient.TokenExchangeAudiences = []string{"billing-service"}
+	newAccessToken := func(t *testing.T, clientId string) string {
+		token, err := utils.GenerateAccessTokenJWT("", &clientId, &userId, time.Now(), time.Hour, map[string]interface{}{"scope": "read"})
+		require.NoError(t, err)
+		return token
+	}
+	frontendToken := newAccessToken(t, "frontend")
+	mobileToken := newAccessToken(t, "mobile-app")
+	revokedToken := newAccessToken(t, "frontend")
+	configuration.IssuerURL = "https://other-issuer.example"
+	foreignToken := newAccessToken(t, "frontend")
+	configuration.IssuerURL = "https://issuer.example"
+	idToken, err := utils.GenerateIDToken("", userId, "frontend", time.Hour, nil)
+	require.NoError(t, err)
+	introspectionToken, err := utils.GenerateIntrospectionJWT("", "frontend", map[string]interface{}{"active": true, "sub": userId})
+	require.NoError(t, err)
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(exchangeClient).Return(nil).AnyTimes()
+	for token, clientId := range map[string]string{frontendToken: "frontend", mobileToken: "mobile-app"} {
+		mockAccessTokenRepository.EXPECT().FindByAccessToken(token).Return(&store.AccessToken{
+			Token:    token,
+			ClientId: &clientId,
+			UserId:   &userId,
+			Scopes:   []store.Scope{readScope},
+		}, nil).AnyTimes()
+	}
+	mockAccessTokenRepository.EXPECT().FindByAccessToken(revokedToken).Return(nil, errors.New("access token not found")).AnyTimes()
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, nil, nil, nil, nil, nil, nil, mockOauthClientService, nil, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	tests := []struct {
+		name             string
+		subjectToken     string
+		subjectTokenType tokentype.TokenType
+		audience         []string
+		scope            string
+		wantErr          error
+	}{
+		{name: "malformed subject token", subjectToken: "not-a-jwt", wantErr: api.ErrInvalidRequest},
+		{name: "subject token issued by another issuer", subjectToken: foreignToken, wantErr: api.ErrInvalidRequest},
+		{name: "revoked subject token", subjectToken: revokedToken, wantErr: api.ErrInvalidRequest},
+		{name: "ID token declared as an access token", subjectToken: idToken, wantErr: api.ErrInvalidRequest},
+		{name: "introspection response", subjectToken: introspectionToken, subjectTokenType: tokentype.JWT, wantErr: api.ErrInvalidRequest},
+		{name: "subject token of a client the policy does not name", subjectToken: mobileToken, wantErr: api.ErrUnauthorizedClient},
+		{name: "audience not allowed for the client", subjectToken: frontendToken, audience: []string{"payroll-service"}, wantErr: api.ErrInvalidTarget},
+		{name: "scope beyond the subject token", subjectToken: frontendToken, scope: "write", wantErr: api.ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subjectTokenType := tt.subjectTokenType
+			if subjectTokenType == "" {
+				subjectTokenType = tokentype.AccessToken
+			}
+
+			got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+				ClientId:         exchangeClient.ClientId,
+				Client:           exchangeClient,
+				GrantType:        granttype.TokenExchange,
+				SubjectToken:     tt.subjectToken,
+				SubjectTokenType: subjectTokenType,
+				Audience:         tt.audience,
+				Scope:            tt.scope,
+			})
+
+			assert.Nil(t, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("access token of a permitted client", func(t *testing.T) {
+		mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+			return token, nil
+		})
+
+		got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+			ClientId:         exchangeClient.ClientId,
+			Client:           exchangeClient,
+			GrantType:        granttype.TokenExchange,
+			SubjectToken:     frontendToken,
+			SubjectTokenType: tokentype.AccessToken,
+			Audience:         []string{"billing-service"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, userId, *got.UserId)
+		assert.Equal(t, []string{"read"}, got.Scope)
+		assert.Empty(t, got.RefreshToken)
+	})
+
+	t.Run("ID token of a permitted client", func(t *testing.T) {
+		mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+			return token, nil
+		})
+
+		got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+			ClientId:         exchangeClient.ClientId,
+			Client:           exchangeClient,
+			GrantType:        granttype.TokenExchange,
+			SubjectToken:     idToken,
+			SubjectTokenType: tokentype.JWT,
+			Audience:         []string{"billing-service"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, userId, *got.UserId)
+		assert.Empty(t, got.Scope, "an ID token grants no scopes")
+	})
+}
+
+// newSelfSignedCertificate returns a self-signed TLS client certificate and the client keys registering it.
+func newSelfSignedCertificate(t *testing.T) (*x509.Certificate, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	key, err := jwk.New(privateKey.Public())
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.X509CertChainKey, []string{base64.StdEncoding.EncodeToString(der)}))
+	keySet := jwk.NewSet()
+	keySet.Add(key)
+	jwks, err := json.Marshal(keySet)
+	require.NoError(t, err)
+	return certificate, string(jwks)
+}
+
+func TestCertificateBoundClientCredentials(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	useTestSigningKey(t)
+
+	certificate, jwks := newSelfSignedCertificate(t)
+	client := newTestClient(t, "mtls-client", granttype.ClientCredentials)
+	client.TokenEndpointAuthMethod = string(authmethodtype.SelfSignedTLSClientAuth)
+	client.Jwks = jwks
+
+	// Mocks
+	mockAccessTokenRepository := mocks.NewMockAccessTokenRepository(ctrl)
+	mockOauthClientService := mocks.NewMockOauthClientService(ctrl)
+	mockAuthorizationDetailsService := mocks.NewMockAuthorizationDetailsService(ctrl)
+	mockAuthorizationDetailsService.EXPECT().Validate("").Return(nil, nil).AnyTimes()
+	mockOauthClientService.EXPECT().PreloadOauthClientScopes(client).Return(nil)
+	mockAccessTokenRepository.EXPECT().Save(gomock.Any()).DoAndReturn(func(token *store.AccessToken) (*store.AccessToken, error) {
+		assert.Equal(t, utils.CertificateThumbprint(certificate), token.CertificateThumbprint, "the access token is bound to the certificate")
+		return token, nil
+	})
+
+	// Under test
+	tokenService := services.NewTokenService(mockAccessTokenRepository, nil, nil, nil, nil, nil, nil, mockOauthClientService, mockAuthorizationDetailsService, newUnrestrictedResourceService(ctrl), newPlaintextTokenEncryptionService(ctrl), nil, nil, zap.NewNop())
+
+	got, err := tokenService.GrantAccessToken(&services.GrantAccessTokenCommand{
+		ClientId:           client.ClientId,
+		Client:             client,
+		GrantType:          granttype.ClientCredentials,
+		ClientCertificates: []*x509.Certificate{certificate},
+	})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, got.AccessToken)
+}
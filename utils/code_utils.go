@@ -2,8 +2,11 @@ package utils
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 	"github.com/google/uuid"
+	"math/big"
 	"strings"
 )
 
@@ -15,3 +18,63 @@ func GenerateAuthCode(clientId string, userId string) (string, error) {
 	code = strings.ToUpper(strings.TrimRight(code, "="))
 	return code, nil
 }
+
+// userCodeAlphabet avoids vowels and look-alike characters so user codes are easy to read and type (RFC 8628, section 6.1).
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// GenerateDeviceCode returns a high-entropy, URL-safe device verification code.
+func GenerateDeviceCode() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate device code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GenerateRefreshToken returns an opaque, high-entropy refresh token. It carries no claims and no signature: the
+// server resolves it against the refresh tokens it stored, so there is no key to leak and no token to forge.
+func GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// RequestUriPrefix identifies request_uri values issued by the pushed authorization request endpoint.
+const RequestUriPrefix = "urn:ietf:params:oauth:request_uri:"
+
+// GenerateRequestUri returns a one-time reference to a pushed authorization request (RFC 9126, section 2.2).
+func GenerateRequestUri() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate request_uri: %w", err)
+	}
+	return RequestUriPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GenerateUserCode returns a short end-user verification code formatted as XXXX-XXXX.
+func GenerateUserCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+	code := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code = append(code, userCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode upper-cases a user code typed by the end user and restores its dash.
+func NormalizeUserCode(userCode string) string {
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+	if len(code) == 8 {
+		return code[:4] + "-" + code[4:]
+	}
+	return code
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+)
+
+// DPoPProofType is the typ header every DPoP proof JWT must carry (RFC 9449, section 4.2).
+const DPoPProofType = "dpop+jwt"
+
+// DPoPSigningAlgorithms lists the asymmetric algorithms accepted for DPoP proofs.
+var DPoPSigningAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// DPoPProof holds the claims of a DPoP proof whose signature was verified with the public key in its header.
+type DPoPProof struct {
+	Jkt             string // SHA-256 JWK thumbprint of the key that signed the proof
+	Method          string // htm
+	Uri             string // htu
+	IssuedAt        time.Time
+	JwtID           string
+	AccessTokenHash string // ath, present when the proof accompanies an access token
+}
+
+// ParseDPoPProof verifies the signature of a DPoP proof with the public key embedded in its jwk header and returns
+// its claims. Checking the claims against the request is left to the caller.
+func ParseDPoPProof(proof string) (*DPoPProof, error) {
+	message, err := jws.Parse([]byte(proof))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DPoP proof: %w", err)
+	}
+	if len(message.Signatures()) != 1 {
+		return nil, errors.New("DPoP proof must have exactly one signature")
+	}
+
+	headers := message.Signatures()[0].ProtectedHeaders()
+	if headers.Type() != DPoPProofType {
+		return nil, fmt.Errorf("DPoP proof typ must be %s", DPoPProofType)
+	}
+	alg := headers.Algorithm()
+	if !slices.Contains(DPoPSigningAlgorithms, alg.String()) {
+		return nil, fmt.Errorf("DPoP proof algorithm %s is not supported", alg)
+	}
+
+	key := headers.JWK()
+	if key == nil {
+		return nil, errors.New("DPoP proof has no jwk header")
+	}
+	switch key.(type) {
+	case jwk.RSAPrivateKey, jwk.ECDSAPrivateKey, jwk.OKPPrivateKey, jwk.SymmetricKey:
+		return nil, errors.New("DPoP proof jwk header must be a public key")
+	}
+
+	payload, err := jws.Verify([]byte(proof), jwa.SignatureAlgorithm(alg), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify DPoP proof: %w", err)
+	}
+
+	var claims struct {
+		Htm string  `json:"htm"`
+		Htu string  `json:"htu"`
+		Iat float64 `json:"iat"`
+		Jti string  `json:"jti"`
+		Ath string  `json:"ath"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to read DPoP proof claims: %w", err)
+	}
+	if claims.Htm == "" || claims.Htu == "" || claims.Iat == 0 || claims.Jti == "" {
+		return nil, errors.New("DPoP proof must contain htm, htu, iat and jti")
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute DPoP key thumbprint: %w", err)
+	}
+
+	return &DPoPProof{
+		Jkt:             base64.RawURLEncoding.EncodeToString(thumbprint),
+		Method:          claims.Htm,
+		Uri:             claims.Htu,
+		IssuedAt:        time.Unix(int64(claims.Iat), 0),
+		JwtID:           claims.Jti,
+		AccessTokenHash: claims.Ath,
+	}, nil
+}
+
+// DPoPAccessTokenHash computes the ath claim binding a DPoP proof to an access token: the base64url encoded
+// SHA-256 hash of the token.
+func DPoPAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NormalizeDPoPUri strips the query and fragment from an htu value and lowercases its scheme and host, so it can
+// be compared with the URI of the request (RFC 9449, section 4.3).
+func NormalizeDPoPUri(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("malformed htu: %s", uri)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
@@ -1,6 +1,16 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func EncryptText(text string) (string, error) {
 	textHash, err := bcrypt.GenerateFromPassword([]byte(text), 3)
@@ -9,3 +19,50 @@ func EncryptText(text string) (string, error) {
 	}
 	return string(textHash), nil
 }
+
+// SealText encrypts text with AES-256-GCM under a key derived from passphrase, for values that must be read back,
+// unlike the hashes EncryptText produces.
+func SealText(text, passphrase string) (string, error) {
+	aead, err := newTextCipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(text), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenText decrypts a value produced by SealText with the same passphrase.
+func OpenText(sealed, passphrase string) (string, error) {
+	aead, err := newTextCipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode sealed text: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("sealed text is too short")
+	}
+	text, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt sealed text: %w", err)
+	}
+	return string(text), nil
+}
+
+func newTextCipher(passphrase string) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("no encryption key configured")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
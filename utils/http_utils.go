@@ -39,6 +39,9 @@ func HandleErrorResponse(w http.ResponseWriter, logger *zap.Logger, err error) {
 	case errors.Is(err, api.ErrInvalidClient):
 		status = http.StatusUnauthorized
 		apiError = api.ErrorResponseBody(api.ErrInvalidClient)
+	case errors.Is(err, api.ErrUnauthorizedClient):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrUnauthorizedClient)
 	case errors.Is(err, api.ErrInvalidGrant):
 		status = http.StatusBadRequest
 		apiError = api.ErrorResponseBody(api.ErrInvalidGrant)
@@ -54,6 +57,33 @@ func HandleErrorResponse(w http.ResponseWriter, logger *zap.Logger, err error) {
 	case errors.Is(err, api.ErrInvalidRedirectUri):
 		status = http.StatusBadRequest
 		apiError = api.ErrorResponseBody(api.ErrInvalidRedirectUri)
+	case errors.Is(err, api.ErrAuthorizationPending):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrAuthorizationPending)
+	case errors.Is(err, api.ErrSlowDown):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrSlowDown)
+	case errors.Is(err, api.ErrExpiredToken):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrExpiredToken)
+	case errors.Is(err, api.ErrInvalidTarget):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrInvalidTarget)
+	case errors.Is(err, api.ErrInvalidRequestUri):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrInvalidRequestUri)
+	case errors.Is(err, api.ErrInvalidRequestObject):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrInvalidRequestObject)
+	case errors.Is(err, api.ErrInvalidDPoPProof):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrInvalidDPoPProof)
+	case errors.Is(err, api.ErrInvalidAuthorizationDetails):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrInvalidAuthorizationDetails)
+	case errors.Is(err, api.ErrAccessDenied):
+		status = http.StatusBadRequest
+		apiError = api.ErrorResponseBody(api.ErrAccessDenied)
 	case errors.Is(err, api.ErrClientAlreadyExists):
 		status = http.StatusConflict
 		apiError = api.ErrorResponseBody(api.ErrClientAlreadyExists)
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// ParseJSONSchema decodes a JSON Schema document. Only the validation keywords supported by ValidateJSONSchema are
+// given meaning; any other keyword is ignored.
+func ParseJSONSchema(document string) (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &schema); err != nil {
+		return nil, fmt.Errorf("malformed JSON schema: %w", err)
+	}
+	return schema, nil
+}
+
+// ValidateJSONSchema checks a decoded JSON value against a subset of JSON Schema: type, enum, const, properties,
+// required, additionalProperties, items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum and exclusiveMaximum. The error names the path of the first value that does not match.
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) error {
+	return validateJSONSchema(schema, value, "$")
+}
+
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if types, ok := schema["type"]; ok && !matchesJSONType(types, value) {
+		return fmt.Errorf("%s must be of type %v", path, types)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !slices.ContainsFunc(enum, func(allowed interface{}) bool {
+		return reflect.DeepEqual(allowed, value)
+	}) {
+		return fmt.Errorf("%s must be one of %v", path, enum)
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s must be %v", path, constant)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateJSONObject(schema, v, path)
+	case []interface{}:
+		if limit, ok := schema["minItems"].(float64); ok && float64(len(v)) < limit {
+			return fmt.Errorf("%s must have at least %v items", path, limit)
+		}
+		if limit, ok := schema["maxItems"].(float64); ok && float64(len(v)) > limit {
+			return fmt.Errorf("%s must have at most %v items", path, limit)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if limit, ok := schema["minLength"].(float64); ok && length < limit {
+			return fmt.Errorf("%s must be at least %v characters long", path, limit)
+		}
+		if limit, ok := schema["maxLength"].(float64); ok && length > limit {
+			return fmt.Errorf("%s must be at most %v characters long", path, limit)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			matched, err := regexp.MatchString(pattern, v)
+			if err != nil {
+				return fmt.Errorf("%s has an invalid pattern in its schema: %w", path, err)
+			}
+			if !matched {
+				return fmt.Errorf("%s must match %s", path, pattern)
+			}
+		}
+	case float64:
+		if limit, ok := schema["minimum"].(float64); ok && v < limit {
+			return fmt.Errorf("%s must be at least %v", path, limit)
+		}
+		if limit, ok := schema["maximum"].(float64); ok && v > limit {
+			return fmt.Errorf("%s must be at most %v", path, limit)
+		}
+		if limit, ok := schema["exclusiveMinimum"].(float64); ok && v <= limit {
+			return fmt.Errorf("%s must be greater than %v", path, limit)
+		}
+		if limit, ok := schema["exclusiveMaximum"].(float64); ok && v >= limit {
+			return fmt.Errorf("%s must be less than %v", path, limit)
+		}
+	}
+	return nil
+}
+
+// validateJSONObject checks the members of an object against the properties, required and additionalProperties
+// keywords of its schema.
+func validateJSONObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := object[name]; !present {
+					return fmt.Errorf("%s.%s is required", path, name)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		memberPath := path + "." + name
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			if err := validateJSONSchema(property, object[name], memberPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s is not allowed", memberPath)
+			}
+		case map[string]interface{}:
+			if err := validateJSONSchema(additional, object[name], memberPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesJSONType reports whether value is of the JSON type, or one of the JSON types, named by types.
+func matchesJSONType(types interface{}, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return isJSONType(t, value)
+	case []interface{}:
+		return slices.ContainsFunc(t, func(name interface{}) bool {
+			typeName, ok := name.(string)
+			return ok && isJSONType(typeName, value)
+		})
+	default:
+		return false
+	}
+}
+
+func isJSONType(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/encryptiontype"
+)
+
+// EncryptJWT nests a signed JWT in a JWE encrypted to key with RSA-OAEP-256 and A256GCM. The cty header tells the
+// recipient the payload is itself a JWT (RFC 7519, section 5.2).
+func EncryptJWT(token string, key jwk.Key) (string, error) {
+	headers := jwe.NewHeaders()
+	if err := headers.Set(jwe.ContentTypeKey, "JWT"); err != nil {
+		return "", fmt.Errorf("failed to set JWE headers: %w", err)
+	}
+
+	encrypted, err := jwe.Encrypt([]byte(token), jwa.RSA_OAEP_256, key, jwa.A256GCM, jwa.NoCompress, jwe.WithProtectedHeaders(headers))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	return string(encrypted), nil
+}
+
+// DecryptJWT decrypts a token encrypted to this server's own JWE key and returns the nested JWT.
+func DecryptJWT(token string) (string, error) {
+	privateKey, err := configuration.GetJWEPrivateKey()
+	if err != nil {
+		return "", fmt.Errorf("JWE private key is not initialized: %w", err)
+	}
+
+	decrypted, err := jwe.Decrypt([]byte(token), jwa.RSA_OAEP_256, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return string(decrypted), nil
+}
+
+// IsEncryptedJWT reports whether token uses the JWE compact serialization, which has five parts where a signed
+// JWT has three.
+func IsEncryptedJWT(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// ServerEncryptionKey returns this server's own JWE public key, which tokens only the server may read, such as
+// access tokens that resource servers must introspect, are encrypted to.
+func ServerEncryptionKey() (jwk.Key, error) {
+	publicKey, err := configuration.GetJWEPublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("JWE public key is not initialized: %w", err)
+	}
+
+	key, err := jwk.New(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWK from JWE public key: %w", err)
+	}
+	if err := key.Set(jwk.KeyIDKey, configuration.KeyID(publicKey)); err != nil {
+		return nil, fmt.Errorf("failed to set JWE key ID: %w", err)
+	}
+	return key, nil
+}
+
+// EncryptionKey returns the RSA key of set registered for encryption: the first one with use "enc" or an
+// RSA-OAEP-256 alg.
+func EncryptionKey(set jwk.Set) (jwk.Key, error) {
+	for i := 0; i < set.Len(); i++ {
+		key, ok := set.Get(i)
+		if !ok || key.KeyType() != jwa.RSA {
+			continue
+		}
+		if key.KeyUsage() == string(jwk.ForEncryption) || key.Algorithm() == encryptiontype.RSAOAEP256 {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no RSA encryption key registered")
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// jwksFetchTimeout bounds how long fetching a remote jwks_uri may take.
+const jwksFetchTimeout = 5 * time.Second
+
+const (
+	// jwksCacheTTL is how long a fetched jwks_uri document is reused before it is fetched again.
+	jwksCacheTTL = 5 * time.Minute
+	// jwksFailureCacheTTL is how long a failed jwks_uri fetch is remembered, so a broken or hostile URI is not
+	// requested again on every use.
+	jwksFailureCacheTTL = 30 * time.Second
+)
+
+// cachedJWKSet is the outcome of fetching a jwks_uri, kept until expiresAt.
+type cachedJWKSet struct {
+	set       jwk.Set
+	err       error
+	expiresAt time.Time
+}
+
+var (
+	jwksCache   = map[string]cachedJWKSet{}
+	jwksCacheMu sync.Mutex
+)
+
+// LoadJWKSet returns the public keys registered inline as a JWK Set document (jwks) or published at jwksUri. Remote
+// documents are fetched with the restricted clientMetadataClient and cached per URI for jwksCacheTTL.
+func LoadJWKSet(jwks, jwksUri string) (jwk.Set, error) {
+	switch {
+	case jwks != "":
+		set, err := jwk.ParseString(jwks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks: %w", err)
+		}
+		return set, nil
+	case jwksUri != "":
+		return fetchJWKSet(jwksUri)
+	default:
+		return nil, errors.New("no keys registered")
+	}
+}
+
+// fetchJWKSet returns the JWK Set published at jwksUri, from the cache while the last fetch has not expired.
+func fetchJWKSet(jwksUri string) (jwk.Set, error) {
+	jwksCacheMu.Lock()
+	cached, ok := jwksCache[jwksUri]
+	jwksCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.set, cached.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	set, err := jwk.Fetch(ctx, jwksUri, jwk.WithHTTPClient(clientMetadataClient))
+	now := time.Now()
+	cached = cachedJWKSet{set: set, expiresAt: now.Add(jwksCacheTTL)}
+	if err != nil {
+		cached = cachedJWKSet{
+			err:       fmt.Errorf("failed to fetch jwks_uri %s: %w", jwksUri, err),
+			expiresAt: now.Add(jwksFailureCacheTTL),
+		}
+	}
+
+	// Store the outcome, dropping the entries that have expired meanwhile
+	jwksCacheMu.Lock()
+	defer jwksCacheMu.Unlock()
+	for uri, entry := range jwksCache {
+		if now.After(entry.expiresAt) {
+			delete(jwksCache, uri)
+		}
+	}
+	jwksCache[jwksUri] = cached
+	return cached.set, cached.err
+}
+
+// clientMetadataClient fetches documents at URLs taken from client metadata, such as jwks_uri and request_uri. The
+// URLs come from clients, so it follows no redirects, uses no proxy and only connects to public addresses: a client
+// cannot make the server reach its own network.
+var clientMetadataClient = &http.Client{
+	Timeout: jwksFetchTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: jwksFetchTimeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return fmt.Errorf("refusing to connect to non-public address %s", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: jwksFetchTimeout,
+	},
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address, rather than a loopback, private, link-local
+// or otherwise reserved one.
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// UnverifiedJWTIssuer reads the iss claim of a JWT without verifying it, so the verification keys can be looked up.
+func UnverifiedJWTIssuer(token string) (string, error) {
+	parsed, err := jwt.ParseString(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse JWT: %w", err)
+	}
+	if parsed.Issuer() == "" {
+		return "", errors.New("JWT has no iss claim")
+	}
+	return parsed.Issuer(), nil
+}
+
+// VerifyJWTWithKeySet verifies a JWT signed by one of the keys in set, and validates its exp, nbf and iat claims.
+// exp and jti are required so that the token can be tracked for replay until it expires.
+func VerifyJWTWithKeySet(token string, set jwk.Set) (jwt.Token, error) {
+	parsed, err := jwt.ParseString(token,
+		jwt.WithKeySet(set),
+		jwt.UseDefaultKey(true),
+		jwt.InferAlgorithmFromKey(true),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(30*time.Second),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify JWT: %w", err)
+	}
+	return parsed, nil
+}
+
+// VerifyJWTWithSecret verifies a JWT signed with an HMAC algorithm and the given shared secret, and validates its exp,
+// nbf and iat claims. As with VerifyJWTWithKeySet, exp and jti are required.
+func VerifyJWTWithSecret(token string, secret []byte) (jwt.Token, error) {
+	message, err := jws.ParseString(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %w", err)
+	}
+	if len(message.Signatures()) != 1 {
+		return nil, errors.New("JWT must have exactly one signature")
+	}
+	alg := message.Signatures()[0].ProtectedHeaders().Algorithm()
+	if alg != jwa.HS256 && alg != jwa.HS384 && alg != jwa.HS512 {
+		return nil, fmt.Errorf("JWT algorithm %s is not an HMAC algorithm", alg)
+	}
+
+	parsed, err := jwt.ParseString(token,
+		jwt.WithVerify(alg, secret),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(30*time.Second),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify JWT: %w", err)
+	}
+	return parsed, nil
+}
+
+// requestObjectMaxSize bounds the size of a request object fetched from a request_uri.
+const requestObjectMaxSize = 64 * 1024
+
+// VerifyRequestObject verifies a request object (RFC 9101) signed by one of the keys in set, and validates its
+// exp, nbf and iat claims. exp is required so a captured request object cannot be used indefinitely.
+func VerifyRequestObject(token string, set jwk.Set) (jwt.Token, error) {
+	parsed, err := jwt.ParseString(token,
+		jwt.WithKeySet(set),
+		jwt.UseDefaultKey(true),
+		jwt.InferAlgorithmFromKey(true),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(30*time.Second),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify request object: %w", err)
+	}
+	return parsed, nil
+}
+
+// RequestObjectParams converts the claims of a verified request object into authorization request parameters.
+// JWT-only claims are left out; values that are not strings, such as the claims request, are JSON encoded.
+func RequestObjectParams(token jwt.Token) (url.Values, error) {
+	claims, err := token.AsMap(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request object claims: %w", err)
+	}
+
+	params := url.Values{}
+	for name, value := range claims {
+		switch name {
+		case jwt.IssuerKey, jwt.SubjectKey, jwt.AudienceKey, jwt.ExpirationKey, jwt.IssuedAtKey, jwt.NotBeforeKey, jwt.JwtIDKey:
+			continue
+		}
+		if str, ok := value.(string); ok {
+			params.Set(name, str)
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request object claim %s: %w", name, err)
+		}
+		params.Set(name, string(encoded))
+	}
+	return params, nil
+}
+
+// FetchRequestObject retrieves a request object passed by reference (RFC 9101, section 5.2). Only https URLs with
+// public addresses are dereferenced; callers check the URL was registered by the client first.
+func FetchRequestObject(requestUri string) (string, error) {
+	u, err := url.Parse(requestUri)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("request_uri must be an absolute https URL")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requestUri, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build request_uri request: %w", err)
+	}
+	req.Header.Set("Accept", "application/oauth-authz-req+jwt")
+
+	res, err := clientMetadataClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch request_uri: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request_uri returned status %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, requestObjectMaxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read request_uri response: %w", err)
+	}
+	if len(body) > requestObjectMaxSize {
+		return "", fmt.Errorf("request object exceeds %d bytes", requestObjectMaxSize)
+	}
+	return string(body), nil
+}
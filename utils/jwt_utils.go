@@ -2,6 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -9,106 +12,194 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/manuelrojas19/go-oauth2-server/configuration"
+	"github.com/manuelrojas19/go-oauth2-server/oauth/signingtype"
 )
 
-// GenerateJWT creates a JWT token with the given parameters.
-func GenerateJWT(clientId *string, userId *string, secretKey interface{}, tokenType string) (string, error) {
-	var expirationTime time.Duration
-	var signingMethod jwt.SigningMethod
+// AccessTokenJWTType is the typ header of JWT access tokens (RFC 9068, section 2.1).
+const AccessTokenJWTType = "at+jwt"
 
-	switch tokenType {
-	case "access":
-		expirationTime = time.Hour
-		signingMethod = jwt.SigningMethodRS256
-		privateKey, err := configuration.GetJWTPrivateKey()
-		if err != nil {
-			return "", fmt.Errorf("private key is not initialized: %w", err)
-		}
-		token := jwt.NewWithClaims(signingMethod, jwt.MapClaims{
-			"iat":  time.Now().Unix(),
-			"exp":  time.Now().Add(expirationTime).Unix(),
-			"type": tokenType,
-			"jti":  generateRandomString(),
-		})
+// JWTType is the typ header of the other JWTs this server signs, such as ID tokens.
+const JWTType = "JWT"
 
-		if clientId != nil {
-			token.Claims.(jwt.MapClaims)["clientId"] = *clientId
-		}
+// IntrospectionJWTType is the typ header of JWT introspection responses (RFC 9701, section 5).
+const IntrospectionJWTType = "token-introspection+jwt"
 
-		if userId != nil {
-			token.Claims.(jwt.MapClaims)["userId"] = *userId
-		}
+// IntrospectionJWTContentType is the media type a resource server accepts to receive a JWT introspection response
+// (RFC 9701, section 4).
+const IntrospectionJWTContentType = "application/" + IntrospectionJWTType
 
-		tokenString, err := token.SignedString(privateKey)
-		if err != nil {
-			return "", fmt.Errorf("failed to sign token: %w", err)
-		}
-		return tokenString, nil
-
-	case "refresh":
-		expirationTime = 24 * 30 * time.Hour
-		signingMethod = jwt.SigningMethodHS256
-		key, ok := secretKey.([]byte)
-		if !ok {
-			return "", fmt.Errorf("invalid key type for HS256; expected []byte, got %T", secretKey)
-		}
-		token := jwt.NewWithClaims(signingMethod, jwt.MapClaims{
-			"iat":  time.Now().Unix(),
-			"exp":  time.Now().Add(expirationTime).Unix(),
-			"type": tokenType,
-			"jti":  generateRandomString(),
-		})
+// SigningAlgorithm returns alg, the algorithm a client registered for one of its tokens, or the server's default
+// signing algorithm when the client registered none.
+func SigningAlgorithm(alg string) string {
+	if alg == "" {
+		return configuration.DefaultSigningAlgorithm
+	}
+	return alg
+}
 
+// GenerateAccessTokenJWT creates an access token following the JWT profile of RFC 9068, valid from issuedAt for
+// lifetime and signed with alg (or the default algorithm when empty), adding extraClaims (e.g. aud, scope, auth_time,
+// act) to the standard ones. The subject is the resource owner, or the client itself when the token is issued to the
+// client on its own behalf. Tokens without an aud claim in extraClaims are issued for the default audience.
+func GenerateAccessTokenJWT(alg string, clientId *string, userId *string, issuedAt time.Time, lifetime time.Duration, extraClaims map[string]interface{}) (string, error) {
+	claims := jwt.MapClaims{
+		"iss": configuration.IssuerURL,
+		"aud": configuration.AccessTokenAudience,
+		"iat": issuedAt.Unix(),
+		"exp": issuedAt.Add(lifetime).Unix(),
+		"jti": generateRandomString(),
+	}
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
+	if clientId != nil {
+		claims["client_id"] = *clientId
+		claims["sub"] = *clientId
+	}
+	if userId != nil {
+		claims["sub"] = *userId
+	}
+
+	// Resource servers not yet migrated to RFC 9068 still read the legacy claim names
+	if configuration.AccessTokenLegacyClaims {
+		claims["type"] = "access"
 		if clientId != nil {
-			token.Claims.(jwt.MapClaims)["clientId"] = *clientId
+			claims["clientId"] = *clientId
 		}
 		if userId != nil {
-			token.Claims.(jwt.MapClaims)["userId"] = *userId
+			claims["userId"] = *userId
 		}
+	}
 
-		tokenString, err := token.SignedString(key)
-		if err != nil {
-			return "", fmt.Errorf("failed to sign token: %w", err)
-		}
-		return tokenString, nil
+	tokenString, err := signJWT(SigningAlgorithm(alg), claims, AccessTokenJWTType)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, nil
+}
 
-	default:
-		return "", fmt.Errorf("unsupported token type: %s", tokenType)
+// GenerateIDToken creates an OpenID Connect ID token for subject, issued by this server to audience and signed with
+// alg (or the default algorithm when empty). The kid header names the key published in the JWK Set so relying
+// parties can select it.
+func GenerateIDToken(alg, subject, audience string, lifetime time.Duration, extraClaims map[string]interface{}) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
+	claims["iss"] = configuration.IssuerURL
+	claims["sub"] = subject
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(lifetime).Unix()
+
+	tokenString, err := signJWT(SigningAlgorithm(alg), claims, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to sign ID token: %w", err)
 	}
+	return tokenString, nil
 }
 
-// ValidateRefreshToken validates the JWT token using the provided secret key and returns the claims if valid.
-func ValidateRefreshToken(tokenString string, secretKey []byte) (jwt.MapClaims, error) {
-	// Parse and validate the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Check token signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unsupported JWT signing method for refresh token: %s", token.Method.Alg())
-		}
-		return secretKey, nil
-	})
+// GenerateIntrospectionJWT wraps an introspection response in a JWT signed with alg for the resource server that
+// asked for it (RFC 9701, section 5). The token_introspection claim carries the response members.
+func GenerateIntrospectionJWT(alg, audience string, response interface{}) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":                 configuration.IssuerURL,
+		"aud":                 audience,
+		"iat":                 time.Now().Unix(),
+		"token_introspection": response,
+	}
+
+	tokenString, err := signJWT(SigningAlgorithm(alg), claims, IntrospectionJWTType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse or validate refresh token: %w", err)
+		return "", fmt.Errorf("failed to sign introspection response: %w", err)
 	}
+	return tokenString, nil
+}
 
-	// Check if the token is valid
-	if !token.Valid {
-		return nil, errors.New("refresh token is invalid")
+// signJWT signs claims with the active key of alg, naming the key in the kid header and setting the typ header when
+// given.
+func signJWT(alg string, claims jwt.MapClaims, typ string) (string, error) {
+	signingKey, err := configuration.GetJWTSigningKey(alg)
+	if err != nil {
+		return "", fmt.Errorf("signing key is not initialized: %w", err)
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return "", fmt.Errorf("unsupported signing algorithm %s", alg)
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("refresh token contains invalid claims")
+	token := jwt.NewWithClaims(method, claims)
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	token.Header["kid"] = signingKey.Id
+	return token.SignedString(signingKey.PrivateKey)
+}
+
+// IDTokenHash computes the at_hash or c_hash value for an access token or authorization code issued alongside an ID
+// token signed with alg: the left-most half of its hash, base64url encoded. The hash is the one of the signature
+// algorithm, and SHA-512 for EdDSA with Ed25519 keys.
+func IDTokenHash(alg, value string) string {
+	var sum []byte
+	switch SigningAlgorithm(alg) {
+	case signingtype.ES384:
+		digest := sha512.Sum384([]byte(value))
+		sum = digest[:]
+	case signingtype.EdDSA:
+		digest := sha512.Sum512([]byte(value))
+		sum = digest[:]
+	default:
+		digest := sha256.Sum256([]byte(value))
+		sum = digest[:]
+	}
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// ParseJWT verifies the signature and expiry of a JWT issued by this server and returns its claims. The kid header
+// selects the key of the key ring the token is verified with, so tokens signed before a rotation stay valid until
+// their key is retired. Tokens encrypted to this server's JWE key are decrypted first.
+func ParseJWT(tokenString string) (jwt.MapClaims, error) {
+	claims, _, err := ParseJWTWithType(tokenString)
+	return claims, err
+}
+
+// ParseJWTWithType is ParseJWT, also returning the typ header of the token, which tells the kinds of JWT this server
+// signs apart.
+func ParseJWTWithType(tokenString string) (jwt.MapClaims, string, error) {
+	if IsEncryptedJWT(tokenString) {
+		var err error
+		tokenString, err = DecryptJWT(tokenString)
+		if err != nil {
+			return nil, "", err
+		}
 	}
 
-	// Validate expiration time
-	exp, ok := claims["exp"].(float64)
-	if !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
-		return nil, errors.New("refresh token has expired")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid header")
+		}
+		key, err := configuration.GetJWTVerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		// A key only verifies the algorithm it was generated for, so a token cannot switch e.g. RS256 for PS256
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected JWT signing method %s for key %s", token.Method.Alg(), kid)
+		}
+		return key.PublicKey(), nil
+	}, jwt.WithValidMethods(signingtype.Supported))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse or validate token: %w", err)
 	}
 
-	return claims, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, "", errors.New("token contains invalid claims")
+	}
+	typ, _ := token.Header["typ"].(string)
+	return claims, typ, nil
 }
 
 // GenerateRandomString generates a random string of the specified length.
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// CertificateThumbprint computes the x5t#S256 confirmation of a certificate: the base64url encoded SHA-256 hash of
+// its DER encoding (RFC 8705, section 3.1).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyClientCertificateChain checks that the leaf of chain was issued, possibly through the intermediates that
+// follow it, by one of roots and may be used for TLS client authentication.
+func VerifyClientCertificateChain(chain []*x509.Certificate, roots *x509.CertPool) error {
+	if len(chain) == 0 {
+		return errors.New("no client certificate presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("client certificate is not trusted: %w", err)
+	}
+	return nil
+}
+
+// CertificateInJWKSet reports whether cert is the first certificate of the x5c chain of one of the keys in set, which
+// is how clients using self-signed certificates register them (RFC 8705, section 2.2.2).
+func CertificateInJWKSet(cert *x509.Certificate, set jwk.Set) bool {
+	for i := 0; i < set.Len(); i++ {
+		key, ok := set.Get(i)
+		if !ok {
+			continue
+		}
+		chain := key.X509CertChain()
+		if len(chain) > 0 && bytes.Equal(chain[0].Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}